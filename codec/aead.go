@@ -0,0 +1,323 @@
+package codec
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const (
+	// AesGcm is AES in Galois/Counter Mode, the key must be 16, 24 or 32 bytes.
+	AesGcm AeadAlgorithm = iota + 1
+	// ChaCha20Poly1305 is ChaCha20-Poly1305 as in RFC 8439, the key must be 32 bytes.
+	ChaCha20Poly1305
+)
+
+const (
+	envelopeVersion  = 1
+	envelopeMinBytes = 3
+	maxKeyIdLen      = 255
+)
+
+var (
+	// ErrUnknownAlgorithm indicates an unsupported AEAD algorithm.
+	ErrUnknownAlgorithm = errors.New("unknown aead algorithm")
+	// ErrInvalidEnvelope indicates a malformed encrypted envelope.
+	ErrInvalidEnvelope = errors.New("invalid envelope")
+	// ErrUnknownKey indicates the envelope key id is not in the keyring.
+	ErrUnknownKey = errors.New("unknown key id")
+	// ErrNoPrimaryKey indicates the keyring has no key to encrypt with.
+	ErrNoPrimaryKey = errors.New("no primary key")
+	// ErrInvalidKeyId indicates an empty or too long key id.
+	ErrInvalidKeyId = errors.New("key id must be 1 to 255 bytes")
+	// ErrDuplicateKeyId indicates the key id is already in the keyring.
+	ErrDuplicateKeyId = errors.New("duplicate key id")
+)
+
+type (
+	// AeadAlgorithm represents an authenticated encryption algorithm.
+	AeadAlgorithm byte
+
+	// Keyring holds the keys used to encrypt and decrypt envelopes.
+	// New data is encrypted with the primary key, while all keys in the
+	// ring are available for decryption, which allows key rotation.
+	Keyring struct {
+		lock    sync.RWMutex
+		primary string
+		keys    map[string]keyringEntry
+	}
+
+	keyringEntry struct {
+		alg  AeadAlgorithm
+		aead cipher.AEAD
+	}
+)
+
+// String returns the name of the algorithm.
+func (a AeadAlgorithm) String() string {
+	switch a {
+	case AesGcm:
+		return "AES-GCM"
+	case ChaCha20Poly1305:
+		return "ChaCha20-Poly1305"
+	default:
+		return fmt.Sprintf("unknown(%d)", byte(a))
+	}
+}
+
+// NewAead returns a cipher.AEAD of the given algorithm with the given key.
+func NewAead(alg AeadAlgorithm, key []byte) (cipher.AEAD, error) {
+	switch alg {
+	case AesGcm:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	case ChaCha20Poly1305:
+		return chacha20poly1305.New(key)
+	default:
+		return nil, ErrUnknownAlgorithm
+	}
+}
+
+// AeadEncrypt encrypts src with a random nonce, which is prepended to the result.
+func AeadEncrypt(aead cipher.AEAD, src, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(src)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, src, additionalData), nil
+}
+
+// AeadDecrypt decrypts src produced by AeadEncrypt.
+func AeadDecrypt(aead cipher.AEAD, src, additionalData []byte) ([]byte, error) {
+	if len(src) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrInvalidEnvelope
+	}
+
+	nonce, ciphertext := src[:aead.NonceSize()], src[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
+
+// NewKeyring returns an empty Keyring.
+func NewKeyring() *Keyring {
+	return &Keyring{
+		keys: make(map[string]keyringEntry),
+	}
+}
+
+// Add adds a key to the keyring. The first key added becomes the primary key.
+// ErrDuplicateKeyId is returned if the id is already in the keyring,
+// because replacing the key makes the envelopes encrypted with it undecryptable.
+func (k *Keyring) Add(id string, alg AeadAlgorithm, key []byte) error {
+	return k.set(id, alg, key, false)
+}
+
+// Replace replaces the key with the given id, like fixing a misconfigured key.
+// Envelopes encrypted with the replaced key can't be decrypted anymore, use Rotate to change keys.
+func (k *Keyring) Replace(id string, alg AeadAlgorithm, key []byte) error {
+	return k.set(id, alg, key, true)
+}
+
+// Rotate adds a key to the keyring and makes it the primary key.
+// Envelopes encrypted with previous keys can still be decrypted.
+func (k *Keyring) Rotate(id string, alg AeadAlgorithm, key []byte) error {
+	if err := k.Add(id, alg, key); err != nil {
+		return err
+	}
+
+	return k.SetPrimary(id)
+}
+
+// SetPrimary makes the key with the given id the one used to encrypt.
+func (k *Keyring) SetPrimary(id string) error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if _, ok := k.keys[id]; !ok {
+		return ErrUnknownKey
+	}
+
+	k.primary = id
+	return nil
+}
+
+// Primary returns the id of the primary key.
+func (k *Keyring) Primary() string {
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+
+	return k.primary
+}
+
+// Remove removes a retired key, envelopes encrypted with it can't be decrypted anymore.
+// The primary key can't be removed.
+func (k *Keyring) Remove(id string) error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if id == k.primary {
+		return fmt.Errorf("can't remove primary key %q", id)
+	}
+
+	delete(k.keys, id)
+	return nil
+}
+
+func (k *Keyring) set(id string, alg AeadAlgorithm, key []byte, replace bool) error {
+	if len(id) == 0 || len(id) > maxKeyIdLen {
+		return ErrInvalidKeyId
+	}
+
+	aead, err := NewAead(alg, key)
+	if err != nil {
+		return err
+	}
+
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	_, ok := k.keys[id]
+	switch {
+	case ok && !replace:
+		return ErrDuplicateKeyId
+	case !ok && replace:
+		return ErrUnknownKey
+	}
+
+	k.keys[id] = keyringEntry{
+		alg:  alg,
+		aead: aead,
+	}
+	if len(k.primary) == 0 {
+		k.primary = id
+	}
+
+	return nil
+}
+
+// Encrypt encrypts src with the primary key and returns a versioned envelope
+// holding the algorithm, the key id, the nonce and the ciphertext.
+// The envelope header is authenticated together with additionalData.
+func (k *Keyring) Encrypt(src, additionalData []byte) ([]byte, error) {
+	k.lock.RLock()
+	id := k.primary
+	entry, ok := k.keys[id]
+	k.lock.RUnlock()
+	if !ok {
+		return nil, ErrNoPrimaryKey
+	}
+
+	header := make([]byte, 0, envelopeMinBytes+len(id))
+	header = append(header, envelopeVersion, byte(entry.alg), byte(len(id)))
+	header = append(header, id...)
+
+	sealed, err := AeadEncrypt(entry.aead, src, envelopeAdditionalData(header, additionalData))
+	if err != nil {
+		return nil, err
+	}
+
+	return append(header, sealed...), nil
+}
+
+// Decrypt decrypts an envelope produced by Encrypt, with whichever key of the
+// keyring it was encrypted with.
+func (k *Keyring) Decrypt(envelope, additionalData []byte) ([]byte, error) {
+	id, err := EnvelopeKeyId(envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	k.lock.RLock()
+	entry, ok := k.keys[id]
+	k.lock.RUnlock()
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if AeadAlgorithm(envelope[1]) != entry.alg {
+		return nil, ErrUnknownAlgorithm
+	}
+
+	headerLen := envelopeMinBytes + len(id)
+	return AeadDecrypt(entry.aead, envelope[headerLen:],
+		envelopeAdditionalData(envelope[:headerLen], additionalData))
+}
+
+// EncryptBase64 encrypts src and returns the base64 encoded envelope.
+func (k *Keyring) EncryptBase64(src []byte) (string, error) {
+	envelope, err := k.Encrypt(src, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(envelope), nil
+}
+
+// DecryptBase64 decrypts a base64 encoded envelope.
+func (k *Keyring) DecryptBase64(src string) ([]byte, error) {
+	envelope, err := base64.StdEncoding.DecodeString(src)
+	if err != nil {
+		return nil, err
+	}
+
+	return k.Decrypt(envelope, nil)
+}
+
+// NeedsRotation reports whether the envelope was encrypted with a key other than the primary key.
+func (k *Keyring) NeedsRotation(envelope []byte) (bool, error) {
+	id, err := EnvelopeKeyId(envelope)
+	if err != nil {
+		return false, err
+	}
+
+	return id != k.Primary(), nil
+}
+
+// Reencrypt decrypts the envelope and encrypts it again with the primary key.
+func (k *Keyring) Reencrypt(envelope, additionalData []byte) ([]byte, error) {
+	plain, err := k.Decrypt(envelope, additionalData)
+	if err != nil {
+		return nil, err
+	}
+
+	return k.Encrypt(plain, additionalData)
+}
+
+// MigrateEcb decrypts src encrypted by EcbEncrypt with ecbKey and returns it as
+// an envelope encrypted with the primary key.
+func (k *Keyring) MigrateEcb(ecbKey, src []byte) ([]byte, error) {
+	plain, err := EcbDecrypt(ecbKey, src)
+	if err != nil {
+		return nil, err
+	}
+
+	return k.Encrypt(plain, nil)
+}
+
+// EnvelopeKeyId returns the id of the key the envelope was encrypted with.
+func EnvelopeKeyId(envelope []byte) (string, error) {
+	if len(envelope) < envelopeMinBytes || envelope[0] != envelopeVersion {
+		return "", ErrInvalidEnvelope
+	}
+
+	idLen := int(envelope[2])
+	if idLen == 0 || len(envelope) < envelopeMinBytes+idLen {
+		return "", ErrInvalidEnvelope
+	}
+
+	return string(envelope[envelopeMinBytes : envelopeMinBytes+idLen]), nil
+}
+
+func envelopeAdditionalData(header, additionalData []byte) []byte {
+	ad := make([]byte, 0, len(header)+len(additionalData))
+	ad = append(ad, header...)
+	return append(ad, additionalData...)
+}
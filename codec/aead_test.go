@@ -0,0 +1,149 @@
+package codec
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAead(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	for _, alg := range []AeadAlgorithm{AesGcm, ChaCha20Poly1305} {
+		aead, err := NewAead(alg, key)
+		assert.Nil(t, err)
+
+		dst, err := AeadEncrypt(aead, []byte("hello"), []byte("ad"))
+		assert.Nil(t, err)
+		src, err := AeadDecrypt(aead, dst, []byte("ad"))
+		assert.Nil(t, err)
+		assert.Equal(t, "hello", string(src))
+
+		_, err = AeadDecrypt(aead, dst, []byte("other"))
+		assert.NotNil(t, err)
+		_, err = AeadDecrypt(aead, dst[:4], nil)
+		assert.Equal(t, ErrInvalidEnvelope, err)
+	}
+
+	_, err := NewAead(AesGcm, []byte("short"))
+	assert.NotNil(t, err)
+	_, err = NewAead(ChaCha20Poly1305, []byte("short"))
+	assert.NotNil(t, err)
+	_, err = NewAead(AeadAlgorithm(9), key)
+	assert.Equal(t, ErrUnknownAlgorithm, err)
+	assert.Equal(t, "AES-GCM", AesGcm.String())
+	assert.Equal(t, "ChaCha20-Poly1305", ChaCha20Poly1305.String())
+	assert.Equal(t, "unknown(9)", AeadAlgorithm(9).String())
+}
+
+func TestKeyring(t *testing.T) {
+	ring := NewKeyring()
+	_, err := ring.Encrypt([]byte("hello"), nil)
+	assert.Equal(t, ErrNoPrimaryKey, err)
+	assert.Equal(t, ErrInvalidKeyId, ring.Add("", AesGcm, bytes.Repeat([]byte{1}, 16)))
+
+	assert.Nil(t, ring.Add("v1", AesGcm, bytes.Repeat([]byte{1}, 16)))
+	assert.Equal(t, "v1", ring.Primary())
+	old, err := ring.Encrypt([]byte("hello"), []byte("user:1"))
+	assert.Nil(t, err)
+	id, err := EnvelopeKeyId(old)
+	assert.Nil(t, err)
+	assert.Equal(t, "v1", id)
+
+	// re-adding a key id doesn't replace the key
+	assert.Equal(t, ErrDuplicateKeyId, ring.Add("v1", AesGcm, bytes.Repeat([]byte{9}, 16)))
+	assert.Equal(t, ErrDuplicateKeyId, ring.Rotate("v1", AesGcm, bytes.Repeat([]byte{9}, 16)))
+	assert.Equal(t, ErrUnknownKey, ring.Replace("v0", AesGcm, bytes.Repeat([]byte{9}, 16)))
+	src, err := ring.Decrypt(old, []byte("user:1"))
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", string(src))
+
+	assert.Nil(t, ring.Rotate("v2", ChaCha20Poly1305, bytes.Repeat([]byte{2}, 32)))
+	assert.Equal(t, "v2", ring.Primary())
+	cur, err := ring.Encrypt([]byte("world"), nil)
+	assert.Nil(t, err)
+
+	rotate, err := ring.NeedsRotation(old)
+	assert.Nil(t, err)
+	assert.True(t, rotate)
+	rotate, err = ring.NeedsRotation(cur)
+	assert.Nil(t, err)
+	assert.False(t, rotate)
+
+	src, err = ring.Decrypt(old, []byte("user:1"))
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", string(src))
+	_, err = ring.Decrypt(old, []byte("user:2"))
+	assert.NotNil(t, err)
+	src, err = ring.Decrypt(cur, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "world", string(src))
+
+	renewed, err := ring.Reencrypt(old, []byte("user:1"))
+	assert.Nil(t, err)
+	id, err = EnvelopeKeyId(renewed)
+	assert.Nil(t, err)
+	assert.Equal(t, "v2", id)
+
+	assert.NotNil(t, ring.Remove("v2"))
+	assert.Nil(t, ring.Remove("v1"))
+	_, err = ring.Decrypt(old, []byte("user:1"))
+	assert.Equal(t, ErrUnknownKey, err)
+	assert.Equal(t, ErrUnknownKey, ring.SetPrimary("v1"))
+
+	// replaced keys can't decrypt the previous envelopes
+	assert.Nil(t, ring.Replace("v2", AesGcm, bytes.Repeat([]byte{3}, 16)))
+	_, err = ring.Decrypt(cur, nil)
+	assert.NotNil(t, err)
+}
+
+func TestKeyringTamper(t *testing.T) {
+	ring := NewKeyring()
+	assert.Nil(t, ring.Add("k", AesGcm, bytes.Repeat([]byte{1}, 32)))
+	assert.Nil(t, ring.Add("j", AesGcm, bytes.Repeat([]byte{1}, 32)))
+	envelope, err := ring.Encrypt([]byte("hello"), nil)
+	assert.Nil(t, err)
+
+	// switching the key id invalidates the authenticated header
+	forged := append([]byte{}, envelope...)
+	forged[3] = 'j'
+	_, err = ring.Decrypt(forged, nil)
+	assert.NotNil(t, err)
+
+	forged = append([]byte{}, envelope...)
+	forged[1] = byte(ChaCha20Poly1305)
+	_, err = ring.Decrypt(forged, nil)
+	assert.Equal(t, ErrUnknownAlgorithm, err)
+
+	for _, bad := range [][]byte{nil, {2, 1, 1, 'k'}, {1, 1, 0}, {1, 1, 5, 'k'}} {
+		_, err = ring.Decrypt(bad, nil)
+		assert.Equal(t, ErrInvalidEnvelope, err)
+	}
+}
+
+func TestKeyringBase64(t *testing.T) {
+	ring := NewKeyring()
+	assert.Nil(t, ring.Add("k", ChaCha20Poly1305, bytes.Repeat([]byte{3}, 32)))
+
+	enc, err := ring.EncryptBase64([]byte("hello"))
+	assert.Nil(t, err)
+	dec, err := ring.DecryptBase64(enc)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", string(dec))
+	_, err = ring.DecryptBase64("@@@")
+	assert.NotNil(t, err)
+}
+
+func TestKeyringMigrateEcb(t *testing.T) {
+	ecbKey := []byte("q4t7w!z%C*F-JaNdRgUjXn2r5u8x/A?D")
+	legacy, err := EcbEncrypt(ecbKey, []byte("secret"))
+	assert.Nil(t, err)
+
+	ring := NewKeyring()
+	assert.Nil(t, ring.Add("k", AesGcm, bytes.Repeat([]byte{1}, 32)))
+	envelope, err := ring.MigrateEcb(ecbKey, legacy)
+	assert.Nil(t, err)
+	src, err := ring.Decrypt(envelope, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "secret", string(src))
+}
@@ -6,8 +6,6 @@ import (
 	"crypto/cipher"
 	"encoding/base64"
 	"errors"
-
-	"github.com/tp-life/utils/logx"
 )
 
 // ErrPaddingSize indicates bad padding size.
@@ -37,13 +35,9 @@ func (x *ecbEncrypter) BlockSize() int { return x.blockSize }
 
 // CryptBlocks encrypts a number of blocks. The length of src must be a multiple of
 // the block size. Dst and src must overlap entirely or not at all.
+// Dst is left untouched if src is not full blocks or dst is smaller than src.
 func (x *ecbEncrypter) CryptBlocks(dst, src []byte) {
-	if len(src)%x.blockSize != 0 {
-		logx.Error("crypto/cipher: input not full blocks")
-		return
-	}
-	if len(dst) < len(src) {
-		logx.Error("crypto/cipher: output smaller than input")
+	if len(src)%x.blockSize != 0 || len(dst) < len(src) {
 		return
 	}
 
@@ -68,13 +62,9 @@ func (x *ecbDecrypter) BlockSize() int {
 
 // CryptBlocks decrypts a number of blocks. The length of src must be a multiple of
 // the block size. Dst and src must overlap entirely or not at all.
+// Dst is left untouched if src is not full blocks or dst is smaller than src.
 func (x *ecbDecrypter) CryptBlocks(dst, src []byte) {
-	if len(src)%x.blockSize != 0 {
-		logx.Error("crypto/cipher: input not full blocks")
-		return
-	}
-	if len(dst) < len(src) {
-		logx.Error("crypto/cipher: output smaller than input")
+	if len(src)%x.blockSize != 0 || len(dst) < len(src) {
 		return
 	}
 
@@ -89,7 +79,6 @@ func (x *ecbDecrypter) CryptBlocks(dst, src []byte) {
 func EcbDecrypt(key, src []byte) ([]byte, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
-		logx.Errorf("Decrypt key error: % x", key)
 		return nil, err
 	}
 
@@ -125,7 +114,6 @@ func EcbDecryptBase64(key, src string) (string, error) {
 func EcbEncrypt(key, src []byte) ([]byte, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
-		logx.Errorf("Encrypt key error: % x", key)
 		return nil, err
 	}
 
@@ -0,0 +1,359 @@
+package codec
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const identityEncoding = "identity"
+
+var (
+	// GzipCompressor is the gzip Compressor with default compression level.
+	GzipCompressor = MustNewGzipCompressor(gzip.DefaultCompression)
+	// ZlibCompressor is the zlib Compressor with default compression level.
+	ZlibCompressor = MustNewZlibCompressor(zlib.DefaultCompression)
+	// DeflateCompressor is the raw deflate Compressor with default compression level.
+	DeflateCompressor = MustNewDeflateCompressor(flate.DefaultCompression)
+
+	encodings = newEncodingRegistry()
+)
+
+type (
+	// Compressor represents a streaming compression format.
+	// Writers must be closed to flush the compressed stream, closing them
+	// doesn't close the underlying writer. Likewise for readers.
+	Compressor interface {
+		Name() string
+		NewWriter(w io.Writer) (io.WriteCloser, error)
+		NewReader(r io.Reader) (io.ReadCloser, error)
+	}
+
+	resetWriter interface {
+		io.WriteCloser
+		Reset(w io.Writer)
+	}
+
+	// pooledCompressor reuses writers and readers, which hold large buffers.
+	pooledCompressor struct {
+		name        string
+		writers     sync.Pool
+		readers     sync.Pool
+		newWriter   func(w io.Writer) (resetWriter, error)
+		newReader   func(r io.Reader) (io.ReadCloser, error)
+		resetReader func(rc io.ReadCloser, r io.Reader) error
+	}
+
+	pooledWriter struct {
+		resetWriter
+		pool *sync.Pool
+	}
+
+	pooledReader struct {
+		io.ReadCloser
+		pool *sync.Pool
+	}
+
+	encodingRegistry struct {
+		lock        sync.RWMutex
+		compressors map[string]Compressor
+		advertised  []string
+	}
+)
+
+// NewGzipCompressor returns a pooled gzip Compressor with the given level.
+func NewGzipCompressor(level int) (Compressor, error) {
+	return newPooledCompressor("gzip", func(w io.Writer) (resetWriter, error) {
+		return gzip.NewWriterLevel(w, level)
+	}, func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	}, func(rc io.ReadCloser, r io.Reader) error {
+		return rc.(*gzip.Reader).Reset(r)
+	})
+}
+
+// MustNewGzipCompressor is like NewGzipCompressor but panics on an invalid level.
+func MustNewGzipCompressor(level int) Compressor {
+	return mustCompressor(NewGzipCompressor(level))
+}
+
+// NewZlibCompressor returns a pooled zlib Compressor with the given level.
+func NewZlibCompressor(level int) (Compressor, error) {
+	return newPooledCompressor("zlib", func(w io.Writer) (resetWriter, error) {
+		return zlib.NewWriterLevel(w, level)
+	}, zlib.NewReader, func(rc io.ReadCloser, r io.Reader) error {
+		return rc.(zlib.Resetter).Reset(r, nil)
+	})
+}
+
+// MustNewZlibCompressor is like NewZlibCompressor but panics on an invalid level.
+func MustNewZlibCompressor(level int) Compressor {
+	return mustCompressor(NewZlibCompressor(level))
+}
+
+// NewDeflateCompressor returns a pooled raw deflate Compressor with the given level.
+func NewDeflateCompressor(level int) (Compressor, error) {
+	return newPooledCompressor("deflate", func(w io.Writer) (resetWriter, error) {
+		return flate.NewWriter(w, level)
+	}, func(r io.Reader) (io.ReadCloser, error) {
+		return flate.NewReader(r), nil
+	}, func(rc io.ReadCloser, r io.Reader) error {
+		return rc.(flate.Resetter).Reset(r, nil)
+	})
+}
+
+// MustNewDeflateCompressor is like NewDeflateCompressor but panics on an invalid level.
+func MustNewDeflateCompressor(level int) Compressor {
+	return mustCompressor(NewDeflateCompressor(level))
+}
+
+// Compress compresses bs with c.
+func Compress(c Compressor, bs []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := c.NewWriter(&buf)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = w.Write(bs); err != nil {
+		w.Close()
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Decompress uncompresses bs with c, the output is limited to 100MB.
+func Decompress(c Compressor, bs []byte) ([]byte, error) {
+	r, err := c.NewReader(bytes.NewReader(bs))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var buf bytes.Buffer
+	if _, err = io.Copy(&buf, io.LimitReader(r, unzipLimit)); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// RegisterEncoding registers c for the given HTTP content coding, e.g. "br".
+// Registered encodings are advertised by AcceptEncoding and picked by NegotiateEncoding.
+func RegisterEncoding(encoding string, c Compressor) {
+	encodings.register(encoding, c, true)
+}
+
+// CompressorForEncoding returns the Compressor of the given Content-Encoding header value.
+func CompressorForEncoding(encoding string) (Compressor, bool) {
+	return encodings.get(encoding)
+}
+
+// AcceptEncoding returns an Accept-Encoding header value listing the registered encodings.
+func AcceptEncoding() string {
+	encodings.lock.RLock()
+	defer encodings.lock.RUnlock()
+
+	return strings.Join(encodings.advertised, ", ")
+}
+
+// NegotiateEncoding picks the registered encoding preferred by the given
+// Accept-Encoding header value. It returns an empty encoding and a nil
+// Compressor if the response should not be compressed.
+func NegotiateEncoding(acceptEncoding string) (string, Compressor) {
+	type candidate struct {
+		encoding string
+		q        float64
+		order    int
+	}
+
+	encodings.lock.RLock()
+	defer encodings.lock.RUnlock()
+
+	var candidates []candidate
+	explicit := make(map[string]bool)
+	wildcard := -1.0
+	for i, part := range strings.Split(acceptEncoding, ",") {
+		encoding, q := parseQuality(part)
+		switch {
+		case len(encoding) == 0:
+			continue
+		case encoding == "*":
+			wildcard = q
+			continue
+		}
+
+		explicit[encoding] = true
+		if _, ok := encodings.compressors[encoding]; ok && q > 0 {
+			candidates = append(candidates, candidate{encoding: encoding, q: q, order: i})
+		}
+	}
+	if wildcard > 0 {
+		for i, encoding := range encodings.advertised {
+			if !explicit[encoding] {
+				candidates = append(candidates, candidate{encoding: encoding, q: wildcard, order: len(explicit) + i})
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return "", nil
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].q != candidates[j].q {
+			return candidates[i].q > candidates[j].q
+		}
+		return candidates[i].order < candidates[j].order
+	})
+
+	best := candidates[0].encoding
+	return best, encodings.compressors[best]
+}
+
+func newPooledCompressor(name string, newWriter func(w io.Writer) (resetWriter, error),
+	newReader func(r io.Reader) (io.ReadCloser, error),
+	resetReader func(rc io.ReadCloser, r io.Reader) error) (Compressor, error) {
+	// validate the options, e.g. the level, up front
+	w, err := newWriter(io.Discard)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &pooledCompressor{
+		name:        name,
+		newWriter:   newWriter,
+		newReader:   newReader,
+		resetReader: resetReader,
+	}
+	c.writers.Put(w)
+
+	return c, nil
+}
+
+func (c *pooledCompressor) Name() string {
+	return c.name
+}
+
+func (c *pooledCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	if pw, ok := c.writers.Get().(resetWriter); ok {
+		pw.Reset(w)
+		return &pooledWriter{resetWriter: pw, pool: &c.writers}, nil
+	}
+
+	pw, err := c.newWriter(w)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pooledWriter{resetWriter: pw, pool: &c.writers}, nil
+}
+
+func (c *pooledCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	if pr, ok := c.readers.Get().(io.ReadCloser); ok {
+		if err := c.resetReader(pr, r); err != nil {
+			return nil, err
+		}
+		return &pooledReader{ReadCloser: pr, pool: &c.readers}, nil
+	}
+
+	pr, err := c.newReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pooledReader{ReadCloser: pr, pool: &c.readers}, nil
+}
+
+func (w *pooledWriter) Close() error {
+	if w.resetWriter == nil {
+		return nil
+	}
+
+	err := w.resetWriter.Close()
+	w.pool.Put(w.resetWriter)
+	w.resetWriter = nil
+	return err
+}
+
+func (r *pooledReader) Close() error {
+	if r.ReadCloser == nil {
+		return nil
+	}
+
+	err := r.ReadCloser.Close()
+	r.pool.Put(r.ReadCloser)
+	r.ReadCloser = nil
+	return err
+}
+
+func newEncodingRegistry() *encodingRegistry {
+	r := &encodingRegistry{
+		compressors: make(map[string]Compressor),
+	}
+	r.register("gzip", GzipCompressor, true)
+	r.register("x-gzip", GzipCompressor, false)
+	// the HTTP deflate content coding is the zlib format, see RFC 9110
+	r.register("deflate", ZlibCompressor, true)
+	r.register(SnappyEncoding, SnappyCompressor, false)
+	return r
+}
+
+func (r *encodingRegistry) register(encoding string, c Compressor, advertise bool) {
+	encoding = strings.ToLower(strings.TrimSpace(encoding))
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if advertise && !slices.Contains(r.advertised, encoding) {
+		r.advertised = append(r.advertised, encoding)
+	}
+	r.compressors[encoding] = c
+}
+
+func (r *encodingRegistry) get(encoding string) (Compressor, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	c, ok := r.compressors[strings.ToLower(strings.TrimSpace(encoding))]
+	return c, ok
+}
+
+func parseQuality(part string) (string, float64) {
+	encoding, params, _ := strings.Cut(part, ";")
+	encoding = strings.ToLower(strings.TrimSpace(encoding))
+	if encoding == identityEncoding {
+		return "", 0
+	}
+
+	q := 1.0
+	for _, param := range strings.Split(params, ";") {
+		key, val, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok || strings.TrimSpace(key) != "q" {
+			continue
+		}
+		if v, err := strconv.ParseFloat(strings.TrimSpace(val), 64); err == nil {
+			q = v
+		}
+	}
+
+	return encoding, q
+}
+
+func mustCompressor(c Compressor, err error) Compressor {
+	if err != nil {
+		panic(err)
+	}
+
+	return c
+}
@@ -0,0 +1,145 @@
+package codec
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressors(t *testing.T) {
+	var buf bytes.Buffer
+	for i := 0; i < 100000; i++ {
+		fmt.Fprint(&buf, i)
+	}
+
+	for _, c := range []Compressor{GzipCompressor, ZlibCompressor, DeflateCompressor, SnappyCompressor} {
+		t.Run(c.Name(), func(t *testing.T) {
+			for i := 0; i < 3; i++ {
+				compressed, err := Compress(c, buf.Bytes())
+				assert.Nil(t, err)
+				assert.True(t, len(compressed) < buf.Len())
+
+				actual, err := Decompress(c, compressed)
+				assert.Nil(t, err)
+				assert.Equal(t, buf.Bytes(), actual)
+			}
+
+			empty, err := Compress(c, nil)
+			assert.Nil(t, err)
+			actual, err := Decompress(c, empty)
+			assert.Nil(t, err)
+			assert.Empty(t, actual)
+		})
+	}
+}
+
+func TestCompressorStreaming(t *testing.T) {
+	pr, pw := io.Pipe()
+	go func() {
+		w, err := GzipCompressor.NewWriter(pw)
+		assert.Nil(t, err)
+		for i := 0; i < 1000; i++ {
+			fmt.Fprintf(w, "line %d\n", i)
+		}
+		assert.Nil(t, w.Close())
+		assert.Nil(t, w.Close())
+		pw.Close()
+	}()
+
+	r, err := GzipCompressor.NewReader(pr)
+	assert.Nil(t, err)
+	content, err := io.ReadAll(r)
+	assert.Nil(t, err)
+	assert.Nil(t, r.Close())
+	assert.Nil(t, r.Close())
+	assert.Equal(t, 1000, bytes.Count(content, []byte("\n")))
+}
+
+func TestCompressorErrors(t *testing.T) {
+	_, err := NewGzipCompressor(100)
+	assert.NotNil(t, err)
+	_, err = NewZlibCompressor(100)
+	assert.NotNil(t, err)
+	_, err = NewDeflateCompressor(100)
+	assert.NotNil(t, err)
+	assert.Panics(t, func() {
+		MustNewGzipCompressor(100)
+	})
+
+	c, err := NewDeflateCompressor(flate.BestSpeed)
+	assert.Nil(t, err)
+	assert.Equal(t, "deflate", c.Name())
+
+	_, err = Decompress(GzipCompressor, []byte("invalid input"))
+	assert.ErrorIs(t, err, gzip.ErrHeader)
+	_, err = Decompress(ZlibCompressor, []byte("invalid"))
+	assert.NotNil(t, err)
+}
+
+func TestEncodings(t *testing.T) {
+	c, ok := CompressorForEncoding(" GZIP ")
+	assert.True(t, ok)
+	assert.Equal(t, GzipCompressor, c)
+	c, ok = CompressorForEncoding("x-gzip")
+	assert.True(t, ok)
+	assert.Equal(t, GzipCompressor, c)
+	c, ok = CompressorForEncoding("deflate")
+	assert.True(t, ok)
+	assert.Equal(t, ZlibCompressor, c)
+	_, ok = CompressorForEncoding("br")
+	assert.False(t, ok)
+	assert.Equal(t, "gzip, deflate", AcceptEncoding())
+
+	tests := []struct {
+		accept   string
+		expected string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"br", ""},
+		{"gzip", "gzip"},
+		{"deflate, gzip", "deflate"},
+		{"deflate;q=0.5, gzip;q=0.8", "gzip"},
+		{"gzip;q=0, deflate", "deflate"},
+		{"br, *;q=0.1", "gzip"},
+		{"gzip;q=0, *", "deflate"},
+		{"*;q=0", ""},
+		{"x-snappy-framed;q=1.0, gzip;q=0.9", "x-snappy-framed"},
+		{"x-snappy-framed;q=0.5, *", "gzip"},
+	}
+	for _, test := range tests {
+		t.Run(test.accept, func(t *testing.T) {
+			encoding, c := NegotiateEncoding(test.accept)
+			assert.Equal(t, test.expected, encoding)
+			if len(test.expected) == 0 {
+				assert.Nil(t, c)
+			} else {
+				assert.NotNil(t, c)
+			}
+		})
+	}
+}
+
+func TestRegisterEncoding(t *testing.T) {
+	RegisterEncoding("x-test", DeflateCompressor)
+	defer func() {
+		encodings = newEncodingRegistry()
+	}()
+
+	c, ok := CompressorForEncoding("x-test")
+	assert.True(t, ok)
+	assert.Equal(t, DeflateCompressor, c)
+	assert.Equal(t, "gzip, deflate, x-test", AcceptEncoding())
+	encoding, _ := NegotiateEncoding("x-test")
+	assert.Equal(t, "x-test", encoding)
+
+	RegisterEncoding(SnappyEncoding, SnappyCompressor)
+	assert.Equal(t, "gzip, deflate, x-test, x-snappy-framed", AcceptEncoding())
+	encoding, _ = NegotiateEncoding("br, *")
+	assert.Equal(t, "gzip", encoding)
+}
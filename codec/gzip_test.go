@@ -16,7 +16,8 @@ func TestGzip(t *testing.T) {
 		fmt.Fprint(&buf, i)
 	}
 
-	bs := Gzip(buf.Bytes())
+	bs, err := Gzip(buf.Bytes())
+	assert.Nil(t, err)
 	actual, err := Gunzip(bs)
 
 	assert.Nil(t, err)
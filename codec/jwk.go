@@ -0,0 +1,302 @@
+package codec
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+)
+
+// ErrInvalidJwk indicates a JSON Web Key that is malformed or of an unsupported type.
+var ErrInvalidJwk = errors.New("invalid jwk")
+
+type (
+	// Jwk is a JSON Web Key as in RFC 7517, supporting RSA, EC P-256 and Ed25519 keys.
+	Jwk struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid,omitempty"`
+		Alg string `json:"alg,omitempty"`
+		Use string `json:"use,omitempty"`
+		Crv string `json:"crv,omitempty"`
+		N   string `json:"n,omitempty"`
+		E   string `json:"e,omitempty"`
+		X   string `json:"x,omitempty"`
+		Y   string `json:"y,omitempty"`
+		D   string `json:"d,omitempty"`
+		P   string `json:"p,omitempty"`
+		Q   string `json:"q,omitempty"`
+	}
+
+	// JwkSet is a set of JSON Web Keys.
+	JwkSet struct {
+		Keys []Jwk `json:"keys"`
+	}
+)
+
+// ParseJwk parses a JSON Web Key.
+func ParseJwk(content []byte) (Jwk, error) {
+	var key Jwk
+	if err := json.Unmarshal(content, &key); err != nil {
+		return key, err
+	}
+
+	return key, nil
+}
+
+// ParseJwkSet parses a JSON Web Key Set.
+func ParseJwkSet(content []byte) (JwkSet, error) {
+	var set JwkSet
+	if err := json.Unmarshal(content, &set); err != nil {
+		return set, err
+	}
+
+	return set, nil
+}
+
+// Verifiers returns the verifiers of all keys in the set, indexed by key id.
+// The keys of unsupported types, curves or algorithms are skipped,
+// so that a set published by an identity provider can be used as long as the keys in use are supported.
+func (s JwkSet) Verifiers() (map[string]Verifier, error) {
+	verifiers := make(map[string]Verifier, len(s.Keys))
+	for _, key := range s.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+
+		verifier, err := key.Verifier()
+		if errors.Is(err, ErrUnsupportedKey) || errors.Is(err, ErrNotP256Key) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		verifiers[key.Kid] = verifier
+	}
+
+	return verifiers, nil
+}
+
+// PublicKey returns the public key of the JWK.
+func (k Jwk) PublicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeJwkInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeJwkInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() {
+			return nil, ErrInvalidJwk
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, ErrNotP256Key
+		}
+		x, err := decodeJwkInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeJwkInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		if !key.Curve.IsOnCurve(x, y) {
+			return nil, ErrInvalidJwk
+		}
+		return key, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, ErrUnsupportedKey
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, ErrInvalidJwk
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, ErrUnsupportedKey
+	}
+}
+
+// PrivateKey returns the private key of the JWK.
+func (k Jwk) PrivateKey() (crypto.PrivateKey, error) {
+	if len(k.D) == 0 {
+		return nil, ErrPrivateKey
+	}
+
+	pub, err := k.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		d, err := decodeJwkInt(k.D)
+		if err != nil {
+			return nil, err
+		}
+		p, err := decodeJwkInt(k.P)
+		if err != nil {
+			return nil, err
+		}
+		q, err := decodeJwkInt(k.Q)
+		if err != nil {
+			return nil, err
+		}
+		priv := &rsa.PrivateKey{PublicKey: *key, D: d, Primes: []*big.Int{p, q}}
+		if err := priv.Validate(); err != nil {
+			return nil, err
+		}
+		priv.Precompute()
+		return priv, nil
+	case *ecdsa.PublicKey:
+		d, err := decodeJwkInt(k.D)
+		if err != nil {
+			return nil, err
+		}
+		// d must be in [1, n-1] and derive the public key x and y
+		if d.Sign() <= 0 || d.Cmp(key.Curve.Params().N) >= 0 {
+			return nil, ErrInvalidJwk
+		}
+		if x, y := key.Curve.ScalarBaseMult(d.Bytes()); x.Cmp(key.X) != 0 || y.Cmp(key.Y) != 0 {
+			return nil, ErrInvalidJwk
+		}
+		return &ecdsa.PrivateKey{PublicKey: *key, D: d}, nil
+	case ed25519.PublicKey:
+		seed, err := base64.RawURLEncoding.DecodeString(k.D)
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return nil, ErrInvalidJwk
+		}
+		priv := ed25519.NewKeyFromSeed(seed)
+		if !key.Equal(priv.Public()) {
+			return nil, ErrInvalidJwk
+		}
+		return priv, nil
+	default:
+		return nil, ErrUnsupportedKey
+	}
+}
+
+// Signer returns a Signer with the private key of the JWK.
+// The algorithm is taken from alg if set, otherwise it's the default one of the key type, see NewSigner.
+func (k Jwk) Signer() (Signer, error) {
+	key, err := k.PrivateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	if rsaKey, ok := key.(*rsa.PrivateKey); ok && k.Alg == AlgRS256 {
+		return NewRsaSigner(rsaKey), nil
+	}
+
+	signer, err := NewSigner(key)
+	if err != nil {
+		return nil, err
+	}
+	if err = k.checkAlgorithm(signer.Algorithm()); err != nil {
+		return nil, err
+	}
+
+	return signer, nil
+}
+
+// Verifier returns a Verifier with the public key of the JWK.
+// The algorithm is taken from alg if set, otherwise it's the default one of the key type, see NewVerifier.
+func (k Jwk) Verifier() (Verifier, error) {
+	key, err := k.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	if rsaKey, ok := key.(*rsa.PublicKey); ok && k.Alg == AlgRS256 {
+		return NewRsaVerifier(rsaKey), nil
+	}
+
+	verifier, err := NewVerifier(key)
+	if err != nil {
+		return nil, err
+	}
+	if err = k.checkAlgorithm(verifier.Algorithm()); err != nil {
+		return nil, err
+	}
+
+	return verifier, nil
+}
+
+// checkAlgorithm checks alg of the JWK against the algorithm of its key type.
+func (k Jwk) checkAlgorithm(alg string) error {
+	switch k.Alg {
+	case "", alg:
+		return nil
+	case AlgRS256, AlgPS256, AlgES256, AlgEdDSA:
+		// a known algorithm that doesn't fit the key type
+		return ErrInvalidJwk
+	default:
+		return ErrUnsupportedKey
+	}
+}
+
+// NewJwk returns the JWK of the given public key with the given key id.
+func NewJwk(kid string, key crypto.PublicKey) (Jwk, error) {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return Jwk{
+			Kty: "RSA",
+			Kid: kid,
+			Alg: AlgPS256,
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		}, nil
+	case *ecdsa.PublicKey:
+		if k.Curve != elliptic.P256() {
+			return Jwk{}, ErrNotP256Key
+		}
+		x := make([]byte, es256KeySize)
+		y := make([]byte, es256KeySize)
+		return Jwk{
+			Kty: "EC",
+			Kid: kid,
+			Alg: AlgES256,
+			Use: "sig",
+			Crv: "P-256",
+			X:   base64.RawURLEncoding.EncodeToString(k.X.FillBytes(x)),
+			Y:   base64.RawURLEncoding.EncodeToString(k.Y.FillBytes(y)),
+		}, nil
+	case ed25519.PublicKey:
+		return Jwk{
+			Kty: "OKP",
+			Kid: kid,
+			Alg: AlgEdDSA,
+			Use: "sig",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(k),
+		}, nil
+	default:
+		return Jwk{}, ErrUnsupportedKey
+	}
+}
+
+func decodeJwkInt(s string) (*big.Int, error) {
+	if len(s) == 0 {
+		return nil, ErrInvalidJwk
+	}
+
+	bs, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidJwk
+	}
+
+	return new(big.Int).SetBytes(bs), nil
+}
@@ -0,0 +1,311 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"time"
+)
+
+const jwtType = "JWT"
+
+var (
+	// ErrTokenMalformed indicates a token that is not a compact serialized JWS.
+	ErrTokenMalformed = errors.New("token is malformed")
+	// ErrTokenAlgorithm indicates a token signed with an algorithm other than the key's.
+	ErrTokenAlgorithm = errors.New("token algorithm doesn't match key")
+	// ErrTokenExpired indicates a token past its exp claim.
+	ErrTokenExpired = errors.New("token is expired")
+	// ErrTokenNotValidYet indicates a token before its nbf claim.
+	ErrTokenNotValidYet = errors.New("token is not valid yet")
+	// ErrTokenNoExpiry indicates a token without exp claim while one is required.
+	ErrTokenNoExpiry = errors.New("token has no expiry")
+	// ErrTokenAudience indicates a token not issued for the expected audience.
+	ErrTokenAudience = errors.New("token audience mismatch")
+	// ErrTokenIssuer indicates a token not issued by the expected issuer.
+	ErrTokenIssuer = errors.New("token issuer mismatch")
+)
+
+type (
+	// JwsHeader is the protected header of a JWS.
+	JwsHeader struct {
+		Alg string `json:"alg"`
+		Typ string `json:"typ,omitempty"`
+		Kid string `json:"kid,omitempty"`
+	}
+
+	// Audience is the aud claim, which may be a single string or an array.
+	Audience []string
+
+	// NumericDate is the seconds since the epoch as in RFC 7519, which may be fractional.
+	NumericDate float64
+
+	// JwtClaims holds the registered claims of RFC 7519.
+	// Embed it in custom claims to have them validated by JwtVerify.
+	JwtClaims struct {
+		Issuer    string      `json:"iss,omitempty"`
+		Subject   string      `json:"sub,omitempty"`
+		Audience  Audience    `json:"aud,omitempty"`
+		ExpiresAt NumericDate `json:"exp,omitempty"`
+		NotBefore NumericDate `json:"nbf,omitempty"`
+		IssuedAt  NumericDate `json:"iat,omitempty"`
+		ID        string      `json:"jti,omitempty"`
+	}
+
+	// KeyResolver returns the verifier for a token with the given header.
+	KeyResolver func(header JwsHeader) (Verifier, error)
+
+	// JwtOption defines the method to customize JWT validation.
+	JwtOption func(opt *jwtOptions)
+
+	jwtOptions struct {
+		leeway        time.Duration
+		audience      string
+		issuer        string
+		now           func() time.Time
+		requireExpiry bool
+	}
+)
+
+// MarshalJSON encodes a single audience as a string.
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+
+	return json.Marshal([]string(a))
+}
+
+// UnmarshalJSON decodes an audience from a string or an array of strings.
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+
+	var multi []string
+	if err := json.Unmarshal(data, &multi); err != nil {
+		return err
+	}
+
+	*a = multi
+	return nil
+}
+
+// Contains reports whether aud is one of the audiences.
+func (a Audience) Contains(aud string) bool {
+	for _, each := range a {
+		if each == aud {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NewNumericDate returns the NumericDate of t, truncated to seconds.
+func NewNumericDate(t time.Time) NumericDate {
+	return NumericDate(t.Unix())
+}
+
+// Time returns d as time.Time.
+func (d NumericDate) Time() time.Time {
+	sec, frac := math.Modf(float64(d))
+	return time.Unix(int64(sec), int64(frac*float64(time.Second)))
+}
+
+// StaticKey returns a KeyResolver that always uses v.
+func StaticKey(v Verifier) KeyResolver {
+	return func(JwsHeader) (Verifier, error) {
+		return v, nil
+	}
+}
+
+// KeySet returns a KeyResolver that picks the verifier by the kid header.
+func KeySet(verifiers map[string]Verifier) KeyResolver {
+	return func(header JwsHeader) (Verifier, error) {
+		v, ok := verifiers[header.Kid]
+		if !ok {
+			return nil, ErrUnknownKey
+		}
+
+		return v, nil
+	}
+}
+
+// WithJwtLeeway customizes the tolerated clock skew when checking exp and nbf.
+func WithJwtLeeway(leeway time.Duration) JwtOption {
+	return func(opt *jwtOptions) {
+		opt.leeway = leeway
+	}
+}
+
+// WithJwtAudience requires the token to be issued for the given audience.
+func WithJwtAudience(aud string) JwtOption {
+	return func(opt *jwtOptions) {
+		opt.audience = aud
+	}
+}
+
+// WithJwtIssuer requires the token to be issued by the given issuer.
+func WithJwtIssuer(iss string) JwtOption {
+	return func(opt *jwtOptions) {
+		opt.issuer = iss
+	}
+}
+
+// WithJwtClock customizes the clock used to check exp and nbf.
+func WithJwtClock(now func() time.Time) JwtOption {
+	return func(opt *jwtOptions) {
+		opt.now = now
+	}
+}
+
+// WithJwtExpiryRequired rejects tokens without exp claim.
+func WithJwtExpiryRequired() JwtOption {
+	return func(opt *jwtOptions) {
+		opt.requireExpiry = true
+	}
+}
+
+// JwsSign returns the compact serialization of payload signed by signer.
+// The kid header is omitted if kid is empty.
+func JwsSign(signer Signer, kid string, payload []byte) (string, error) {
+	return jwsSign(signer, JwsHeader{Alg: signer.Algorithm(), Kid: kid}, payload)
+}
+
+// JwsVerify verifies the compact serialized token and returns its header and payload.
+func JwsVerify(token string, keys KeyResolver) (JwsHeader, []byte, error) {
+	var header JwsHeader
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return header, nil, ErrTokenMalformed
+	}
+
+	rawHeader, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return header, nil, ErrTokenMalformed
+	}
+	if err = json.Unmarshal(rawHeader, &header); err != nil {
+		return header, nil, ErrTokenMalformed
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return header, nil, ErrTokenMalformed
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return header, nil, ErrTokenMalformed
+	}
+
+	verifier, err := keys(header)
+	if err != nil {
+		return header, nil, err
+	}
+	// never let the token choose the algorithm, this also rejects "none"
+	if header.Alg != verifier.Algorithm() {
+		return header, nil, ErrTokenAlgorithm
+	}
+
+	signingInput := token[:len(parts[0])+1+len(parts[1])]
+	if err = verifier.Verify([]byte(signingInput), signature); err != nil {
+		return header, nil, err
+	}
+
+	return header, payload, nil
+}
+
+// JwtSign returns claims encoded as a JWT signed by signer.
+// The kid header is omitted if kid is empty.
+func JwtSign(signer Signer, kid string, claims any) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	return jwsSign(signer, JwsHeader{Alg: signer.Algorithm(), Typ: jwtType, Kid: kid}, payload)
+}
+
+// JwtVerify verifies the token signature, decodes its payload into claims and
+// validates the registered exp, nbf, aud and iss claims.
+func JwtVerify(token string, keys KeyResolver, claims any, opts ...JwtOption) error {
+	_, payload, err := JwsVerify(token, keys)
+	if err != nil {
+		return err
+	}
+
+	var registered JwtClaims
+	if err = json.Unmarshal(payload, &registered); err != nil {
+		return ErrTokenMalformed
+	}
+	if err = registered.Validate(opts...); err != nil {
+		return err
+	}
+
+	if claims == nil {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	return decoder.Decode(claims)
+}
+
+// Validate checks the time, audience and issuer claims with the given options.
+func (c JwtClaims) Validate(opts ...JwtOption) error {
+	o := jwtOptions{
+		now: time.Now,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	now := o.now()
+	if c.ExpiresAt == 0 {
+		if o.requireExpiry {
+			return ErrTokenNoExpiry
+		}
+	} else if !now.Before(c.ExpiresAt.Time().Add(o.leeway)) {
+		return ErrTokenExpired
+	}
+
+	if c.NotBefore != 0 && now.Add(o.leeway).Before(c.NotBefore.Time()) {
+		return ErrTokenNotValidYet
+	}
+
+	if len(o.audience) > 0 && !c.Audience.Contains(o.audience) {
+		return ErrTokenAudience
+	}
+
+	if len(o.issuer) > 0 && c.Issuer != o.issuer {
+		return ErrTokenIssuer
+	}
+
+	return nil
+}
+
+func jwsSign(signer Signer, header JwsHeader, payload []byte) (string, error) {
+	rawHeader, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+
+	var buf strings.Builder
+	buf.WriteString(base64.RawURLEncoding.EncodeToString(rawHeader))
+	buf.WriteByte('.')
+	buf.WriteString(base64.RawURLEncoding.EncodeToString(payload))
+
+	signature, err := signer.Sign([]byte(buf.String()))
+	if err != nil {
+		return "", err
+	}
+
+	buf.WriteByte('.')
+	buf.WriteString(base64.RawURLEncoding.EncodeToString(signature))
+	return buf.String(), nil
+}
@@ -0,0 +1,132 @@
+package codec
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testClaims struct {
+	JwtClaims
+	Role string `json:"role"`
+}
+
+func TestJwt(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+	signer, err := NewEd25519Signer(priv)
+	assert.Nil(t, err)
+	verifier, err := NewEd25519Verifier(pub)
+	assert.Nil(t, err)
+	keys := KeySet(map[string]Verifier{"k1": verifier})
+
+	now := time.Unix(1700000000, 0)
+	clock := WithJwtClock(func() time.Time { return now })
+	claims := testClaims{
+		JwtClaims: JwtClaims{
+			Issuer:    "auth",
+			Audience:  Audience{"api"},
+			ExpiresAt: NewNumericDate(now.Add(time.Minute)),
+			NotBefore: NewNumericDate(now),
+		},
+		Role: "admin",
+	}
+
+	token, err := JwtSign(signer, "k1", claims)
+	assert.Nil(t, err)
+
+	var decoded testClaims
+	assert.Nil(t, JwtVerify(token, keys, &decoded, clock, WithJwtAudience("api"), WithJwtIssuer("auth")))
+	assert.Equal(t, claims, decoded)
+
+	assert.Equal(t, ErrTokenAudience, JwtVerify(token, keys, nil, clock, WithJwtAudience("web")))
+	assert.Equal(t, ErrTokenIssuer, JwtVerify(token, keys, nil, clock, WithJwtIssuer("other")))
+
+	late := WithJwtClock(func() time.Time { return now.Add(time.Minute + time.Second) })
+	assert.Equal(t, ErrTokenExpired, JwtVerify(token, keys, nil, late))
+	assert.Nil(t, JwtVerify(token, keys, nil, late, WithJwtLeeway(5*time.Second)))
+
+	early := WithJwtClock(func() time.Time { return now.Add(-time.Second) })
+	assert.Equal(t, ErrTokenNotValidYet, JwtVerify(token, keys, nil, early))
+	assert.Nil(t, JwtVerify(token, keys, nil, early, WithJwtLeeway(5*time.Second)))
+
+	_, err = JwtSign(signer, "", testClaims{})
+	assert.Nil(t, err)
+	noExp, err := JwtSign(signer, "k1", testClaims{})
+	assert.Nil(t, err)
+	assert.Nil(t, JwtVerify(noExp, keys, nil))
+	assert.Equal(t, ErrTokenNoExpiry, JwtVerify(noExp, keys, nil, WithJwtExpiryRequired()))
+
+	other, err := JwtSign(signer, "k2", claims)
+	assert.Nil(t, err)
+	assert.Equal(t, ErrUnknownKey, JwtVerify(other, keys, nil, clock))
+}
+
+func TestJwsVerifyRejects(t *testing.T) {
+	hs := NewHmacSigner([]byte("secret"))
+	token, err := JwsSign(hs, "", []byte(`{"a":1}`))
+	assert.Nil(t, err)
+
+	header, payload, err := JwsVerify(token, StaticKey(hs))
+	assert.Nil(t, err)
+	assert.Equal(t, AlgHS256, header.Alg)
+	assert.Equal(t, `{"a":1}`, string(payload))
+
+	parts := strings.Split(token, ".")
+	none := base64URL(`{"alg":"none"}`) + "." + parts[1] + "."
+	_, _, err = JwsVerify(none, StaticKey(hs))
+	assert.Equal(t, ErrTokenAlgorithm, err)
+
+	tampered := parts[0] + "." + base64URL(`{"a":2}`) + "." + parts[2]
+	_, _, err = JwsVerify(tampered, StaticKey(hs))
+	assert.Equal(t, ErrInvalidSignature, err)
+
+	for _, bad := range []string{"", "a.b", "!.b.c", base64URL("{") + ".b.c", parts[0] + ".!.c", parts[0] + "." + parts[1] + ".!"} {
+		_, _, err = JwsVerify(bad, StaticKey(hs))
+		assert.Equal(t, ErrTokenMalformed, err, bad)
+	}
+}
+
+func TestNumericDate(t *testing.T) {
+	var claims JwtClaims
+	assert.Nil(t, json.Unmarshal([]byte(`{"exp":1700000000.5,"nbf":1700000000}`), &claims))
+	assert.Equal(t, time.Unix(1700000000, int64(time.Second/2)), claims.ExpiresAt.Time())
+	assert.Equal(t, NewNumericDate(time.Unix(1700000000, 0)), claims.NotBefore)
+
+	clock := func(nsec int64) JwtOption {
+		return WithJwtClock(func() time.Time { return time.Unix(1700000000, nsec) })
+	}
+	assert.Nil(t, claims.Validate(clock(int64(time.Second/4))))
+	assert.Equal(t, ErrTokenExpired, claims.Validate(clock(int64(time.Second/2))))
+
+	bs, err := json.Marshal(JwtClaims{ExpiresAt: NewNumericDate(time.Unix(1700000000, 1))})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"exp":1700000000}`, string(bs))
+}
+
+func TestAudience(t *testing.T) {
+	var claims JwtClaims
+	assert.Nil(t, json.Unmarshal([]byte(`{"aud":"a"}`), &claims))
+	assert.Equal(t, Audience{"a"}, claims.Audience)
+	assert.Nil(t, json.Unmarshal([]byte(`{"aud":["a","b"]}`), &claims))
+	assert.Equal(t, Audience{"a", "b"}, claims.Audience)
+	assert.True(t, claims.Audience.Contains("b"))
+	assert.NotNil(t, json.Unmarshal([]byte(`{"aud":1}`), &claims))
+
+	bs, err := json.Marshal(Audience{"a"})
+	assert.Nil(t, err)
+	assert.Equal(t, `"a"`, string(bs))
+	bs, err = json.Marshal(Audience{"a", "b"})
+	assert.Nil(t, err)
+	assert.Equal(t, `["a","b"]`, string(bs))
+}
+
+func base64URL(s string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
@@ -0,0 +1,385 @@
+package codec
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+)
+
+const (
+	// AlgRS256 is RSASSA-PKCS1-v1_5 using SHA-256.
+	AlgRS256 = "RS256"
+	// AlgPS256 is RSASSA-PSS using SHA-256.
+	AlgPS256 = "PS256"
+	// AlgES256 is ECDSA using P-256 and SHA-256.
+	AlgES256 = "ES256"
+	// AlgEdDSA is Ed25519.
+	AlgEdDSA = "EdDSA"
+	// AlgHS256 is HMAC using SHA-256.
+	AlgHS256 = "HS256"
+
+	es256KeySize = 32
+)
+
+var (
+	// ErrInvalidSignature indicates the signature doesn't match the data.
+	ErrInvalidSignature = errors.New("invalid signature")
+	// ErrUnsupportedKey indicates a key type that can't be used to sign or verify.
+	ErrUnsupportedKey = errors.New("unsupported key type")
+	// ErrNotP256Key indicates an ECDSA key on a curve other than P-256.
+	ErrNotP256Key = errors.New("ecdsa key is not on curve P-256")
+	// ErrInvalidEd25519Key indicates an Ed25519 key of the wrong size.
+	ErrInvalidEd25519Key = errors.New("invalid ed25519 key size")
+	// ErrPemBlock indicates the content is not PEM encoded.
+	ErrPemBlock = errors.New("failed to decode PEM block")
+)
+
+type (
+	// Signer represents a signer of a JWS algorithm.
+	Signer interface {
+		Algorithm() string
+		Sign(data []byte) ([]byte, error)
+	}
+
+	// Verifier represents a signature verifier of a JWS algorithm.
+	Verifier interface {
+		Algorithm() string
+		Verify(data, signature []byte) error
+	}
+
+	// SignVerifier represents a symmetric algorithm that both signs and verifies.
+	SignVerifier interface {
+		Signer
+		Verifier
+	}
+
+	rsaSigner struct {
+		key *rsa.PrivateKey
+	}
+
+	rsaVerifier struct {
+		key *rsa.PublicKey
+	}
+
+	rsaPssSigner struct {
+		key *rsa.PrivateKey
+	}
+
+	rsaPssVerifier struct {
+		key *rsa.PublicKey
+	}
+
+	ecdsaSigner struct {
+		key *ecdsa.PrivateKey
+	}
+
+	ecdsaVerifier struct {
+		key *ecdsa.PublicKey
+	}
+
+	ed25519Signer struct {
+		key ed25519.PrivateKey
+	}
+
+	ed25519Verifier struct {
+		key ed25519.PublicKey
+	}
+
+	hmacSigner struct {
+		key []byte
+	}
+)
+
+var pssOptions = &rsa.PSSOptions{
+	SaltLength: rsa.PSSSaltLengthEqualsHash,
+}
+
+// NewSigner returns a Signer for the given private key.
+// RSA keys sign with PS256, ECDSA P-256 keys with ES256 and Ed25519 keys with EdDSA.
+func NewSigner(key crypto.PrivateKey) (Signer, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return NewRsaPssSigner(k), nil
+	case *ecdsa.PrivateKey:
+		return NewEcdsaSigner(k)
+	case ed25519.PrivateKey:
+		return NewEd25519Signer(k)
+	case *ed25519.PrivateKey:
+		return NewEd25519Signer(*k)
+	default:
+		return nil, ErrUnsupportedKey
+	}
+}
+
+// NewVerifier returns a Verifier for the given public key.
+func NewVerifier(key crypto.PublicKey) (Verifier, error) {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return NewRsaPssVerifier(k), nil
+	case *ecdsa.PublicKey:
+		return NewEcdsaVerifier(k)
+	case ed25519.PublicKey:
+		return NewEd25519Verifier(k)
+	case *ed25519.PublicKey:
+		return NewEd25519Verifier(*k)
+	default:
+		return nil, ErrUnsupportedKey
+	}
+}
+
+// NewSignerFromFile returns a Signer with the PEM encoded private key in the given file.
+func NewSignerFromFile(file string) (Signer, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ParsePrivateKeyPem(content)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSigner(key)
+}
+
+// NewVerifierFromFile returns a Verifier with the PEM encoded public key or certificate in the given file.
+func NewVerifierFromFile(file string) (Verifier, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ParsePublicKeyPem(content)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewVerifier(key)
+}
+
+// ParsePrivateKeyPem parses a PKCS#1, SEC 1 or PKCS#8 PEM encoded private key.
+func ParsePrivateKeyPem(content []byte) (crypto.PrivateKey, error) {
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, ErrPemBlock
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+}
+
+// ParsePublicKeyPem parses a PKIX or PKCS#1 PEM encoded public key, or the public key of a certificate.
+func ParsePublicKeyPem(content []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, ErrPemBlock
+	}
+
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	default:
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	}
+}
+
+// NewRsaSigner returns an RS256 Signer.
+// Prefer NewRsaPssSigner for new keys, RS256 is for the peers that only support it.
+func NewRsaSigner(key *rsa.PrivateKey) Signer {
+	return &rsaSigner{key: key}
+}
+
+func (s *rsaSigner) Algorithm() string {
+	return AlgRS256
+}
+
+func (s *rsaSigner) Sign(data []byte) ([]byte, error) {
+	digest := sha256.Sum256(data)
+	return rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+}
+
+// NewRsaVerifier returns an RS256 Verifier.
+func NewRsaVerifier(key *rsa.PublicKey) Verifier {
+	return &rsaVerifier{key: key}
+}
+
+func (v *rsaVerifier) Algorithm() string {
+	return AlgRS256
+}
+
+func (v *rsaVerifier) Verify(data, signature []byte) error {
+	digest := sha256.Sum256(data)
+	if err := rsa.VerifyPKCS1v15(v.key, crypto.SHA256, digest[:], signature); err != nil {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// NewRsaPssSigner returns a PS256 Signer.
+func NewRsaPssSigner(key *rsa.PrivateKey) Signer {
+	return &rsaPssSigner{key: key}
+}
+
+func (s *rsaPssSigner) Algorithm() string {
+	return AlgPS256
+}
+
+func (s *rsaPssSigner) Sign(data []byte) ([]byte, error) {
+	digest := sha256.Sum256(data)
+	return rsa.SignPSS(rand.Reader, s.key, crypto.SHA256, digest[:], pssOptions)
+}
+
+// NewRsaPssVerifier returns a PS256 Verifier.
+func NewRsaPssVerifier(key *rsa.PublicKey) Verifier {
+	return &rsaPssVerifier{key: key}
+}
+
+func (v *rsaPssVerifier) Algorithm() string {
+	return AlgPS256
+}
+
+func (v *rsaPssVerifier) Verify(data, signature []byte) error {
+	digest := sha256.Sum256(data)
+	if err := rsa.VerifyPSS(v.key, crypto.SHA256, digest[:], signature, pssOptions); err != nil {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// NewEcdsaSigner returns an ES256 Signer, the key must be on curve P-256.
+func NewEcdsaSigner(key *ecdsa.PrivateKey) (Signer, error) {
+	if key.Curve != elliptic.P256() {
+		return nil, ErrNotP256Key
+	}
+
+	return &ecdsaSigner{key: key}, nil
+}
+
+func (s *ecdsaSigner) Algorithm() string {
+	return AlgES256
+}
+
+// Sign returns the signature as the fixed size concatenation of r and s, as JWS requires.
+func (s *ecdsaSigner) Sign(data []byte) ([]byte, error) {
+	digest := sha256.Sum256(data)
+	r, ss, err := ecdsa.Sign(rand.Reader, s.key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	signature := make([]byte, 2*es256KeySize)
+	r.FillBytes(signature[:es256KeySize])
+	ss.FillBytes(signature[es256KeySize:])
+	return signature, nil
+}
+
+// NewEcdsaVerifier returns an ES256 Verifier, the key must be on curve P-256.
+func NewEcdsaVerifier(key *ecdsa.PublicKey) (Verifier, error) {
+	if key.Curve != elliptic.P256() {
+		return nil, ErrNotP256Key
+	}
+
+	return &ecdsaVerifier{key: key}, nil
+}
+
+func (v *ecdsaVerifier) Algorithm() string {
+	return AlgES256
+}
+
+func (v *ecdsaVerifier) Verify(data, signature []byte) error {
+	if len(signature) != 2*es256KeySize {
+		return ErrInvalidSignature
+	}
+
+	digest := sha256.Sum256(data)
+	r := new(big.Int).SetBytes(signature[:es256KeySize])
+	s := new(big.Int).SetBytes(signature[es256KeySize:])
+	if !ecdsa.Verify(v.key, digest[:], r, s) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// NewEd25519Signer returns an EdDSA Signer, the key must be of ed25519.PrivateKeySize.
+func NewEd25519Signer(key ed25519.PrivateKey) (Signer, error) {
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, ErrInvalidEd25519Key
+	}
+
+	return &ed25519Signer{key: key}, nil
+}
+
+func (s *ed25519Signer) Algorithm() string {
+	return AlgEdDSA
+}
+
+func (s *ed25519Signer) Sign(data []byte) ([]byte, error) {
+	return ed25519.Sign(s.key, data), nil
+}
+
+// NewEd25519Verifier returns an EdDSA Verifier, the key must be of ed25519.PublicKeySize.
+func NewEd25519Verifier(key ed25519.PublicKey) (Verifier, error) {
+	if len(key) != ed25519.PublicKeySize {
+		return nil, ErrInvalidEd25519Key
+	}
+
+	return &ed25519Verifier{key: key}, nil
+}
+
+func (v *ed25519Verifier) Algorithm() string {
+	return AlgEdDSA
+}
+
+func (v *ed25519Verifier) Verify(data, signature []byte) error {
+	if !ed25519.Verify(v.key, data, signature) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// NewHmacSigner returns an HS256 signer, which also verifies its own signatures.
+func NewHmacSigner(key []byte) SignVerifier {
+	return &hmacSigner{key: key}
+}
+
+func (h *hmacSigner) Algorithm() string {
+	return AlgHS256
+}
+
+func (h *hmacSigner) Sign(data []byte) ([]byte, error) {
+	return Hmac(h.key, string(data)), nil
+}
+
+func (h *hmacSigner) Verify(data, signature []byte) error {
+	if !hmac.Equal(Hmac(h.key, string(data)), signature) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
@@ -0,0 +1,228 @@
+package codec
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tp-life/utils/fs"
+)
+
+func TestSignVerify(t *testing.T) {
+	rsaKey, err := ParsePrivateKeyPem([]byte(priKey))
+	assert.Nil(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+	rsaPub, err := ParsePublicKeyPem([]byte(pubKey))
+	assert.Nil(t, err)
+
+	pairs := []struct {
+		alg  string
+		priv any
+		pub  any
+	}{
+		{AlgPS256, rsaKey, rsaPub},
+		{AlgES256, ecKey, &ecKey.PublicKey},
+		{AlgEdDSA, edKey, edPub},
+	}
+	for _, pair := range pairs {
+		t.Run(pair.alg, func(t *testing.T) {
+			signer, err := NewSigner(pair.priv)
+			assert.Nil(t, err)
+			verifier, err := NewVerifier(pair.pub)
+			assert.Nil(t, err)
+			assert.Equal(t, pair.alg, signer.Algorithm())
+			assert.Equal(t, pair.alg, verifier.Algorithm())
+
+			sig, err := signer.Sign([]byte(testBody))
+			assert.Nil(t, err)
+			assert.Nil(t, verifier.Verify([]byte(testBody), sig))
+			assert.Equal(t, ErrInvalidSignature, verifier.Verify([]byte("tampered"), sig))
+			assert.Equal(t, ErrInvalidSignature, verifier.Verify([]byte(testBody), sig[1:]))
+		})
+	}
+
+	hs := NewHmacSigner([]byte("secret"))
+	sig, err := hs.Sign([]byte(testBody))
+	assert.Nil(t, err)
+	assert.Nil(t, hs.Verify([]byte(testBody), sig))
+	assert.Equal(t, ErrInvalidSignature, NewHmacSigner([]byte("other")).Verify([]byte(testBody), sig))
+
+	_, err = NewSigner("foo")
+	assert.Equal(t, ErrUnsupportedKey, err)
+	_, err = NewVerifier("foo")
+	assert.Equal(t, ErrUnsupportedKey, err)
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.Nil(t, err)
+	_, err = NewSigner(p384)
+	assert.Equal(t, ErrNotP256Key, err)
+	_, err = NewVerifier(&p384.PublicKey)
+	assert.Equal(t, ErrNotP256Key, err)
+	_, err = NewSigner(edKey[:10])
+	assert.Equal(t, ErrInvalidEd25519Key, err)
+	_, err = NewVerifier(edPub[1:])
+	assert.Equal(t, ErrInvalidEd25519Key, err)
+}
+
+func TestSignerFromFile(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	der, err := x509.MarshalECPrivateKey(ecKey)
+	assert.Nil(t, err)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	assert.Nil(t, err)
+	pub, err := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	assert.Nil(t, err)
+
+	for _, block := range []*pem.Block{
+		{Type: "EC PRIVATE KEY", Bytes: der},
+		{Type: "PRIVATE KEY", Bytes: pkcs8},
+	} {
+		file, err := fs.TempFilenameWithText(string(pem.EncodeToMemory(block)))
+		assert.Nil(t, err)
+		defer os.Remove(file)
+
+		signer, err := NewSignerFromFile(file)
+		assert.Nil(t, err)
+		assert.Equal(t, AlgES256, signer.Algorithm())
+	}
+
+	file, err := fs.TempFilenameWithText(string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub})))
+	assert.Nil(t, err)
+	defer os.Remove(file)
+	verifier, err := NewVerifierFromFile(file)
+	assert.Nil(t, err)
+	assert.Equal(t, AlgES256, verifier.Algorithm())
+
+	_, err = NewSignerFromFile("not-exist")
+	assert.NotNil(t, err)
+	_, err = NewVerifierFromFile("not-exist")
+	assert.NotNil(t, err)
+	_, err = ParsePrivateKeyPem([]byte("foo"))
+	assert.Equal(t, ErrPemBlock, err)
+	_, err = ParsePublicKeyPem([]byte("foo"))
+	assert.Equal(t, ErrPemBlock, err)
+}
+
+func TestJwk(t *testing.T) {
+	rsaKey, err := ParsePrivateKeyPem([]byte(priKey))
+	assert.Nil(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+
+	signers := map[string]any{"rsa": rsaKey, "ec": ecKey, "ed": edKey}
+	var set JwkSet
+	for kid, pub := range map[string]any{"rsa": rsaPublic(t), "ec": &ecKey.PublicKey, "ed": edPub} {
+		jwk, err := NewJwk(kid, pub)
+		assert.Nil(t, err)
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	content, err := json.Marshal(set)
+	assert.Nil(t, err)
+	parsed, err := ParseJwkSet(content)
+	assert.Nil(t, err)
+	verifiers, err := parsed.Verifiers()
+	assert.Nil(t, err)
+	assert.Len(t, verifiers, 3)
+
+	for kid, priv := range signers {
+		signer, err := NewSigner(priv)
+		assert.Nil(t, err)
+		sig, err := signer.Sign([]byte(testBody))
+		assert.Nil(t, err)
+		assert.Nil(t, verifiers[kid].Verify([]byte(testBody), sig), kid)
+	}
+
+	// private ed25519 and ec keys round trip through d
+	edJwk, err := NewJwk("ed", edPub)
+	assert.Nil(t, err)
+	edJwk.D = base64.RawURLEncoding.EncodeToString(edKey.Seed())
+	signer, err := edJwk.Signer()
+	assert.Nil(t, err)
+	sig, err := signer.Sign([]byte(testBody))
+	assert.Nil(t, err)
+	assert.Nil(t, verifiers["ed"].Verify([]byte(testBody), sig))
+
+	ecJwk, err := NewJwk("ec", &ecKey.PublicKey)
+	assert.Nil(t, err)
+	ecJwk.D = base64.RawURLEncoding.EncodeToString(ecKey.D.Bytes())
+	signer, err = ecJwk.Signer()
+	assert.Nil(t, err)
+	sig, err = signer.Sign([]byte(testBody))
+	assert.Nil(t, err)
+	assert.Nil(t, verifiers["ec"].Verify([]byte(testBody), sig))
+
+	jwk, err := ParseJwk([]byte(`{"kty":"EC","crv":"P-256","x":"AQ","y":"AQ"}`))
+	assert.Nil(t, err)
+	_, err = jwk.PublicKey()
+	assert.Equal(t, ErrInvalidJwk, err)
+	_, err = Jwk{Kty: "oct"}.PublicKey()
+	assert.Equal(t, ErrUnsupportedKey, err)
+	_, err = Jwk{Kty: "RSA", N: "AQ", E: "AQ"}.PrivateKey()
+	assert.Equal(t, ErrPrivateKey, err)
+	_, err = ParseJwk([]byte("{"))
+	assert.NotNil(t, err)
+
+	// d must match the public key
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	ecJwk.D = base64.RawURLEncoding.EncodeToString(other.D.Bytes())
+	_, err = ecJwk.PrivateKey()
+	assert.Equal(t, ErrInvalidJwk, err)
+	edJwk.D = base64.RawURLEncoding.EncodeToString(make([]byte, ed25519.SeedSize))
+	_, err = edJwk.PrivateKey()
+	assert.Equal(t, ErrInvalidJwk, err)
+}
+
+func TestJwkAlgorithm(t *testing.T) {
+	rsaKey, err := ParsePrivateKeyPem([]byte(priKey))
+	assert.Nil(t, err)
+	jwk, err := NewJwk("rsa", rsaPublic(t))
+	assert.Nil(t, err)
+
+	set := JwkSet{Keys: []Jwk{
+		jwk,
+		{Kty: "EC", Kid: "p384", Crv: "P-384", X: "AQ", Y: "AQ"},
+		{Kty: "OKP", Kid: "x25519", Crv: "X25519", X: "AQ"},
+		{Kty: "oct", Kid: "oct"},
+		{Kty: "RSA", Kid: "rs512", Alg: "RS512", N: jwk.N, E: jwk.E},
+		{Kty: "RSA", Kid: "rs256", Alg: AlgRS256, N: jwk.N, E: jwk.E},
+	}}
+	verifiers, err := set.Verifiers()
+	assert.Nil(t, err)
+	assert.Len(t, verifiers, 2)
+	assert.Equal(t, AlgPS256, verifiers["rsa"].Algorithm())
+	assert.Equal(t, AlgRS256, verifiers["rs256"].Algorithm())
+
+	sig, err := NewRsaSigner(rsaKey.(*rsa.PrivateKey)).Sign([]byte(testBody))
+	assert.Nil(t, err)
+	assert.Nil(t, verifiers["rs256"].Verify([]byte(testBody), sig))
+	assert.Equal(t, ErrInvalidSignature, verifiers["rsa"].Verify([]byte(testBody), sig))
+
+	// a known algorithm that doesn't fit the key type is malformed
+	jwk.Alg = AlgES256
+	_, err = jwk.Verifier()
+	assert.Equal(t, ErrInvalidJwk, err)
+	_, err = JwkSet{Keys: []Jwk{jwk}}.Verifiers()
+	assert.Equal(t, ErrInvalidJwk, err)
+}
+
+func rsaPublic(t *testing.T) any {
+	pub, err := ParsePublicKeyPem([]byte(pubKey))
+	assert.Nil(t, err)
+	return pub
+}
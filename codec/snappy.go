@@ -0,0 +1,399 @@
+package codec
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+)
+
+// The snappy framing format, see
+// https://github.com/google/snappy/blob/main/framing_format.txt
+// and the block format in
+// https://github.com/google/snappy/blob/main/format_description.txt
+const (
+	// SnappyEncoding is the content coding of the snappy framing format, it's not a standard
+	// coding, so it's not advertised by AcceptEncoding unless registered with RegisterEncoding.
+	SnappyEncoding = "x-snappy-framed"
+
+	snappyChunkCompressed   = 0x00
+	snappyChunkUncompressed = 0x01
+	snappyChunkStreamId     = 0xff
+	snappyMagic             = "sNaPpY"
+
+	snappyMaxBlockSize   = 65536
+	snappyChecksumSize   = 4
+	snappyChunkHeader    = 4
+	snappyMaxEncodedSize = 76490 // max encoded size of a 64KB block
+	snappyMinMatch       = 4
+	snappyHashBits       = 14
+
+	snappyTagLiteral = 0x00
+	snappyTagCopy1   = 0x01
+	snappyTagCopy2   = 0x02
+	snappyTagCopy4   = 0x03
+)
+
+var (
+	// SnappyCompressor is the snappy framing format Compressor.
+	SnappyCompressor Compressor = snappyCompressor{}
+
+	// ErrSnappyCorrupt indicates a corrupt snappy stream.
+	ErrSnappyCorrupt = errors.New("snappy: corrupt input")
+	// ErrSnappyChecksum indicates a snappy chunk with a wrong checksum.
+	ErrSnappyChecksum = errors.New("snappy: checksum mismatch")
+
+	crc32c = crc32.MakeTable(crc32.Castagnoli)
+)
+
+type (
+	snappyCompressor struct{}
+
+	snappyWriter struct {
+		w       io.Writer
+		buf     []byte
+		encoded []byte
+		started bool
+		closed  bool
+		err     error
+	}
+
+	snappyReader struct {
+		r       io.Reader
+		buf     []byte
+		decoded []byte
+		pending []byte
+		started bool
+		err     error
+	}
+)
+
+func (snappyCompressor) Name() string {
+	return "snappy"
+}
+
+func (snappyCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return &snappyWriter{
+		w:       w,
+		buf:     make([]byte, 0, snappyMaxBlockSize),
+		encoded: make([]byte, snappyChunkHeader+snappyChecksumSize+snappyMaxEncodedSize),
+	}, nil
+}
+
+func (snappyCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return &snappyReader{
+		r:       r,
+		buf:     make([]byte, snappyChunkHeader+snappyChecksumSize+snappyMaxEncodedSize),
+		decoded: make([]byte, snappyMaxBlockSize),
+	}, nil
+}
+
+func (w *snappyWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, io.ErrClosedPipe
+	}
+
+	var n int
+	for len(p) > 0 && w.err == nil {
+		free := snappyMaxBlockSize - len(w.buf)
+		if free > len(p) {
+			free = len(p)
+		}
+		w.buf = append(w.buf, p[:free]...)
+		p = p[free:]
+		n += free
+
+		if len(w.buf) == snappyMaxBlockSize {
+			w.err = w.flush()
+		}
+	}
+
+	return n, w.err
+}
+
+func (w *snappyWriter) Close() error {
+	if w.closed {
+		return w.err
+	}
+
+	w.closed = true
+	if w.err == nil {
+		w.err = w.flush()
+	}
+
+	return w.err
+}
+
+func (w *snappyWriter) flush() error {
+	if !w.started {
+		w.started = true
+		header := []byte{snappyChunkStreamId, byte(len(snappyMagic)), 0, 0}
+		if _, err := w.w.Write(append(header, snappyMagic...)); err != nil {
+			return err
+		}
+	}
+	if len(w.buf) == 0 {
+		return nil
+	}
+
+	body := w.encoded[snappyChunkHeader+snappyChecksumSize:]
+	n := snappyEncodeBlock(body, w.buf)
+	chunkType := byte(snappyChunkCompressed)
+	// store incompressible data as is
+	if n >= len(w.buf)-len(w.buf)/8 {
+		chunkType = snappyChunkUncompressed
+		n = copy(body, w.buf)
+	}
+
+	size := snappyChecksumSize + n
+	chunk := w.encoded[:snappyChunkHeader+size]
+	chunk[0] = chunkType
+	chunk[1], chunk[2], chunk[3] = byte(size), byte(size>>8), byte(size>>16)
+	binary.LittleEndian.PutUint32(chunk[snappyChunkHeader:], snappyChecksum(w.buf))
+	w.buf = w.buf[:0]
+
+	_, err := w.w.Write(chunk)
+	return err
+}
+
+func (r *snappyReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.err = r.readChunk()
+	}
+
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+func (r *snappyReader) Close() error {
+	r.err = io.ErrClosedPipe
+	return nil
+}
+
+func (r *snappyReader) readChunk() error {
+	header := r.buf[:snappyChunkHeader]
+	if _, err := io.ReadFull(r.r, header); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return ErrSnappyCorrupt
+		}
+		return err
+	}
+
+	chunkType := header[0]
+	size := int(header[1]) | int(header[2])<<8 | int(header[3])<<16
+	if !r.started && chunkType != snappyChunkStreamId {
+		return ErrSnappyCorrupt
+	}
+
+	switch {
+	case chunkType == snappyChunkStreamId:
+		if size != len(snappyMagic) {
+			return ErrSnappyCorrupt
+		}
+		magic := r.buf[:size]
+		if _, err := io.ReadFull(r.r, magic); err != nil || string(magic) != snappyMagic {
+			return ErrSnappyCorrupt
+		}
+		r.started = true
+		return nil
+	case chunkType == snappyChunkCompressed || chunkType == snappyChunkUncompressed:
+		if size < snappyChecksumSize || size > len(r.buf) {
+			return ErrSnappyCorrupt
+		}
+		chunk := r.buf[:size]
+		if _, err := io.ReadFull(r.r, chunk); err != nil {
+			return ErrSnappyCorrupt
+		}
+
+		checksum := binary.LittleEndian.Uint32(chunk)
+		data := chunk[snappyChecksumSize:]
+		if chunkType == snappyChunkCompressed {
+			n, err := snappyDecodeBlock(r.decoded, data)
+			if err != nil {
+				return err
+			}
+			data = r.decoded[:n]
+		} else if len(data) > snappyMaxBlockSize {
+			return ErrSnappyCorrupt
+		}
+
+		if snappyChecksum(data) != checksum {
+			return ErrSnappyChecksum
+		}
+		r.pending = data
+		return nil
+	case chunkType >= 0x80:
+		// padding and reserved skippable chunks
+		if _, err := io.CopyN(io.Discard, r.r, int64(size)); err != nil {
+			return ErrSnappyCorrupt
+		}
+		return nil
+	default:
+		// reserved unskippable chunks
+		return ErrSnappyCorrupt
+	}
+}
+
+// snappyEncodeBlock encodes src, at most 64KB, into dst and returns the
+// number of bytes written. It uses a greedy hash based match finder.
+func snappyEncodeBlock(dst, src []byte) int {
+	d := binary.PutUvarint(dst, uint64(len(src)))
+	if len(src) < snappyMinMatch {
+		return d + snappyEmitLiteral(dst[d:], src)
+	}
+
+	var table [1 << snappyHashBits]int32
+	hash := func(u uint32) uint32 {
+		return (u * 0x1e35a7bd) >> (32 - snappyHashBits)
+	}
+
+	lit := 0
+	for s := 0; s+snappyMinMatch <= len(src); {
+		cur := binary.LittleEndian.Uint32(src[s:])
+		h := hash(cur)
+		candidate := int(table[h]) - 1
+		table[h] = int32(s + 1)
+
+		if candidate < 0 || binary.LittleEndian.Uint32(src[candidate:]) != cur {
+			s++
+			continue
+		}
+
+		if lit < s {
+			d += snappyEmitLiteral(dst[d:], src[lit:s])
+		}
+
+		length := snappyMinMatch
+		for s+length < len(src) && src[candidate+length] == src[s+length] {
+			length++
+		}
+		d += snappyEmitCopy(dst[d:], s-candidate, length)
+		s += length
+		lit = s
+	}
+
+	if lit < len(src) {
+		d += snappyEmitLiteral(dst[d:], src[lit:])
+	}
+
+	return d
+}
+
+func snappyEmitLiteral(dst, lit []byte) int {
+	n := len(lit) - 1
+	var i int
+	switch {
+	case n < 60:
+		dst[0] = byte(n)<<2 | snappyTagLiteral
+		i = 1
+	case n < 1<<8:
+		dst[0] = 60<<2 | snappyTagLiteral
+		dst[1] = byte(n)
+		i = 2
+	default:
+		dst[0] = 61<<2 | snappyTagLiteral
+		dst[1], dst[2] = byte(n), byte(n>>8)
+		i = 3
+	}
+
+	return i + copy(dst[i:], lit)
+}
+
+func snappyEmitCopy(dst []byte, offset, length int) int {
+	var i int
+	for length > 0 {
+		n := length
+		if n > 64 {
+			n = 64
+		}
+		dst[i] = byte(n-1)<<2 | snappyTagCopy2
+		dst[i+1], dst[i+2] = byte(offset), byte(offset>>8)
+		i += 3
+		length -= n
+	}
+
+	return i
+}
+
+// snappyDecodeBlock decodes the block src into dst and returns the number of
+// bytes written.
+func snappyDecodeBlock(dst, src []byte) (int, error) {
+	size, n := binary.Uvarint(src)
+	if n <= 0 || size > uint64(len(dst)) {
+		return 0, ErrSnappyCorrupt
+	}
+	dst = dst[:size]
+
+	var d int
+	for s := n; s < len(src); {
+		tag := src[s]
+		var length, offset int
+		switch tag & 0x03 {
+		case snappyTagLiteral:
+			length = int(tag >> 2)
+			s++
+			if length >= 60 {
+				extra := length - 59
+				if s+extra > len(src) {
+					return 0, ErrSnappyCorrupt
+				}
+				length = 0
+				for i := extra - 1; i >= 0; i-- {
+					length = length<<8 | int(src[s+i])
+				}
+				s += extra
+			}
+			length++
+			if length > len(src)-s || length > len(dst)-d {
+				return 0, ErrSnappyCorrupt
+			}
+			d += copy(dst[d:], src[s:s+length])
+			s += length
+			continue
+		case snappyTagCopy1:
+			if s+2 > len(src) {
+				return 0, ErrSnappyCorrupt
+			}
+			length = 4 + int(tag>>2)&0x07
+			offset = int(tag&0xe0)<<3 | int(src[s+1])
+			s += 2
+		case snappyTagCopy2:
+			if s+3 > len(src) {
+				return 0, ErrSnappyCorrupt
+			}
+			length = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint16(src[s+1:]))
+			s += 3
+		case snappyTagCopy4:
+			if s+5 > len(src) {
+				return 0, ErrSnappyCorrupt
+			}
+			length = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint32(src[s+1:]))
+			s += 5
+		}
+
+		if offset <= 0 || offset > d || length > len(dst)-d {
+			return 0, ErrSnappyCorrupt
+		}
+		// byte by byte, the source may overlap the destination
+		for end := d + length; d < end; d++ {
+			dst[d] = dst[d-offset]
+		}
+	}
+
+	if d != len(dst) {
+		return 0, ErrSnappyCorrupt
+	}
+
+	return d, nil
+}
+
+func snappyChecksum(b []byte) uint32 {
+	c := crc32.Checksum(b, crc32c)
+	return (c>>15 | c<<17) + 0xa282ead8
+}
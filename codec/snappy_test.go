@@ -0,0 +1,108 @@
+package codec
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnappyBlock(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	inputs := [][]byte{
+		[]byte("a"),
+		[]byte("abc"),
+		bytes.Repeat([]byte("a"), 1000),
+		bytes.Repeat([]byte("abcdefgh"), 8192),
+		make([]byte, snappyMaxBlockSize),
+	}
+	random := make([]byte, snappyMaxBlockSize)
+	rnd.Read(random)
+	inputs = append(inputs, random, random[:300])
+
+	dst := make([]byte, snappyMaxEncodedSize)
+	decoded := make([]byte, snappyMaxBlockSize)
+	for _, input := range inputs {
+		n := snappyEncodeBlock(dst, input)
+		m, err := snappyDecodeBlock(decoded, dst[:n])
+		assert.Nil(t, err)
+		assert.Equal(t, input, decoded[:m])
+	}
+}
+
+func TestSnappyDecodeBlockTags(t *testing.T) {
+	decoded := make([]byte, snappyMaxBlockSize)
+
+	// literal "ab", copy1 of length 4 at offset 2, copy4 of length 2 at offset 1
+	block := []byte{8, 1 << 2, 'a', 'b', snappyTagCopy1, 2, 1<<2 | snappyTagCopy4, 1, 0, 0, 0}
+	n, err := snappyDecodeBlock(decoded, block)
+	assert.Nil(t, err)
+	assert.Equal(t, "abababbb", string(decoded[:n]))
+
+	// literal with a 1 byte length
+	long := append([]byte{61, 60 << 2, 60}, bytes.Repeat([]byte("x"), 61)...)
+	n, err = snappyDecodeBlock(decoded, long)
+	assert.Nil(t, err)
+	assert.Equal(t, 61, n)
+
+	for _, bad := range [][]byte{
+		{},
+		{5, 0, 'a'},
+		{2, 0, 'a', 1<<2 | snappyTagCopy2, 5, 0},
+		{2, 0, 'a', snappyTagCopy1},
+		{1, 60 << 2},
+		{0xff, 0xff, 0xff, 0xff, 0x0f},
+	} {
+		_, err = snappyDecodeBlock(decoded, bad)
+		assert.Equal(t, ErrSnappyCorrupt, err, bad)
+	}
+}
+
+func TestSnappyFraming(t *testing.T) {
+	compressed, err := Compress(SnappyCompressor, []byte("hello hello hello hello"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0xff, 6, 0, 0, 's', 'N', 'a', 'P', 'p', 'Y'}, compressed[:10])
+
+	// skippable chunks are ignored
+	stream := append([]byte{}, compressed[:10]...)
+	stream = append(stream, 0xfe, 2, 0, 0, 0, 0)
+	stream = append(stream, compressed[10:]...)
+	actual, err := Decompress(SnappyCompressor, stream)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello hello hello hello", string(actual))
+
+	tampered := append([]byte{}, compressed...)
+	tampered[14]++
+	_, err = Decompress(SnappyCompressor, tampered)
+	assert.Equal(t, ErrSnappyChecksum, err)
+
+	_, err = Decompress(SnappyCompressor, compressed[10:])
+	assert.Equal(t, ErrSnappyCorrupt, err)
+	_, err = Decompress(SnappyCompressor, compressed[:len(compressed)-1])
+	assert.Equal(t, ErrSnappyCorrupt, err)
+	_, err = Decompress(SnappyCompressor, append(append([]byte{}, compressed[:10]...), 0x02, 0, 0, 0))
+	assert.Equal(t, ErrSnappyCorrupt, err)
+	_, err = Decompress(SnappyCompressor, []byte{0xff, 5, 0, 0, 's', 'N', 'a', 'P', 'p'})
+	assert.Equal(t, ErrSnappyCorrupt, err)
+}
+
+func TestSnappyWriterAfterClose(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := SnappyCompressor.NewWriter(&buf)
+	assert.Nil(t, err)
+	assert.Nil(t, w.Close())
+	assert.Nil(t, w.Close())
+	_, err = w.Write([]byte("a"))
+	assert.Equal(t, io.ErrClosedPipe, err)
+
+	r, err := SnappyCompressor.NewReader(&buf)
+	assert.Nil(t, err)
+	content, err := io.ReadAll(r)
+	assert.Nil(t, err)
+	assert.Empty(t, content)
+	assert.Nil(t, r.Close())
+	_, err = r.Read(make([]byte, 1))
+	assert.Equal(t, io.ErrClosedPipe, err)
+}
@@ -48,31 +48,52 @@ func Load(file string, v any, opts ...Option) error {
 		return err
 	}
 
+	return loadContent(file, content, v, opts...)
+}
+
+// LoadConfig loads config into v from file, .json, .yaml and .yml are acceptable.
+// Deprecated: use Load instead.
+func LoadConfig(file string, v any, opts ...Option) error {
+	return Load(file, v, opts...)
+}
+
+// loadContent loads config into v from the content of file, the loader is chosen by the file extension.
+func loadContent(file string, content []byte, v any, opts ...Option) error {
 	loader, ok := loaders[strings.ToLower(path.Ext(file))]
 	if !ok {
 		return fmt.Errorf("unrecognized file type: %s", file)
 	}
 
-	var opt options
-	for _, o := range opts {
-		o(&opt)
+	opt := newOptions(opts)
+	if opt.env {
+		content = expandEnv(content, opt)
+	}
+	if opt.secrets {
+		return loadWithSecrets(file, content, v, opt)
 	}
+	if len(opt.unmarshalOpts) > 0 {
+		format, err := encoding.ParseFormat(file)
+		if err != nil {
+			return err
+		}
 
-	if opt.env {
-		return loader([]byte(os.ExpandEnv(string(content))), v)
+		data, err := encoding.Convert(content, format, encoding.FormatJson)
+		if err != nil {
+			return err
+		}
+
+		return mapping.LocateErrors(format, content, loadFromJsonBytes(data, v, opt.unmarshalOpts...))
 	}
 
 	return loader(content, v)
 }
 
-// LoadConfig loads config into v from file, .json, .yaml and .yml are acceptable.
-// Deprecated: use Load instead.
-func LoadConfig(file string, v any, opts ...Option) error {
-	return Load(file, v, opts...)
-}
-
 // LoadFromJsonBytes loads config into v from content json bytes.
 func LoadFromJsonBytes(content []byte, v any) error {
+	return loadFromJsonBytes(content, v)
+}
+
+func loadFromJsonBytes(content []byte, v any, opts ...mapping.UnmarshalOption) error {
 	info, err := buildFieldsInfo(reflect.TypeOf(v), "")
 	if err != nil {
 		return err
@@ -85,7 +106,8 @@ func LoadFromJsonBytes(content []byte, v any) error {
 
 	lowerCaseKeyMap := toLowerCaseKeyMap(m, info)
 
-	return mapping.UnmarshalJsonMap(lowerCaseKeyMap, v, mapping.WithCanonicalKeyFunc(toLowerCase))
+	opts = append([]mapping.UnmarshalOption{mapping.WithCanonicalKeyFunc(toLowerCase)}, opts...)
+	return mapping.UnmarshalJsonMap(lowerCaseKeyMap, v, opts...)
 }
 
 // LoadConfigFromJsonBytes loads config into v from content json bytes.
@@ -101,7 +123,7 @@ func LoadFromTomlBytes(content []byte, v any) error {
 		return err
 	}
 
-	return LoadFromJsonBytes(b, v)
+	return mapping.LocateErrors(encoding.FormatToml, content, LoadFromJsonBytes(b, v))
 }
 
 // LoadFromYamlBytes loads config into v from content yaml bytes.
@@ -111,7 +133,7 @@ func LoadFromYamlBytes(content []byte, v any) error {
 		return err
 	}
 
-	return LoadFromJsonBytes(b, v)
+	return mapping.LocateErrors(encoding.FormatYaml, content, LoadFromJsonBytes(b, v))
 }
 
 // LoadConfigFromYamlBytes loads config into v from content yaml bytes.
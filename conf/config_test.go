@@ -1,6 +1,9 @@
 package conf
 
 import (
+	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"reflect"
 	"testing"
@@ -8,6 +11,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/tp-life/utils/fs"
 	"github.com/tp-life/utils/hash"
+	"github.com/tp-life/utils/mapping"
 )
 
 var dupErr conflictKeyError
@@ -225,6 +229,62 @@ d = "abcd!@#112"
 	assert.Equal(t, "abcd!@#112", val.D)
 }
 
+func TestConfigWithUnmarshalOptions(t *testing.T) {
+	text := `endpoint: https://example.com/api
+peer: 10.0.0.1
+`
+	tmpfile, err := createTempFile(".yaml", text)
+	assert.Nil(t, err)
+	defer os.Remove(tmpfile)
+
+	var val struct {
+		Endpoint url.URL `json:"endpoint"`
+		Peer     net.IP  `json:"peer"`
+	}
+	assert.Error(t, Load(tmpfile, &val))
+
+	parseURL := mapping.WithTypeDecoder(func(v any) (url.URL, error) {
+		u, err := url.Parse(fmt.Sprint(v))
+		if err != nil {
+			return url.URL{}, err
+		}
+
+		return *u, nil
+	})
+	assert.NoError(t, Load(tmpfile, &val, WithUnmarshalOptions(parseURL)))
+	assert.Equal(t, "example.com", val.Endpoint.Host)
+	assert.Equal(t, "10.0.0.1", val.Peer.String())
+}
+
+func TestConfigErrorPositions(t *testing.T) {
+	tests := []struct {
+		ext  string
+		text string
+		line int
+	}{
+		{ext: ".yaml", text: "name: foo\nport: 80000\n", line: 2},
+		{ext: ".toml", text: "name = \"foo\"\n\nport = 80000\n", line: 3},
+	}
+
+	for _, test := range tests {
+		t.Run(test.ext, func(t *testing.T) {
+			tmpfile, err := createTempFile(test.ext, test.text)
+			assert.Nil(t, err)
+			defer os.Remove(tmpfile)
+
+			var val struct {
+				Name string `json:"name"`
+				Port int    `json:"port,range=[1:65535]"`
+			}
+			err = Load(tmpfile, &val, WithUnmarshalOptions(mapping.WithAggregatedErrors()))
+			var fes mapping.FieldErrors
+			if assert.ErrorAs(t, err, &fes) {
+				assert.Equal(t, test.line, fes[0].Line)
+			}
+		})
+	}
+}
+
 func TestConfigJsonEnv(t *testing.T) {
 	tests := []string{
 		".json",
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package conf
+
+import "errors"
+
+var errNotifyUnsupported = errors.New("file notifications are not supported")
+
+func newNotifier(string) (notifier, error) {
+	return nil, errNotifyUnsupported
+}
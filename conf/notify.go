@@ -0,0 +1,68 @@
+//go:build linux
+
+package conf
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/tp-life/utils/threading"
+)
+
+// the events of the files that are written, replaced, created, removed or touched
+const inotifyMask = syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO | syscall.IN_MOVED_FROM |
+	syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_ATTRIB
+
+type inotifyNotifier struct {
+	file   *os.File
+	events chan struct{}
+}
+
+// newNotifier watches the directory of file with inotify, instead of the file itself,
+// so that the files replaced by renaming, like the mounted ConfigMaps in kubernetes, are watched as well.
+func newNotifier(file string) (notifier, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = syscall.InotifyAddWatch(fd, filepath.Dir(file), inotifyMask); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+
+	// the non-blocking fd is read with the runtime poller, so that Close interrupts the reading
+	n := &inotifyNotifier{
+		file:   os.NewFile(uintptr(fd), "inotify"),
+		events: make(chan struct{}, 1),
+	}
+	threading.GoSafe(n.read)
+
+	return n, nil
+}
+
+func (n *inotifyNotifier) Close() error {
+	return n.file.Close()
+}
+
+func (n *inotifyNotifier) Events() <-chan struct{} {
+	return n.events
+}
+
+func (n *inotifyNotifier) read() {
+	defer close(n.events)
+
+	buf := make([]byte, 4096)
+	for {
+		if _, err := n.file.Read(buf); err != nil {
+			return
+		}
+
+		// the events are merged, the file is checked for changes on reloading
+		select {
+		case n.events <- struct{}{}:
+		default:
+		}
+	}
+}
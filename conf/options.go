@@ -1,11 +1,16 @@
 package conf
 
+import "github.com/tp-life/utils/mapping"
+
 type (
 	// Option defines the method to customize the config options.
 	Option func(opt *options)
 
 	options struct {
-		env bool
+		env           bool
+		secrets       bool
+		keyProvider   KeyProvider
+		unmarshalOpts []mapping.UnmarshalOption
 	}
 )
 
@@ -15,3 +20,37 @@ func UseEnv() Option {
 		opt.env = true
 	}
 }
+
+// UseSecrets customizes the config to resolve secret references,
+// like ${file:/run/secrets/db} and ${env:DB_PASS}.
+func UseSecrets() Option {
+	return func(opt *options) {
+		opt.secrets = true
+	}
+}
+
+// WithKeyProvider customizes the config to resolve secret references,
+// and decrypt the enc:<base64> values with the keys from provider.
+func WithKeyProvider(provider KeyProvider) Option {
+	return func(opt *options) {
+		opt.secrets = true
+		opt.keyProvider = provider
+	}
+}
+
+// WithUnmarshalOptions customizes the config with the unmarshal options,
+// like mapping.WithTypeDecoder to decode the fields of rich types.
+func WithUnmarshalOptions(opts ...mapping.UnmarshalOption) Option {
+	return func(opt *options) {
+		opt.unmarshalOpts = append(opt.unmarshalOpts, opts...)
+	}
+}
+
+func newOptions(opts []Option) options {
+	var opt options
+	for _, o := range opts {
+		o(&opt)
+	}
+
+	return opt
+}
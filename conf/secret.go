@@ -0,0 +1,356 @@
+package conf
+
+import (
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/tp-life/utils/codec"
+	"github.com/tp-life/utils/encoding"
+	"github.com/tp-life/utils/jsonx"
+	"github.com/tp-life/utils/mapping"
+)
+
+const (
+	encPrefix      = "enc:"
+	secretOption   = "secret"
+	redactedSecret = "******"
+	fileRefKind    = "file"
+	envRefKind     = "env"
+)
+
+var (
+	// ErrSecretNotFound indicates that the referenced secret file or environment variable doesn't exist.
+	ErrSecretNotFound = errors.New("secret not found")
+	// ErrNoKeyProvider indicates that an enc: value is found without a key provider.
+	ErrNoKeyProvider = errors.New("no key provider to decrypt enc: values")
+
+	secretRefPattern = regexp.MustCompile(`\$\{(file|env):([^}]+)}`)
+)
+
+type (
+	// A Decrypter decrypts base64 encoded values, *codec.Keyring implements it.
+	Decrypter interface {
+		DecryptBase64(src string) ([]byte, error)
+	}
+
+	// A KeyProvider provides the Decrypter for enc: values, like loading keys from a mounted file or a KMS.
+	// It's called only if there are enc: values.
+	KeyProvider func() (Decrypter, error)
+
+	aeadDecrypter struct {
+		aead cipher.AEAD
+	}
+)
+
+// KeyringProvider returns a KeyProvider of keyring, the values are envelopes
+// encrypted by keyring.EncryptBase64, which carry the key ids for key rotation.
+func KeyringProvider(keyring *codec.Keyring) KeyProvider {
+	return func() (Decrypter, error) {
+		return keyring, nil
+	}
+}
+
+// KeyFromEnv returns a KeyProvider with the base64 encoded key in the environment variable env,
+// the values are encrypted by codec.AeadEncrypt with alg and then base64 encoded.
+func KeyFromEnv(env string, alg codec.AeadAlgorithm) KeyProvider {
+	return func() (Decrypter, error) {
+		val, ok := os.LookupEnv(env)
+		if !ok {
+			return nil, fmt.Errorf("%w: env %s", ErrSecretNotFound, env)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(val))
+		if err != nil {
+			return nil, fmt.Errorf("decode key in env %s, %w", env, err)
+		}
+
+		aead, err := codec.NewAead(alg, key)
+		if err != nil {
+			return nil, err
+		}
+
+		return aeadDecrypter{aead: aead}, nil
+	}
+}
+
+// Redact returns the values of v with the fields tagged as secret redacted,
+// like `json:",secret"`, it's used to log or dump the config safely.
+func Redact(v any) any {
+	return redactValue(reflect.ValueOf(v))
+}
+
+// Dump returns the indented json of v with the fields tagged as secret redacted.
+func Dump(v any) (string, error) {
+	// encoding/json sorts the map keys, which makes dumps comparable
+	content, err := json.MarshalIndent(Redact(v), "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	return string(content), nil
+}
+
+func (d aeadDecrypter) DecryptBase64(src string) ([]byte, error) {
+	content, err := base64.StdEncoding.DecodeString(src)
+	if err != nil {
+		return nil, err
+	}
+
+	return codec.AeadDecrypt(d.aead, content, nil)
+}
+
+// expandEnv expands the environment variables in content, secret references are kept.
+func expandEnv(content []byte, opt options) []byte {
+	if !opt.secrets {
+		return []byte(os.ExpandEnv(string(content)))
+	}
+
+	return []byte(os.Expand(string(content), func(name string) string {
+		if strings.HasPrefix(name, fileRefKind+":") || strings.HasPrefix(name, envRefKind+":") {
+			return "${" + name + "}"
+		}
+
+		return os.Getenv(name)
+	}))
+}
+
+func loadWithSecrets(file string, content []byte, v any, opt options) error {
+	format, err := encoding.ParseFormat(file)
+	if err != nil {
+		return err
+	}
+
+	m, err := toMap(content, format)
+	if err != nil {
+		return err
+	}
+
+	if m, err = resolveMapSecrets(m, opt); err != nil {
+		return err
+	}
+
+	data, err := jsonx.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	return mapping.LocateErrors(format, content, loadFromJsonBytes(data, v, opt.unmarshalOpts...))
+}
+
+func resolveMapSecrets(m map[string]any, opt options) (map[string]any, error) {
+	if !opt.secrets || m == nil {
+		return m, nil
+	}
+
+	r := &secretResolver{provider: opt.keyProvider}
+	val, err := r.resolve(m)
+	if err != nil {
+		return nil, err
+	}
+
+	return val.(map[string]any), nil
+}
+
+// secretResolver resolves the secret references, the decrypter is created on the first enc: value.
+type secretResolver struct {
+	provider  KeyProvider
+	decrypter Decrypter
+}
+
+func (r *secretResolver) resolve(val any) (any, error) {
+	switch v := val.(type) {
+	case map[string]any:
+		for key, item := range v {
+			resolved, err := r.resolve(item)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			v[key] = resolved
+		}
+	case []any:
+		for i, item := range v {
+			resolved, err := r.resolve(item)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+			v[i] = resolved
+		}
+	case string:
+		return r.resolveString(v)
+	}
+
+	return val, nil
+}
+
+func (r *secretResolver) resolveString(s string) (string, error) {
+	if strings.HasPrefix(s, encPrefix) {
+		return r.decrypt(s[len(encPrefix):])
+	}
+
+	var err error
+	resolved := secretRefPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		match := secretRefPattern.FindStringSubmatch(ref)
+		val, e := readSecretRef(match[1], match[2])
+		if e != nil && err == nil {
+			err = e
+		}
+		return val
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return resolved, nil
+}
+
+func (r *secretResolver) decrypt(src string) (string, error) {
+	if r.decrypter == nil {
+		if r.provider == nil {
+			return "", ErrNoKeyProvider
+		}
+
+		decrypter, err := r.provider()
+		if err != nil {
+			return "", err
+		}
+		r.decrypter = decrypter
+	}
+
+	// don't wrap the error with the value, which might leak partial secrets
+	plain, err := r.decrypter.DecryptBase64(strings.TrimSpace(src))
+	if err != nil {
+		return "", fmt.Errorf("decrypt enc: value, %w", err)
+	}
+
+	return string(plain), nil
+}
+
+func readSecretRef(kind, name string) (string, error) {
+	name = strings.TrimSpace(name)
+	switch kind {
+	case fileRefKind:
+		content, err := os.ReadFile(name)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return "", fmt.Errorf("%w: file %s", ErrSecretNotFound, name)
+			}
+			return "", err
+		}
+		// secret files usually end with a newline
+		return strings.TrimRight(string(content), "\r\n"), nil
+	default:
+		val, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("%w: env %s", ErrSecretNotFound, name)
+		}
+		return val, nil
+	}
+}
+
+func redactValue(v reflect.Value) any {
+	if !v.IsValid() {
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return redactValue(v.Elem())
+	case reflect.Struct:
+		if _, ok := v.Interface().(json.Marshaler); ok {
+			return v.Interface()
+		}
+
+		m := make(map[string]any)
+		redactStruct(m, v)
+		return m
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+
+		list := make([]any, v.Len())
+		for i := range list {
+			list[i] = redactValue(v.Index(i))
+		}
+		return list
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+
+		m := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			m[fmt.Sprint(iter.Key().Interface())] = redactValue(iter.Value())
+		}
+		return m
+	default:
+		return v.Interface()
+	}
+}
+
+func redactStruct(m map[string]any, v reflect.Value) {
+	tp := v.Type()
+	for i := 0; i < tp.NumField(); i++ {
+		field := tp.Field(i)
+		tag := field.Tag.Get(jsonTagKey)
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+
+		fv := v.Field(i)
+		if field.Anonymous && len(tagName(tag)) == 0 {
+			if ft := mapping.Deref(field.Type); ft.Kind() == reflect.Struct {
+				if fv.Kind() == reflect.Ptr {
+					if fv.IsNil() {
+						continue
+					}
+					fv = fv.Elem()
+				}
+				redactStruct(m, fv)
+				continue
+			}
+		}
+
+		name := getTagName(field)
+		if isSecretField(tag) {
+			if fv.IsZero() {
+				m[name] = redactValue(fv)
+			} else {
+				m[name] = redactedSecret
+			}
+			continue
+		}
+
+		m[name] = redactValue(fv)
+	}
+}
+
+func isSecretField(tag string) bool {
+	segs := strings.Split(tag, string(jsonTagSep))
+	for _, seg := range segs[1:] {
+		if strings.TrimSpace(seg) == secretOption {
+			return true
+		}
+	}
+
+	return false
+}
+
+func tagName(tag string) string {
+	if pos := strings.IndexByte(tag, jsonTagSep); pos >= 0 {
+		tag = tag[:pos]
+	}
+
+	return strings.TrimSpace(tag)
+}
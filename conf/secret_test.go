@@ -0,0 +1,172 @@
+package conf
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tp-life/utils/codec"
+)
+
+type secretConfig struct {
+	Name string
+	DB   struct {
+		User     string
+		Password string `json:",secret"`
+		Token    string `json:",optional,secret"`
+	}
+	Keys []string `json:",optional"`
+}
+
+func TestLoadWithSecrets(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "db")
+	assert.Nil(t, os.WriteFile(secretFile, []byte("s3cret\n"), 0o600))
+	t.Setenv("DB_USER", "admin")
+	t.Setenv("NAME", "app")
+
+	keyring := codec.NewKeyring()
+	assert.Nil(t, keyring.Add("v1", codec.AesGcm, make([]byte, 32)))
+	encrypted, err := keyring.EncryptBase64([]byte("t0ken"))
+	assert.Nil(t, err)
+
+	file := filepath.Join(dir, "config.yaml")
+	assert.Nil(t, os.WriteFile(file, []byte(`Name: ${NAME}
+DB:
+  User: ${env:DB_USER}
+  Password: ${file:`+secretFile+`}
+  Token: enc:`+encrypted+`
+Keys: ["user-${env:DB_USER}"]
+`), 0o644))
+
+	var c secretConfig
+	assert.Nil(t, Load(file, &c, UseEnv(), WithKeyProvider(KeyringProvider(keyring))))
+	assert.Equal(t, "app", c.Name)
+	assert.Equal(t, "admin", c.DB.User)
+	assert.Equal(t, "s3cret", c.DB.Password)
+	assert.Equal(t, "t0ken", c.DB.Token)
+	assert.Equal(t, []string{"user-admin"}, c.Keys)
+
+	// references are kept as is without secrets enabled
+	var plain secretConfig
+	assert.Nil(t, Load(file, &plain))
+	assert.Equal(t, "${env:DB_USER}", plain.DB.User)
+
+	var layered secretConfig
+	assert.Nil(t, LoadSources(&layered, FromFile(file, UseSecrets(),
+		WithKeyProvider(KeyringProvider(keyring)))))
+	assert.Equal(t, "s3cret", layered.DB.Password)
+}
+
+func TestLoadWithSecretsErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+		opts    []Option
+		err     error
+	}{
+		{
+			name:    "missing env",
+			content: `{"Name":"a","DB":{"User":"${env:NOT_EXISTS_ENV}","Password":"p"}}`,
+			opts:    []Option{UseSecrets()},
+			err:     ErrSecretNotFound,
+		},
+		{
+			name:    "missing file",
+			content: `{"Name":"a","DB":{"User":"u","Password":"${file:/not/exists}"}}`,
+			opts:    []Option{UseSecrets()},
+			err:     ErrSecretNotFound,
+		},
+		{
+			name:    "no key provider",
+			content: `{"Name":"a","DB":{"User":"u","Password":"enc:AAAA"}}`,
+			opts:    []Option{UseSecrets()},
+			err:     ErrNoKeyProvider,
+		},
+		{
+			name:    "missing key",
+			content: `{"Name":"a","DB":{"User":"u","Password":"enc:AAAA"}}`,
+			opts:    []Option{WithKeyProvider(KeyFromEnv("NOT_EXISTS_KEY", codec.AesGcm))},
+			err:     ErrSecretNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			file := filepath.Join(dir, "config.json")
+			assert.Nil(t, os.WriteFile(file, []byte(test.content), 0o644))
+			var c secretConfig
+			assert.ErrorIs(t, Load(file, &c, test.opts...), test.err)
+		})
+	}
+}
+
+func TestKeyFromEnv(t *testing.T) {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	assert.Nil(t, err)
+	t.Setenv("CONF_KEY", base64.StdEncoding.EncodeToString(key))
+
+	aead, err := codec.NewAead(codec.ChaCha20Poly1305, key)
+	assert.Nil(t, err)
+	encrypted, err := codec.AeadEncrypt(aead, []byte("pass"), nil)
+	assert.Nil(t, err)
+
+	file := filepath.Join(t.TempDir(), "config.toml")
+	assert.Nil(t, os.WriteFile(file, []byte(`Name = "a"
+[DB]
+User = "u"
+Password = "enc:`+base64.StdEncoding.EncodeToString(encrypted)+`"
+`), 0o644))
+
+	var c secretConfig
+	assert.Nil(t, Load(file, &c, WithKeyProvider(KeyFromEnv("CONF_KEY", codec.ChaCha20Poly1305))))
+	assert.Equal(t, "pass", c.DB.Password)
+
+	t.Setenv("CONF_KEY", base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	assert.NotNil(t, Load(file, &c, WithKeyProvider(KeyFromEnv("CONF_KEY", codec.ChaCha20Poly1305))))
+}
+
+func TestDump(t *testing.T) {
+	type Common struct {
+		Env string
+	}
+	type config struct {
+		Common
+		secretConfig
+		Ignored string `json:"-"`
+		Extra   *secretConfig
+		private string
+	}
+
+	var c config
+	c.Env = "prod"
+	c.Name = "app"
+	c.DB.User = "admin"
+	c.DB.Password = "s3cret"
+	c.Extra = &secretConfig{Name: "extra"}
+	c.Extra.DB.Password = "p"
+	c.private = "x"
+
+	content, err := Dump(c)
+	assert.Nil(t, err)
+	assert.NotContains(t, content, "s3cret")
+	assert.NotContains(t, content, `"p"`)
+	assert.JSONEq(t, `{
+  "Env": "prod",
+  "Extra": {
+    "DB": {"Password": "******", "Token": "", "User": ""},
+    "Keys": null,
+    "Name": "extra"
+  }
+}`, content)
+
+	redacted := Redact(&c.secretConfig).(map[string]any)
+	assert.Equal(t, "******", redacted["DB"].(map[string]any)["Password"])
+	assert.Equal(t, "admin", redacted["DB"].(map[string]any)["User"])
+}
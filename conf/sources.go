@@ -0,0 +1,423 @@
+package conf
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tp-life/utils/encoding"
+	"github.com/tp-life/utils/jsonx"
+	"github.com/tp-life/utils/mapping"
+)
+
+const envKeySep = "_"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+type (
+	// A Source provides config values, values of later sources override the earlier ones.
+	// The method is unexported on purpose, because the builtin sources resolve the keys with the config type,
+	// which is an implementation detail. Use SourceFunc to implement custom sources.
+	Source interface {
+		load(tp reflect.Type) (map[string]any, error)
+	}
+
+	// SourceFunc is a custom source, keys are matched with the config fields case-insensitively.
+	SourceFunc func() (map[string]any, error)
+
+	// Subscriber is a remote source of config contents, *discov.Subscriber implements it.
+	Subscriber interface {
+		AddListener(listener func())
+		Values() []string
+	}
+
+	// MemorySubscriber is an in-memory Subscriber, it can be used instead of discov.Subscriber in tests.
+	MemorySubscriber struct {
+		lock      sync.Mutex
+		values    []string
+		listeners []func()
+	}
+
+	fileSource struct {
+		file     string
+		optional bool
+		opts     []Option
+	}
+
+	envSource struct {
+		prefix string
+	}
+
+	flagSource struct {
+		flags *flag.FlagSet
+	}
+
+	subscriberSource struct {
+		sub    Subscriber
+		format encoding.Format
+		opts   []Option
+	}
+)
+
+// FromMap returns a source of the values in m, it's usually used as the defaults at the bottom.
+func FromMap(m map[string]any) Source {
+	return SourceFunc(func() (map[string]any, error) {
+		return m, nil
+	})
+}
+
+// FromFile returns a source of the config file, the format is chosen by the file extension.
+func FromFile(file string, opts ...Option) Source {
+	return fileSource{
+		file: file,
+		opts: opts,
+	}
+}
+
+// FromOptionalFile is like FromFile, but the source is empty if the file doesn't exist.
+// It's usually used for environment-specific overlay files, like config.prod.yaml.
+func FromOptionalFile(file string, opts ...Option) Source {
+	return fileSource{
+		file:     file,
+		optional: true,
+		opts:     opts,
+	}
+}
+
+// FromEnv returns a source of the environment variables starting with prefix and an underscore.
+// The rest of the names are matched with the config fields, like APP_LOG_LEVEL for Log.Level
+// and APP_MAX_CONNS for MaxConns with the prefix APP.
+func FromEnv(prefix string) Source {
+	return envSource{
+		prefix: prefix,
+	}
+}
+
+// FromFlags returns a source of the flags explicitly set on the command line.
+// Flags are matched with the config fields, like -log.level for Log.Level and -max-conns for MaxConns.
+func FromFlags(flags *flag.FlagSet) Source {
+	return flagSource{
+		flags: flags,
+	}
+}
+
+// FromSubscriber returns a source of the remote config contents in the format,
+// like "json" or "yaml". Multiple values are merged, and they must not set the same key to different values,
+// because the order of the values is not guaranteed, like discov.Subscriber.
+// Use sub.AddListener to reload the config on changes, and UseSecrets or WithKeyProvider to resolve secrets.
+func FromSubscriber(sub Subscriber, format string, opts ...Option) Source {
+	f, err := encoding.ParseFormat(format)
+	if err != nil {
+		return SourceFunc(func() (map[string]any, error) {
+			return nil, err
+		})
+	}
+
+	return subscriberSource{
+		sub:    sub,
+		format: f,
+		opts:   opts,
+	}
+}
+
+// LoadSources loads config into v from the sources, values of later sources override the earlier ones.
+// Maps are merged deeply, other values, including arrays, are replaced as a whole.
+func LoadSources(v any, sources ...Source) error {
+	tp := reflect.TypeOf(v)
+	info, err := buildFieldsInfo(tp, "")
+	if err != nil {
+		return err
+	}
+
+	merged := make(map[string]any)
+	for _, source := range sources {
+		m, err := source.load(tp)
+		if err != nil {
+			return err
+		}
+
+		mergeMap(merged, toLowerCaseKeyMap(m, info))
+	}
+
+	return mapping.UnmarshalJsonMap(merged, v, mapping.WithCanonicalKeyFunc(toLowerCase))
+}
+
+// MustLoadSources loads config into v from the sources, exits on error.
+func MustLoadSources(v any, sources ...Source) {
+	if err := LoadSources(v, sources...); err != nil {
+		log.Fatalf("error: config sources, %s", err.Error())
+	}
+}
+
+// NewMemorySubscriber returns a MemorySubscriber with values.
+func NewMemorySubscriber(values ...string) *MemorySubscriber {
+	return &MemorySubscriber{
+		values: values,
+	}
+}
+
+// AddListener adds listener to s.
+func (s *MemorySubscriber) AddListener(listener func()) {
+	s.lock.Lock()
+	s.listeners = append(s.listeners, listener)
+	s.lock.Unlock()
+}
+
+// Set sets the values and notifies the listeners.
+func (s *MemorySubscriber) Set(values ...string) {
+	s.lock.Lock()
+	s.values = values
+	listeners := append([]func(){}, s.listeners...)
+	s.lock.Unlock()
+
+	for _, listener := range listeners {
+		listener()
+	}
+}
+
+// Values returns all the values.
+func (s *MemorySubscriber) Values() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return append([]string(nil), s.values...)
+}
+
+func (f SourceFunc) load(_ reflect.Type) (map[string]any, error) {
+	return f()
+}
+
+func (s fileSource) load(_ reflect.Type) (map[string]any, error) {
+	content, err := os.ReadFile(s.file)
+	if err != nil {
+		if s.optional && errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	opt := newOptions(s.opts)
+	if opt.env {
+		content = expandEnv(content, opt)
+	}
+
+	format, err := encoding.ParseFormat(s.file)
+	if err != nil {
+		return nil, fmt.Errorf("unrecognized file type: %s", s.file)
+	}
+
+	m, err := toMap(content, format)
+	if err != nil {
+		return nil, fmt.Errorf("config file %s, %w", s.file, err)
+	}
+
+	return resolveMapSecrets(m, opt)
+}
+
+func (s envSource) load(tp reflect.Type) (map[string]any, error) {
+	prefix := s.prefix
+	if len(prefix) > 0 {
+		prefix += envKeySep
+	}
+
+	m := make(map[string]any)
+	for _, env := range os.Environ() {
+		name, val, ok := strings.Cut(env, "=")
+		if !ok || len(name) <= len(prefix) || !strings.EqualFold(name[:len(prefix)], prefix) {
+			continue
+		}
+
+		setByName(m, tp, name[len(prefix):], val)
+	}
+
+	return m, nil
+}
+
+func (s flagSource) load(tp reflect.Type) (map[string]any, error) {
+	m := make(map[string]any)
+	s.flags.Visit(func(f *flag.Flag) {
+		setByName(m, tp, f.Name, f.Value.String())
+	})
+
+	return m, nil
+}
+
+func (s subscriberSource) load(_ reflect.Type) (map[string]any, error) {
+	merged := make(map[string]any)
+	for _, val := range s.sub.Values() {
+		m, err := toMap([]byte(val), s.format)
+		if err != nil {
+			return nil, fmt.Errorf("remote config, %w", err)
+		}
+
+		if err = mergeDisjointMap(merged, m, ""); err != nil {
+			return nil, fmt.Errorf("remote config, %w", err)
+		}
+	}
+
+	return resolveMapSecrets(merged, newOptions(s.opts))
+}
+
+// mergeMap merges src into dst deeply, values in src take precedence.
+func mergeMap(dst, src map[string]any) {
+	for key, val := range src {
+		srcMap, ok := val.(map[string]any)
+		if !ok {
+			dst[key] = val
+			continue
+		}
+
+		dstMap, ok := dst[key].(map[string]any)
+		if !ok {
+			dstMap = make(map[string]any)
+			dst[key] = dstMap
+		}
+		mergeMap(dstMap, srcMap)
+	}
+}
+
+// mergeDisjointMap merges src into dst deeply, it fails if they have different values of the same key.
+func mergeDisjointMap(dst, src map[string]any, parent string) error {
+	for key, val := range src {
+		fullName := getFullName(parent, key)
+		prev, ok := dst[key]
+		if !ok {
+			dst[key] = val
+			continue
+		}
+
+		srcMap, srcOk := val.(map[string]any)
+		dstMap, dstOk := prev.(map[string]any)
+		switch {
+		case srcOk && dstOk:
+			if err := mergeDisjointMap(dstMap, srcMap, fullName); err != nil {
+				return err
+			}
+		case !reflect.DeepEqual(prev, val):
+			return fmt.Errorf("conflict values of key %s", fullName)
+		}
+	}
+
+	return nil
+}
+
+func toMap(content []byte, format encoding.Format) (map[string]any, error) {
+	data, err := encoding.Convert(content, format, encoding.FormatJson)
+	if err != nil {
+		return nil, err
+	}
+
+	var m map[string]any
+	if err = jsonx.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// setByName sets val into m at the path of the field matched by name, unmatched names are ignored.
+func setByName(m map[string]any, tp reflect.Type, name, val string) {
+	path, ft, ok := resolveKey(tp, splitKey(name))
+	if !ok {
+		return
+	}
+
+	for _, key := range path[:len(path)-1] {
+		child, ok := m[key].(map[string]any)
+		if !ok {
+			child = make(map[string]any)
+			m[key] = child
+		}
+		m = child
+	}
+	m[path[len(path)-1]] = typedValue(ft, val)
+}
+
+// resolveKey finds the canonical path of the field named by parts,
+// consecutive parts are joined to match a field case-insensitively, like [max conns] for MaxConns.
+// The map keys are kept as is, because they are not canonicalized on unmarshaling.
+func resolveKey(tp reflect.Type, parts []string) ([]string, reflect.Type, bool) {
+	if len(parts) == 0 {
+		return nil, nil, false
+	}
+
+	tp = mapping.Deref(tp)
+	switch tp.Kind() {
+	case reflect.Struct:
+		for i := 0; i < tp.NumField(); i++ {
+			field := tp.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			ft := mapping.Deref(field.Type)
+			if field.Anonymous && ft.Kind() == reflect.Struct {
+				if path, t, ok := resolveKey(ft, parts); ok {
+					return path, t, true
+				}
+				continue
+			}
+
+			name := toLowerCase(getTagName(field))
+			normalized := strings.Join(strings.FieldsFunc(name, isKeySep), "")
+			for j := 1; j <= len(parts); j++ {
+				if toLowerCase(strings.Join(parts[:j], "")) != normalized {
+					continue
+				}
+				if j == len(parts) {
+					return []string{name}, field.Type, true
+				}
+				if path, t, ok := resolveKey(field.Type, parts[j:]); ok {
+					return append([]string{name}, path...), t, true
+				}
+			}
+		}
+	case reflect.Map:
+		if len(parts) == 1 {
+			return parts, tp.Elem(), true
+		}
+		if path, t, ok := resolveKey(tp.Elem(), parts[1:]); ok {
+			return append([]string{parts[0]}, path...), t, true
+		}
+	}
+
+	return nil, nil, false
+}
+
+// typedValue converts val to the json value of the field type, slices and maps are kept as json strings.
+func typedValue(tp reflect.Type, val string) any {
+	tp = mapping.Deref(tp)
+	if tp == durationType {
+		return val
+	}
+
+	switch tp.Kind() {
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(val); err == nil {
+			return b
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return json.Number(strings.TrimSpace(val))
+	}
+
+	return val
+}
+
+// splitKey splits names like LOG_LEVEL, log.level and max-conns into parts.
+func splitKey(name string) []string {
+	return strings.FieldsFunc(name, isKeySep)
+}
+
+func isKeySep(r rune) bool {
+	return r == '.' || r == '-' || r == '_'
+}
@@ -0,0 +1,148 @@
+package conf
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tp-life/utils/discov"
+)
+
+var _ Subscriber = (*discov.Subscriber)(nil)
+
+type sourcesConfig struct {
+	Name     string
+	MaxConns int           `json:",default=100"`
+	Timeout  time.Duration `json:",default=1s"`
+	Verbose  bool          `json:",optional"`
+	Log      struct {
+		Level string `json:",default=info"`
+		Path  string `json:",optional"`
+	}
+	Hosts  []string          `json:",optional"`
+	Labels map[string]string `json:",optional"`
+}
+
+func TestLoadSources(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "config.yaml")
+	assert.Nil(t, os.WriteFile(base, []byte(`Name: base
+Log:
+  Level: warn
+  Path: /var/log
+Hosts: [a, b]
+Labels:
+  Team: infra
+`), 0o644))
+	overlay := filepath.Join(dir, "config.prod.json")
+	assert.Nil(t, os.WriteFile(overlay, []byte(`{"log":{"level":"error"},"hosts":["c"],"labels":{"zone":"z1"}}`), 0o644))
+
+	t.Setenv("APP_LOG_LEVEL", "debug")
+	t.Setenv("APP_MAX_CONNS", "200")
+	t.Setenv("APP_UNKNOWN_KEY", "ignored")
+	t.Setenv("OTHER_NAME", "ignored")
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.String("name", "", "")
+	flags.Bool("verbose", false, "")
+	flags.Duration("timeout", 0, "")
+	assert.Nil(t, flags.Parse([]string{"-verbose", "-timeout=3s"}))
+
+	remote := NewMemorySubscriber(`{"Log":{"Path":"/data/log"}}`)
+
+	var c sourcesConfig
+	err := LoadSources(&c,
+		FromMap(map[string]any{"name": "default", "maxConns": 10}),
+		FromFile(base),
+		FromOptionalFile(overlay),
+		FromOptionalFile(filepath.Join(dir, "config.dev.json")),
+		FromEnv("APP"),
+		FromFlags(flags),
+		FromSubscriber(remote, "json"),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, "base", c.Name)
+	assert.Equal(t, 200, c.MaxConns)
+	assert.Equal(t, 3*time.Second, c.Timeout)
+	assert.True(t, c.Verbose)
+	assert.Equal(t, "debug", c.Log.Level)
+	assert.Equal(t, "/data/log", c.Log.Path)
+	assert.Equal(t, []string{"c"}, c.Hosts)
+	assert.Equal(t, map[string]string{"Team": "infra", "zone": "z1"}, c.Labels)
+}
+
+func TestLoadSourcesErrors(t *testing.T) {
+	var c sourcesConfig
+	assert.NotNil(t, LoadSources(&c, FromFile(filepath.Join(t.TempDir(), "none.yaml"))))
+
+	file := filepath.Join(t.TempDir(), "config.xml")
+	assert.Nil(t, os.WriteFile(file, []byte("<xml/>"), 0o644))
+	assert.NotNil(t, LoadSources(&c, FromFile(file)))
+
+	assert.NotNil(t, LoadSources(&c, FromSubscriber(NewMemorySubscriber(), "xml")))
+	assert.NotNil(t, LoadSources(&c, FromSubscriber(NewMemorySubscriber("name: [a"), "yaml")))
+
+	t.Setenv("APP_MAX_CONNS", "many")
+	assert.NotNil(t, LoadSources(&c, FromEnv("APP")))
+}
+
+func TestMemorySubscriber(t *testing.T) {
+	sub := NewMemorySubscriber("name: foo")
+	var changed int
+	sub.AddListener(func() {
+		changed++
+	})
+	sub.Set("name: bar", "maxConns: 5")
+	assert.Equal(t, 1, changed)
+	assert.Equal(t, []string{"name: bar", "maxConns: 5"}, sub.Values())
+
+	var c sourcesConfig
+	assert.Nil(t, LoadSources(&c, FromSubscriber(sub, "yaml")))
+	assert.Equal(t, "bar", c.Name)
+	assert.Equal(t, 5, c.MaxConns)
+
+	sub.Set("name: bar", "name: baz")
+	assert.NotNil(t, LoadSources(&c, FromSubscriber(sub, "yaml")))
+}
+
+func TestResolveKey(t *testing.T) {
+	type Inner struct {
+		Level string `json:"log_level"`
+	}
+	type config struct {
+		Inner
+		DB struct {
+			MaxIdle int `json:"maxIdle"`
+		}
+		Rules map[string]struct {
+			Limit int
+		}
+	}
+
+	var c config
+	tp := reflect.TypeOf(&c)
+	tests := []struct {
+		name   string
+		expect []string
+	}{
+		{name: "LOG_LEVEL", expect: []string{"log_level"}},
+		{name: "db.max-idle", expect: []string{"db", "maxidle"}},
+		{name: "DB_MAXIDLE", expect: []string{"db", "maxidle"}},
+		{name: "rules_login_limit", expect: []string{"rules", "login", "limit"}},
+		{name: "RULES_Login_LIMIT", expect: []string{"rules", "Login", "limit"}},
+		{name: "unknown"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			path, _, ok := resolveKey(tp, splitKey(test.name))
+			assert.Equal(t, len(test.expect) > 0, ok)
+			assert.Equal(t, test.expect, path)
+		})
+	}
+}
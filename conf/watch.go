@@ -0,0 +1,303 @@
+package conf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tp-life/utils/diff"
+	"github.com/tp-life/utils/logx"
+	"github.com/tp-life/utils/threading"
+	"github.com/tp-life/utils/timex"
+	"github.com/tp-life/utils/validation"
+)
+
+const defaultPollInterval = time.Second
+
+// ErrInvalidConfig indicates that the reloaded config failed the validation.
+var ErrInvalidConfig = errors.New("invalid config")
+
+type (
+	// ChangeHandler is called with the old and new config after the config changed,
+	// changes describes what changed, so that handlers can react selectively.
+	ChangeHandler[T any] func(prev, cur T, changes diff.Changelog)
+
+	// WatchOption customizes a Watcher.
+	WatchOption func(opt *watchOptions)
+
+	watchOptions struct {
+		interval    time.Duration
+		loadOpts    []Option
+		onError     func(err error)
+		newTicker   func(d time.Duration) timex.Ticker
+		newNotifier func(file string) (notifier, error)
+	}
+
+	// notifier sends on Events when the file might be changed, Events is closed on errors.
+	notifier interface {
+		Close() error
+		Events() <-chan struct{}
+	}
+
+	subscriber[T any] struct {
+		id uint64
+		fn ChangeHandler[T]
+	}
+
+	// A Watcher keeps the config loaded from a file, and reloads it when the file changes.
+	Watcher[T any] struct {
+		file      string
+		opts      watchOptions
+		notifier  notifier
+		validate  func(T) error
+		value     atomic.Pointer[T]
+		content   []byte
+		modTime   time.Time
+		size      int64
+		lock      sync.Mutex
+		handlers  []subscriber[T]
+		handlerId uint64
+		reloadMu  sync.Mutex
+		done      chan struct{}
+		stopOnce  sync.Once
+	}
+)
+
+// WithPollInterval customizes the interval to check the file for changes, defaults to 1s.
+// The polling is the fallback of the file notifications, for the platforms and file systems without them.
+func WithPollInterval(interval time.Duration) WatchOption {
+	return func(opt *watchOptions) {
+		opt.interval = interval
+	}
+}
+
+// WithLoadOptions customizes the options used to load the config file, like UseEnv.
+func WithLoadOptions(opts ...Option) WatchOption {
+	return func(opt *watchOptions) {
+		opt.loadOpts = append(opt.loadOpts, opts...)
+	}
+}
+
+// WithReloadErrorHandler customizes the handler of errors on reloading,
+// the errors are logged by default. The previous config is kept on errors.
+func WithReloadErrorHandler(fn func(err error)) WatchOption {
+	return func(opt *watchOptions) {
+		opt.onError = fn
+	}
+}
+
+// Watch loads the config of type T from file, and reloads it when the file changes.
+// The changes are detected by the file notifications of the directory of file on linux,
+// so that the files replaced by renaming, like the mounted ConfigMaps in kubernetes, are handled as well.
+// The file is polled at the interval of WithPollInterval as well, which is the fallback on other platforms,
+// on the file systems without notifications like NFS, or if the notifications fail.
+// The content is compared as well, so that touching the file doesn't trigger the handlers.
+// The new config is validated before being swapped in if T or *T implements validation.Validator,
+// an invalid config is rejected and the previous one is kept.
+// The returned error is not nil if the initial load fails.
+func Watch[T any](file string, opts ...WatchOption) (*Watcher[T], error) {
+	return WatchWithValidator[T](file, nil, opts...)
+}
+
+// WatchWithValidator is like Watch, but validates the config with validate as well.
+func WatchWithValidator[T any](file string, validate func(T) error, opts ...WatchOption) (*Watcher[T], error) {
+	o := watchOptions{
+		interval: defaultPollInterval,
+		onError: func(err error) {
+			logx.Errorf("reload config file %s, error: %v", file, err)
+		},
+		newTicker:   timex.NewTicker,
+		newNotifier: newNotifier,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	w := &Watcher[T]{
+		file:     file,
+		opts:     o,
+		validate: validate,
+		done:     make(chan struct{}),
+	}
+	if _, err := w.reload(true); err != nil {
+		return nil, err
+	}
+	if o.newNotifier != nil {
+		// the polling still works if the notifications are not available
+		if n, err := o.newNotifier(file); err == nil {
+			w.notifier = n
+		}
+	}
+
+	ticker := o.newTicker(o.interval)
+	threading.GoSafe(func() {
+		w.watch(ticker)
+	})
+
+	return w, nil
+}
+
+// MustWatch is like Watch, but exits on error.
+func MustWatch[T any](file string, opts ...WatchOption) *Watcher[T] {
+	w, err := Watch[T](file, opts...)
+	if err != nil {
+		log.Fatalf("error: config file %s, %s", file, err.Error())
+	}
+
+	return w
+}
+
+// Get returns the current config.
+func (w *Watcher[T]) Get() T {
+	return *w.value.Load()
+}
+
+// Reload reloads the config file immediately, even if the file is not changed.
+func (w *Watcher[T]) Reload() error {
+	_, err := w.reload(true)
+	return err
+}
+
+// Stop stops watching the file, the current config is still available.
+func (w *Watcher[T]) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.done)
+	})
+}
+
+// Subscribe registers fn to be called on config changes,
+// the returned function unsubscribes it.
+func (w *Watcher[T]) Subscribe(fn ChangeHandler[T]) (unsubscribe func()) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.handlerId++
+	id := w.handlerId
+	w.handlers = append(w.handlers, subscriber[T]{id: id, fn: fn})
+
+	return func() {
+		w.lock.Lock()
+		defer w.lock.Unlock()
+
+		for i, sub := range w.handlers {
+			if sub.id == id {
+				w.handlers = append(w.handlers[:i:i], w.handlers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+func (w *Watcher[T]) watch(ticker timex.Ticker) {
+	defer ticker.Stop()
+
+	var events <-chan struct{}
+	if w.notifier != nil {
+		events = w.notifier.Events()
+		defer w.notifier.Close()
+	}
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case _, ok := <-events:
+			if !ok {
+				// keep polling if the notifications fail
+				events = nil
+				continue
+			}
+		case <-ticker.Chan():
+		}
+
+		if _, err := w.reload(false); err != nil {
+			w.opts.onError(err)
+		}
+	}
+}
+
+// reload loads the file if it changed or force is true, returns true if the config is swapped.
+func (w *Watcher[T]) reload(force bool) (bool, error) {
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+
+	info, err := os.Stat(w.file)
+	if err != nil {
+		return false, err
+	}
+	// check the content only if the stat changed, the content might be the same after touching
+	if !force && info.ModTime().Equal(w.modTime) && info.Size() == w.size {
+		return false, nil
+	}
+
+	content, err := os.ReadFile(w.file)
+	if err != nil {
+		return false, err
+	}
+
+	prev := w.value.Load()
+	if !force && prev != nil && bytes.Equal(content, w.content) {
+		w.modTime, w.size = info.ModTime(), info.Size()
+		return false, nil
+	}
+
+	cur := new(T)
+	if err = loadContent(w.file, content, cur, w.opts.loadOpts...); err != nil {
+		return false, err
+	}
+	if err = w.check(cur); err != nil {
+		return false, err
+	}
+
+	w.value.Store(cur)
+	w.content = content
+	w.modTime, w.size = info.ModTime(), info.Size()
+	if prev != nil {
+		w.notify(*prev, *cur)
+	}
+
+	return true, nil
+}
+
+func (w *Watcher[T]) check(cur *T) error {
+	var err error
+	if v, ok := any(cur).(validation.Validator); ok {
+		err = v.Validate()
+	} else if v, ok := any(*cur).(validation.Validator); ok {
+		err = v.Validate()
+	}
+	if err == nil && w.validate != nil {
+		err = w.validate(*cur)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+
+	return nil
+}
+
+func (w *Watcher[T]) notify(prev, cur T) {
+	changes, err := diff.Diff(prev, cur)
+	if err != nil {
+		w.opts.onError(err)
+	}
+	if err == nil && len(changes) == 0 {
+		return
+	}
+
+	w.lock.Lock()
+	handlers := w.handlers
+	w.lock.Unlock()
+
+	// handlers are called in the order of subscription, a panic in one handler doesn't affect the others
+	for _, sub := range handlers {
+		threading.RunSafe(func() {
+			sub.fn(prev, cur, changes)
+		})
+	}
+}
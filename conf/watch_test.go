@@ -0,0 +1,179 @@
+package conf
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tp-life/utils/diff"
+	"github.com/tp-life/utils/timex"
+)
+
+type watchLog struct {
+	Level string `json:",default=info"`
+	Path  string `json:",optional"`
+}
+
+type watchConfig struct {
+	Name string
+	Log  watchLog
+	Rate int `json:",default=100"`
+}
+
+func (c watchConfig) Validate() error {
+	if c.Rate <= 0 {
+		return errors.New("rate must be positive")
+	}
+
+	return nil
+}
+
+func newWatchTestFile(t *testing.T, content string) string {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	writeWatchTestFile(t, file, content)
+	return file
+}
+
+func writeWatchTestFile(t *testing.T, file, content string) {
+	assert.Nil(t, os.WriteFile(file, []byte(content), 0o644))
+	// make sure the modification is visible even on file systems with coarse timestamps
+	stamp := time.Now().Add(time.Duration(len(content)) * time.Second)
+	assert.Nil(t, os.Chtimes(file, stamp, stamp))
+}
+
+func withTestTicker(ticker timex.Ticker) WatchOption {
+	return func(opt *watchOptions) {
+		opt.newTicker = func(time.Duration) timex.Ticker {
+			return ticker
+		}
+		// reload on ticks only
+		opt.newNotifier = nil
+	}
+}
+
+func TestWatchNotify(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("file notifications are only supported on linux")
+	}
+
+	file := newWatchTestFile(t, "name: foo\n")
+	w, err := Watch[watchConfig](file, WithPollInterval(time.Hour))
+	assert.Nil(t, err)
+	defer w.Stop()
+
+	names := make(chan string, 1)
+	w.Subscribe(func(_, cur watchConfig, _ diff.Changelog) {
+		names <- cur.Name
+	})
+
+	// replaced by renaming, like the mounted ConfigMaps in kubernetes
+	tmp := filepath.Join(filepath.Dir(file), "config.tmp")
+	writeWatchTestFile(t, tmp, "name: bar\n")
+	assert.Nil(t, os.Rename(tmp, file))
+	select {
+	case name := <-names:
+		assert.Equal(t, "bar", name)
+	case <-time.After(5 * time.Second):
+		t.Fatal("config not reloaded on notifications")
+	}
+
+	writeWatchTestFile(t, file, "name: baz\n")
+	select {
+	case name := <-names:
+		assert.Equal(t, "baz", name)
+	case <-time.After(5 * time.Second):
+		t.Fatal("config not reloaded on notifications")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	file := newWatchTestFile(t, "name: foo\n")
+	ticker := timex.NewFakeTicker()
+	errs := make(chan error, 1)
+	w, err := Watch[watchConfig](file, withTestTicker(ticker), WithReloadErrorHandler(func(err error) {
+		errs <- err
+	}))
+	assert.Nil(t, err)
+	defer w.Stop()
+	assert.Equal(t, watchConfig{Name: "foo", Log: watchLog{Level: "info"}, Rate: 100}, w.Get())
+
+	type event struct {
+		prev, cur watchConfig
+		changes   diff.Changelog
+	}
+	events := make(chan event, 1)
+	w.Subscribe(func(prev, cur watchConfig, changes diff.Changelog) {
+		events <- event{prev: prev, cur: cur, changes: changes}
+	})
+	unsubscribe := w.Subscribe(func(prev, cur watchConfig, changes diff.Changelog) {
+		t.Error("unsubscribed handler should not be called")
+	})
+	unsubscribe()
+
+	writeWatchTestFile(t, file, "name: foo\nlog:\n  level: debug\n")
+	ticker.Tick()
+	select {
+	case e := <-events:
+		assert.Equal(t, "info", e.prev.Log.Level)
+		assert.Equal(t, "debug", e.cur.Log.Level)
+		assert.Len(t, e.changes, 1)
+		assert.Equal(t, []string{"Log", "Level"}, e.changes[0].Path)
+		assert.Equal(t, "debug", e.changes[0].To)
+	case <-time.After(time.Second):
+		t.Fatal("no change notified")
+	}
+	assert.Equal(t, "debug", w.Get().Log.Level)
+
+	// invalid config is rejected, the previous one is kept
+	writeWatchTestFile(t, file, "name: bar\nlog:\n  level: debug\nrate: -1\n")
+	ticker.Tick()
+	select {
+	case err := <-errs:
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+	case <-time.After(time.Second):
+		t.Fatal("no error reported")
+	}
+	assert.Equal(t, "foo", w.Get().Name)
+
+	writeWatchTestFile(t, file, "name: bar\n")
+	assert.Nil(t, w.Reload())
+	assert.Equal(t, "bar", w.Get().Name)
+	e := <-events
+	assert.Len(t, e.changes, 2)
+}
+
+func TestWatchWithValidator(t *testing.T) {
+	file := newWatchTestFile(t, "name: foo\n")
+	_, err := WatchWithValidator[watchConfig](file, func(c watchConfig) error {
+		if c.Name == "foo" {
+			return errors.New("foo is reserved")
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+
+	_, err = Watch[watchConfig](filepath.Join(t.TempDir(), "none.yaml"))
+	assert.NotNil(t, err)
+}
+
+func TestWatchUnchanged(t *testing.T) {
+	file := newWatchTestFile(t, "name: foo\n")
+	w, err := Watch[watchConfig](file, withTestTicker(timex.NewFakeTicker()))
+	assert.Nil(t, err)
+	defer w.Stop()
+
+	w.Subscribe(func(prev, cur watchConfig, changes diff.Changelog) {
+		t.Error("handler should not be called without changes")
+	})
+
+	stamp := time.Now().Add(time.Hour)
+	assert.Nil(t, os.Chtimes(file, stamp, stamp))
+	swapped, err := w.reload(false)
+	assert.Nil(t, err)
+	assert.False(t, swapped)
+	assert.Nil(t, w.Reload())
+}
@@ -86,6 +86,8 @@ type Differ struct {
 	FlattenEmbeddedStructs bool
 	ConvertCompatibleTypes bool
 	Filter                 FilterFunc
+	defaultResolver        ConflictResolver
+	pathResolvers          []pathResolver
 }
 
 // Changelog stores a list of changed items
@@ -0,0 +1,268 @@
+package diff
+
+import (
+	"reflect"
+)
+
+// ConflictType represents an enum with all the supported conflict types
+type ConflictType uint8
+
+const (
+	// ConflictUpdateUpdate both sides changed the same path to different values
+	ConflictUpdateUpdate ConflictType = iota + 1
+	// ConflictDeleteUpdate ours deleted a path that theirs modified
+	ConflictDeleteUpdate
+	// ConflictUpdateDelete ours modified a path that theirs deleted
+	ConflictUpdateDelete
+)
+
+func (t ConflictType) String() string {
+	switch t {
+	case ConflictUpdateUpdate:
+		return "update/update"
+	case ConflictDeleteUpdate:
+		return "delete/update"
+	case ConflictUpdateDelete:
+		return "update/delete"
+	default:
+		return "unknown"
+	}
+}
+
+// Conflict stores two sets of overlapping changes made to the same path of a
+// common base. Ours and Theirs hold the changes of either side that touch Path
+// or anything below it.
+type Conflict struct {
+	Type     ConflictType `json:"type"`
+	Path     []string     `json:"path"`
+	Ours     Changelog    `json:"ours"`
+	Theirs   Changelog    `json:"theirs"`
+	Resolved bool         `json:"resolved"`
+}
+
+// Conflicts stores a list of conflicts found during a three-way merge
+type Conflicts []Conflict
+
+// Unresolved returns the conflicts no resolver could settle
+func (cs Conflicts) Unresolved() Conflicts {
+	var ret Conflicts
+	for _, c := range cs {
+		if !c.Resolved {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
+
+// HasUnresolved indicates if any conflict was left unresolved
+func (cs Conflicts) HasUnresolved() bool {
+	return len(cs.Unresolved()) > 0
+}
+
+// ConflictResolver decides which changes to apply for a conflict. Returning
+// false leaves the conflict unresolved and the target untouched at its path.
+type ConflictResolver func(c Conflict) (Changelog, bool)
+
+type pathResolver struct {
+	path     []string
+	resolver ConflictResolver
+}
+
+// ResolveOurs is a ConflictResolver that keeps our side of every conflict
+func ResolveOurs(c Conflict) (Changelog, bool) {
+	return c.Ours, true
+}
+
+// ResolveTheirs is a ConflictResolver that keeps their side of every conflict
+func ResolveTheirs(c Conflict) (Changelog, bool) {
+	return c.Theirs, true
+}
+
+// ThreeWayMerge diffs base against ours and theirs, and applies both sets of
+// changes to target. target must be a pointer to a copy of base, it holds the
+// merged value once the call returns.
+func ThreeWayMerge(base, ours, theirs, target interface{}, opts ...func(d *Differ) error) (PatchLog, Conflicts, error) {
+	d, err := NewDiffer(opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return d.ThreeWayMerge(base, ours, theirs, target)
+}
+
+// ThreeWayMerge diffs base against ours and theirs, and applies both sets of
+// changes to target. Changes that do not overlap, or that are identical on
+// both sides, are applied as is. Overlapping changes are reported as conflicts
+// and handed to the registered resolvers, see MergeResolver and
+// MergePathResolver. Unresolved conflicts are not applied, so target keeps the
+// base value for their paths.
+func (d *Differ) ThreeWayMerge(base, ours, theirs, target interface{}) (PatchLog, Conflicts, error) {
+	ocl, err := d.Diff(base, ours)
+	if err != nil {
+		return nil, nil, err
+	}
+	tcl, err := d.Diff(base, theirs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	merged, conflicts := mergeChangelogs(ocl, tcl)
+	for i := range conflicts {
+		resolver := d.conflictResolver(conflicts[i].Path)
+		if resolver == nil {
+			continue
+		}
+		if cl, ok := resolver(conflicts[i]); ok {
+			conflicts[i].Resolved = true
+			merged = append(merged, cl...)
+		}
+	}
+
+	return d.Patch(merged, target), conflicts, nil
+}
+
+func (d *Differ) conflictResolver(path []string) ConflictResolver {
+	for _, pr := range d.pathResolvers {
+		if len(pr.path) == len(path) && pathmatch(pr.path, path) {
+			return pr.resolver
+		}
+	}
+	return d.defaultResolver
+}
+
+// mergeChangelogs splits two changelogs made against the same base into the
+// changes that can be applied together and the groups of conflicting changes.
+func mergeChangelogs(ours, theirs Changelog) (Changelog, Conflicts) {
+	n := len(ours)
+	groups := newUnionFind(n + len(theirs))
+	conflicting := make([]bool, n+len(theirs))
+	duplicate := make([]bool, len(theirs))
+
+	for i, oc := range ours {
+		for j, tc := range theirs {
+			if !overlaps(oc.Path, tc.Path) {
+				continue
+			}
+			if sameChange(oc, tc) {
+				duplicate[j] = true
+				continue
+			}
+			conflicting[i], conflicting[n+j] = true, true
+			groups.union(i, n+j)
+		}
+	}
+
+	var merged Changelog
+	for i, oc := range ours {
+		if !conflicting[i] {
+			merged = append(merged, oc)
+		}
+	}
+	for j, tc := range theirs {
+		if !conflicting[n+j] && !duplicate[j] {
+			merged = append(merged, tc)
+		}
+	}
+
+	var conflicts Conflicts
+	index := make(map[int]int)
+	for i := range conflicting {
+		if !conflicting[i] {
+			continue
+		}
+		root := groups.find(i)
+		pos, ok := index[root]
+		if !ok {
+			pos = len(conflicts)
+			index[root] = pos
+			conflicts = append(conflicts, Conflict{})
+		}
+
+		c := &conflicts[pos]
+		if i < n {
+			c.Ours = append(c.Ours, ours[i])
+			c.Path = shorterPath(c.Path, ours[i].Path)
+		} else {
+			c.Theirs = append(c.Theirs, theirs[i-n])
+			c.Path = shorterPath(c.Path, theirs[i-n].Path)
+		}
+	}
+
+	for i := range conflicts {
+		conflicts[i].Type = conflictType(conflicts[i].Ours, conflicts[i].Theirs)
+	}
+
+	return merged, conflicts
+}
+
+func conflictType(ours, theirs Changelog) ConflictType {
+	switch {
+	case allOfType(ours, DELETE) && !allOfType(theirs, DELETE):
+		return ConflictDeleteUpdate
+	case allOfType(theirs, DELETE) && !allOfType(ours, DELETE):
+		return ConflictUpdateDelete
+	default:
+		return ConflictUpdateUpdate
+	}
+}
+
+func allOfType(cl Changelog, t string) bool {
+	for _, c := range cl {
+		if c.Type != t {
+			return false
+		}
+	}
+	return len(cl) > 0
+}
+
+func sameChange(a, b Change) bool {
+	return a.Type == b.Type && equalPath(a.Path, b.Path) && reflect.DeepEqual(a.To, b.To)
+}
+
+// overlaps reports whether one path equals or is nested under the other
+func overlaps(a, b []string) bool {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	return equalPath(a, b[:len(a)])
+}
+
+func equalPath(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func shorterPath(current, path []string) []string {
+	if current == nil || len(path) < len(current) {
+		return path
+	}
+	return current
+}
+
+type unionFind []int
+
+func newUnionFind(n int) unionFind {
+	uf := make(unionFind, n)
+	for i := range uf {
+		uf[i] = i
+	}
+	return uf
+}
+
+func (uf unionFind) find(i int) int {
+	for uf[i] != i {
+		uf[i] = uf[uf[i]]
+		i = uf[i]
+	}
+	return i
+}
+
+func (uf unionFind) union(a, b int) {
+	uf[uf.find(a)] = uf.find(b)
+}
@@ -0,0 +1,168 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mergeConf struct {
+	Name    string            `diff:"name"`
+	Port    int               `diff:"port"`
+	Tags    []string          `diff:"tags"`
+	Labels  map[string]string `diff:"labels"`
+	Enabled bool              `diff:"enabled"`
+}
+
+func newMergeBase() mergeConf {
+	return mergeConf{
+		Name:   "svc",
+		Port:   80,
+		Tags:   []string{"a"},
+		Labels: map[string]string{"env": "dev", "team": "core"},
+	}
+}
+
+func TestThreeWayMergeNoConflicts(t *testing.T) {
+	base := newMergeBase()
+	ours := newMergeBase()
+	ours.Port = 8080
+	theirs := newMergeBase()
+	theirs.Name = "api"
+	theirs.Labels["zone"] = "eu"
+
+	target := newMergeBase()
+	pl, conflicts, err := ThreeWayMerge(base, ours, theirs, &target)
+	require.NoError(t, err)
+	assert.Empty(t, conflicts)
+	assert.False(t, pl.HasErrors())
+	assert.Equal(t, "api", target.Name)
+	assert.Equal(t, 8080, target.Port)
+	assert.Equal(t, "eu", target.Labels["zone"])
+}
+
+func TestThreeWayMergeSameChange(t *testing.T) {
+	base := newMergeBase()
+	ours := newMergeBase()
+	ours.Enabled = true
+	theirs := newMergeBase()
+	theirs.Enabled = true
+
+	target := newMergeBase()
+	pl, conflicts, err := ThreeWayMerge(base, ours, theirs, &target)
+	require.NoError(t, err)
+	assert.Empty(t, conflicts)
+	assert.Len(t, pl, 1)
+	assert.True(t, target.Enabled)
+}
+
+func TestThreeWayMergeConflicts(t *testing.T) {
+	base := newMergeBase()
+	ours := newMergeBase()
+	ours.Port = 8080
+	delete(ours.Labels, "team")
+	theirs := newMergeBase()
+	theirs.Port = 9090
+	theirs.Labels["team"] = "infra"
+
+	target := newMergeBase()
+	_, conflicts, err := ThreeWayMerge(base, ours, theirs, &target)
+	require.NoError(t, err)
+	require.Len(t, conflicts, 2)
+	assert.True(t, conflicts.HasUnresolved())
+
+	byPath := map[string]Conflict{}
+	for _, c := range conflicts {
+		byPath[c.Path[len(c.Path)-1]] = c
+	}
+	assert.Equal(t, ConflictUpdateUpdate, byPath["port"].Type)
+	assert.Equal(t, []string{"port"}, byPath["port"].Path)
+	assert.Equal(t, ConflictDeleteUpdate, byPath["team"].Type)
+
+	// unresolved conflicts keep the base value
+	assert.Equal(t, 80, target.Port)
+	assert.Equal(t, "core", target.Labels["team"])
+}
+
+func TestThreeWayMergeResolvers(t *testing.T) {
+	base := newMergeBase()
+	ours := newMergeBase()
+	ours.Port = 8080
+	ours.Name = "ours"
+	theirs := newMergeBase()
+	theirs.Port = 9090
+	theirs.Name = "theirs"
+
+	t.Run("ours", func(t *testing.T) {
+		target := newMergeBase()
+		_, conflicts, err := ThreeWayMerge(base, ours, theirs, &target, MergeResolver(ResolveOurs))
+		require.NoError(t, err)
+		assert.Len(t, conflicts, 2)
+		assert.False(t, conflicts.HasUnresolved())
+		assert.Equal(t, 8080, target.Port)
+		assert.Equal(t, "ours", target.Name)
+	})
+
+	t.Run("theirs", func(t *testing.T) {
+		target := newMergeBase()
+		_, _, err := ThreeWayMerge(base, ours, theirs, &target, MergeResolver(ResolveTheirs))
+		require.NoError(t, err)
+		assert.Equal(t, 9090, target.Port)
+		assert.Equal(t, "theirs", target.Name)
+	})
+
+	t.Run("path", func(t *testing.T) {
+		target := newMergeBase()
+		_, conflicts, err := ThreeWayMerge(base, ours, theirs, &target,
+			MergeResolver(ResolveTheirs),
+			MergePathResolver([]string{"port"}, func(c Conflict) (Changelog, bool) {
+				return Changelog{{Type: UPDATE, Path: c.Path, From: 80, To: 10000}}, true
+			}),
+		)
+		require.NoError(t, err)
+		assert.False(t, conflicts.HasUnresolved())
+		assert.Equal(t, 10000, target.Port)
+		assert.Equal(t, "theirs", target.Name)
+	})
+
+	t.Run("declined", func(t *testing.T) {
+		target := newMergeBase()
+		_, conflicts, err := ThreeWayMerge(base, ours, theirs, &target,
+			MergePathResolver([]string{"name"}, func(c Conflict) (Changelog, bool) {
+				return nil, false
+			}),
+		)
+		require.NoError(t, err)
+		assert.Len(t, conflicts.Unresolved(), 2)
+		assert.Equal(t, "svc", target.Name)
+	})
+}
+
+func TestThreeWayMergeNestedConflict(t *testing.T) {
+	type item struct {
+		ID    string `diff:"id,identifier"`
+		Value int    `diff:"value"`
+	}
+	type doc struct {
+		Items map[string]item `diff:"items"`
+	}
+
+	base := doc{Items: map[string]item{"a": {ID: "a", Value: 1}}}
+	ours := doc{Items: map[string]item{}}
+	theirs := doc{Items: map[string]item{"a": {ID: "a", Value: 2}}}
+
+	target := doc{Items: map[string]item{"a": {ID: "a", Value: 1}}}
+	_, conflicts, err := ThreeWayMerge(base, ours, theirs, &target)
+	require.NoError(t, err)
+	require.Len(t, conflicts, 1)
+	assert.Equal(t, ConflictDeleteUpdate, conflicts[0].Type)
+	assert.Equal(t, []string{"items", "a"}, conflicts[0].Path[:2])
+}
+
+func TestConflictTypeString(t *testing.T) {
+	assert.Equal(t, "update/update", ConflictUpdateUpdate.String())
+	assert.Equal(t, "delete/update", ConflictDeleteUpdate.String())
+	assert.Equal(t, "update/delete", ConflictUpdateDelete.String())
+	assert.Equal(t, "unknown", ConflictType(0).String())
+}
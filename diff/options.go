@@ -91,3 +91,19 @@ func Filter(f FilterFunc) func(d *Differ) error {
 		return nil
 	}
 }
+
+// MergeResolver sets the resolver used for three-way merge conflicts that have no path specific resolver
+func MergeResolver(r ConflictResolver) func(d *Differ) error {
+	return func(d *Differ) error {
+		d.defaultResolver = r
+		return nil
+	}
+}
+
+// MergePathResolver registers a resolver for three-way merge conflicts at path. Paths may contain valid regexp to match items
+func MergePathResolver(path []string, r ConflictResolver) func(d *Differ) error {
+	return func(d *Differ) error {
+		d.pathResolvers = append(d.pathResolvers, pathResolver{path: path, resolver: r})
+		return nil
+	}
+}
@@ -0,0 +1,309 @@
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"github.com/tp-life/utils/color"
+)
+
+// ValueFormatter formats a changed value for display
+type ValueFormatter func(v interface{}) string
+
+// Messages holds the translatable texts of a Renderer. Create, Update and
+// Delete are text/template sources executed with a RenderedChange.
+type Messages struct {
+	Create    string
+	Update    string
+	Delete    string
+	Separator string
+
+	FieldHeader  string
+	ChangeHeader string
+	FromHeader   string
+	ToHeader     string
+
+	CreateName string
+	UpdateName string
+	DeleteName string
+}
+
+// DefaultMessages are the english texts used by a Renderer
+var DefaultMessages = Messages{
+	Create:       "{{.Label}} '{{.To}}' added",
+	Update:       "{{.Label}} changed from {{.From}} to {{.To}}",
+	Delete:       "{{.Label}} '{{.From}}' removed",
+	Separator:    "; ",
+	FieldHeader:  "Field",
+	ChangeHeader: "Change",
+	FromHeader:   "From",
+	ToHeader:     "To",
+	CreateName:   "added",
+	UpdateName:   "changed",
+	DeleteName:   "removed",
+}
+
+// RenderedChange is a change with its label and values formatted for display
+type RenderedChange struct {
+	Type  string
+	Path  []string
+	Label string
+	From  string
+	To    string
+}
+
+// Renderer turns a changelog into human readable summaries
+type Renderer struct {
+	TagName    string
+	LabelTag   string
+	Messages   Messages
+	labels     map[string]string
+	root       reflect.Type
+	formatters map[reflect.Type]ValueFormatter
+	templates  map[string]*template.Template
+}
+
+// NewRenderer creates a new configurable changelog renderer
+func NewRenderer(opts ...func(r *Renderer) error) (*Renderer, error) {
+	r := Renderer{
+		TagName:    "diff",
+		LabelTag:   "label",
+		Messages:   DefaultMessages,
+		labels:     make(map[string]string),
+		formatters: make(map[reflect.Type]ValueFormatter),
+	}
+
+	for _, opt := range opts {
+		if err := opt(&r); err != nil {
+			return nil, err
+		}
+	}
+
+	r.templates = make(map[string]*template.Template)
+	for t, src := range map[string]string{
+		CREATE: r.Messages.Create,
+		UPDATE: r.Messages.Update,
+		DELETE: r.Messages.Delete,
+	} {
+		tpl, err := template.New(t).Parse(src)
+		if err != nil {
+			return nil, err
+		}
+		r.templates[t] = tpl
+	}
+
+	return &r, nil
+}
+
+// RenderMessages sets the texts used by the renderer, e.g. a translation of DefaultMessages
+func RenderMessages(m Messages) func(r *Renderer) error {
+	return func(r *Renderer) error {
+		r.Messages = m
+		return nil
+	}
+}
+
+// RenderLabels maps dot joined change paths to labels, e.g. "price" to "Price"
+func RenderLabels(labels map[string]string) func(r *Renderer) error {
+	return func(r *Renderer) error {
+		for k, v := range labels {
+			r.labels[k] = v
+		}
+		return nil
+	}
+}
+
+// RenderLabelsFrom resolves labels from the label tags of the diffed type of v
+func RenderLabelsFrom(v interface{}) func(r *Renderer) error {
+	return func(r *Renderer) error {
+		r.root = reflect.TypeOf(v)
+		return nil
+	}
+}
+
+// RenderFormatter registers a formatter for values of the same type as sample
+func RenderFormatter(sample interface{}, f ValueFormatter) func(r *Renderer) error {
+	return func(r *Renderer) error {
+		r.formatters[reflect.TypeOf(sample)] = f
+		return nil
+	}
+}
+
+// Render formats every change of cl
+func (r *Renderer) Render(cl Changelog) []RenderedChange {
+	ret := make([]RenderedChange, 0, len(cl))
+	for _, c := range cl {
+		ret = append(ret, RenderedChange{
+			Type:  c.Type,
+			Path:  c.Path,
+			Label: r.label(c.Path),
+			From:  r.format(c.From),
+			To:    r.format(c.To),
+		})
+	}
+	return ret
+}
+
+// Text renders cl as sentences joined by the message separator
+func (r *Renderer) Text(cl Changelog) (string, error) {
+	lines, err := r.sentences(cl)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(lines, r.Messages.Separator), nil
+}
+
+// Terminal renders cl one sentence per line, colored by change type
+func (r *Renderer) Terminal(cl Changelog) (string, error) {
+	lines, err := r.sentences(cl)
+	if err != nil {
+		return "", err
+	}
+
+	var buf strings.Builder
+	for i, c := range cl {
+		switch c.Type {
+		case CREATE:
+			buf.WriteString(color.WithColor("+ "+lines[i], color.FgGreen))
+		case DELETE:
+			buf.WriteString(color.WithColor("- "+lines[i], color.FgRed))
+		default:
+			buf.WriteString(color.WithColor("~ "+lines[i], color.FgYellow))
+		}
+		buf.WriteByte('\n')
+	}
+	return buf.String(), nil
+}
+
+// Markdown renders cl as a markdown table
+func (r *Renderer) Markdown(cl Changelog) string {
+	var buf strings.Builder
+	m := r.Messages
+	fmt.Fprintf(&buf, "| %s | %s | %s | %s |\n", m.FieldHeader, m.ChangeHeader, m.FromHeader, m.ToHeader)
+	buf.WriteString("| --- | --- | --- | --- |\n")
+	for _, c := range r.Render(cl) {
+		fmt.Fprintf(&buf, "| %s | %s | %s | %s |\n", markdownCell(c.Label), markdownCell(r.typeName(c.Type)),
+			markdownCell(c.From), markdownCell(c.To))
+	}
+	return buf.String()
+}
+
+// HTML renders cl as an html table with all texts escaped
+func (r *Renderer) HTML(cl Changelog) string {
+	var buf strings.Builder
+	m := r.Messages
+	buf.WriteString("<table>\n<thead><tr>")
+	for _, h := range []string{m.FieldHeader, m.ChangeHeader, m.FromHeader, m.ToHeader} {
+		fmt.Fprintf(&buf, "<th>%s</th>", html.EscapeString(h))
+	}
+	buf.WriteString("</tr></thead>\n<tbody>\n")
+	for _, c := range r.Render(cl) {
+		fmt.Fprintf(&buf, "<tr class=\"%s\">", c.Type)
+		for _, v := range []string{c.Label, r.typeName(c.Type), c.From, c.To} {
+			fmt.Fprintf(&buf, "<td>%s</td>", html.EscapeString(v))
+		}
+		buf.WriteString("</tr>\n")
+	}
+	buf.WriteString("</tbody>\n</table>\n")
+	return buf.String()
+}
+
+func (r *Renderer) sentences(cl Changelog) ([]string, error) {
+	var buf bytes.Buffer
+	lines := make([]string, 0, len(cl))
+	for _, c := range r.Render(cl) {
+		tpl, ok := r.templates[c.Type]
+		if !ok {
+			return nil, ErrInvalidChangeType
+		}
+
+		buf.Reset()
+		if err := tpl.Execute(&buf, c); err != nil {
+			return nil, err
+		}
+		lines = append(lines, buf.String())
+	}
+	return lines, nil
+}
+
+func (r *Renderer) typeName(t string) string {
+	switch t {
+	case CREATE:
+		return r.Messages.CreateName
+	case DELETE:
+		return r.Messages.DeleteName
+	default:
+		return r.Messages.UpdateName
+	}
+}
+
+func (r *Renderer) format(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	if f, ok := r.formatters[reflect.TypeOf(v)]; ok {
+		return f(v)
+	}
+	return fmt.Sprint(v)
+}
+
+// label returns the explicit label of path, the label tag of the matching
+// struct field, or the dot joined path, in that order.
+func (r *Renderer) label(path []string) string {
+	key := strings.Join(path, ".")
+	if l, ok := r.labels[key]; ok {
+		return l
+	}
+	if l := r.tagLabel(path); l != "" {
+		return l
+	}
+	return key
+}
+
+func (r *Renderer) tagLabel(path []string) string {
+	var label string
+	t := r.root
+	for i := 0; t != nil && i < len(path); {
+		switch t.Kind() {
+		case reflect.Ptr:
+			t = t.Elem()
+		case reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+			i++
+		case reflect.Struct:
+			f, ok := r.field(t, path[i])
+			if !ok {
+				return label
+			}
+			label = f.Tag.Get(r.LabelTag)
+			t = f.Type
+			i++
+		default:
+			return label
+		}
+	}
+	return label
+}
+
+func (r *Renderer) field(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tname := tagName(r.TagName, f)
+		if tname == "" {
+			tname = f.Name
+		}
+		if tname == name {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
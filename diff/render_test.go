@@ -0,0 +1,111 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type renderItem struct {
+	Price float64  `diff:"price" label:"Price"`
+	Tags  []string `diff:"tags" label:"Tag"`
+	Owner *struct {
+		Name string `diff:"name" label:"Owner name"`
+	} `diff:"owner"`
+	Stock int `diff:"stock"`
+}
+
+func newTestRenderer(t *testing.T, opts ...func(r *Renderer) error) *Renderer {
+	opts = append([]func(r *Renderer) error{
+		RenderLabelsFrom(renderItem{}),
+		RenderFormatter(float64(0), func(v interface{}) string {
+			return fmt.Sprintf("%.2f", v)
+		}),
+	}, opts...)
+	r, err := NewRenderer(opts...)
+	require.NoError(t, err)
+	return r
+}
+
+func TestRendererText(t *testing.T) {
+	cl := Changelog{
+		{Type: UPDATE, Path: []string{"price"}, From: 10.0, To: 12.5},
+		{Type: CREATE, Path: []string{"tags", "1"}, To: "sale"},
+	}
+
+	out, err := newTestRenderer(t).Text(cl)
+	require.NoError(t, err)
+	assert.Equal(t, "Price changed from 10.00 to 12.50; Tag 'sale' added", out)
+}
+
+func TestRendererLabels(t *testing.T) {
+	r := newTestRenderer(t, RenderLabels(map[string]string{"stock": "Stock level"}))
+	rendered := r.Render(Changelog{
+		{Type: UPDATE, Path: []string{"owner", "name"}, From: "a", To: "b"},
+		{Type: UPDATE, Path: []string{"stock"}, From: 1, To: 2},
+		{Type: DELETE, Path: []string{"unknown", "path"}, From: 1},
+	})
+	require.Len(t, rendered, 3)
+	assert.Equal(t, "Owner name", rendered[0].Label)
+	assert.Equal(t, "Stock level", rendered[1].Label)
+	assert.Equal(t, "unknown.path", rendered[2].Label)
+	assert.Equal(t, "", rendered[2].To)
+}
+
+func TestRendererMessages(t *testing.T) {
+	r := newTestRenderer(t, RenderMessages(Messages{
+		Create:    "新增{{.Label}}：{{.To}}",
+		Update:    "{{.Label}}由{{.From}}改为{{.To}}",
+		Delete:    "删除{{.Label}}：{{.From}}",
+		Separator: "，",
+	}))
+
+	out, err := r.Text(Changelog{
+		{Type: UPDATE, Path: []string{"price"}, From: 10.0, To: 12.5},
+		{Type: DELETE, Path: []string{"tags", "0"}, From: "new"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Price由10.00改为12.50，删除Tag：new", out)
+
+	_, err = NewRenderer(RenderMessages(Messages{Create: "{{.Label"}))
+	assert.Error(t, err)
+
+	_, err = r.Text(Changelog{{Type: "move", Path: []string{"price"}}})
+	assert.Equal(t, ErrInvalidChangeType, err)
+}
+
+func TestRendererMarkdown(t *testing.T) {
+	out := newTestRenderer(t).Markdown(Changelog{
+		{Type: UPDATE, Path: []string{"price"}, From: 10.0, To: 12.5},
+		{Type: CREATE, Path: []string{"tags", "1"}, To: "a|b"},
+	})
+
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	require.Len(t, lines, 4)
+	assert.Equal(t, "| Field | Change | From | To |", lines[0])
+	assert.Equal(t, "| Price | changed | 10.00 | 12.50 |", lines[2])
+	assert.Equal(t, "| Tag | added |  | a\\|b |", lines[3])
+}
+
+func TestRendererHTML(t *testing.T) {
+	out := newTestRenderer(t).HTML(Changelog{
+		{Type: CREATE, Path: []string{"tags", "1"}, To: "<b>"},
+	})
+	assert.Contains(t, out, "<th>Field</th>")
+	assert.Contains(t, out, `<tr class="create"><td>Tag</td><td>added</td><td></td><td>&lt;b&gt;</td></tr>`)
+}
+
+func TestRendererTerminal(t *testing.T) {
+	out, err := newTestRenderer(t).Terminal(Changelog{
+		{Type: CREATE, Path: []string{"tags", "1"}, To: "sale"},
+		{Type: DELETE, Path: []string{"tags", "0"}, From: "new"},
+		{Type: UPDATE, Path: []string{"stock"}, From: 1, To: 2},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, out, "+ Tag 'sale' added")
+	assert.Contains(t, out, "- Tag 'new' removed")
+	assert.Contains(t, out, "~ stock changed from 1 to 2")
+}
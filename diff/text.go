@@ -0,0 +1,222 @@
+package diff
+
+import (
+	"strings"
+	"unicode"
+)
+
+// EditType represents an enum with all the supported text edit operations
+type EditType uint8
+
+const (
+	// EditEqual the line or word is unchanged
+	EditEqual EditType = iota
+	// EditInsert the line or word only exists in the new text
+	EditInsert
+	// EditDelete the line or word only exists in the old text
+	EditDelete
+)
+
+func (t EditType) String() string {
+	switch t {
+	case EditInsert:
+		return "insert"
+	case EditDelete:
+		return "delete"
+	default:
+		return "equal"
+	}
+}
+
+// Edit stores a single line or word of a text diff. OldPos and NewPos are the
+// zero based positions in the old and new sequences; for inserts OldPos is the
+// position the text is inserted at, for deletes NewPos likewise.
+type Edit struct {
+	Type   EditType
+	OldPos int
+	NewPos int
+	Text   string
+}
+
+// SplitLines splits s into lines, keeping the line terminators so that
+// joining the result gives back s
+func SplitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// DiffText returns the line edits turning a into b. Lines keep their
+// terminators, so a missing newline at the end of file shows up as a change.
+func DiffText(a, b string) []Edit {
+	return DiffLines(SplitLines(a), SplitLines(b))
+}
+
+// DiffLines returns the shortest edit script turning a into b, computed with
+// the Myers algorithm
+func DiffLines(a, b []string) []Edit {
+	return myers(a, b)
+}
+
+// DiffWords returns the word level edits turning a into b. Words, whitespace
+// runs and punctuation are compared as separate tokens.
+func DiffWords(a, b string) []Edit {
+	return myers(splitWords(a), splitWords(b))
+}
+
+// HighlightWords renders the word level changes between a and b. Removed
+// words of a are wrapped with del and inserted words of b with ins.
+func HighlightWords(a, b string, del, ins func(string) string) (string, string) {
+	var old, cur strings.Builder
+	edits := DiffWords(a, b)
+
+	for i := 0; i < len(edits); {
+		j := i
+		var run strings.Builder
+		for j < len(edits) && edits[j].Type == edits[i].Type {
+			run.WriteString(edits[j].Text)
+			j++
+		}
+
+		switch edits[i].Type {
+		case EditEqual:
+			old.WriteString(run.String())
+			cur.WriteString(run.String())
+		case EditDelete:
+			old.WriteString(del(run.String()))
+		case EditInsert:
+			cur.WriteString(ins(run.String()))
+		}
+		i = j
+	}
+
+	return old.String(), cur.String()
+}
+
+func splitWords(s string) []string {
+	var words []string
+	start := 0
+	class := -1
+	for i, r := range s {
+		c := runeClass(r)
+		if i > start && (c != class || c == 2) {
+			words = append(words, s[start:i])
+			start = i
+		}
+		class = c
+	}
+	if start < len(s) {
+		words = append(words, s[start:])
+	}
+	return words
+}
+
+// runeClass groups letters and digits (0), whitespace (1) and everything else
+// (2), the latter always being a token of its own
+func runeClass(r rune) int {
+	switch {
+	case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+		return 0
+	case unicode.IsSpace(r):
+		return 1
+	default:
+		return 2
+	}
+}
+
+// myers implements the greedy O(ND) algorithm of "An O(ND) Difference
+// Algorithm and Its Variations". Only the furthest reaching paths of every
+// round are kept, which is enough to backtrack the edit script.
+func myers(a, b []string) []Edit {
+	n, m := len(a), len(b)
+	max := n + m
+	if max == 0 {
+		return nil
+	}
+
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+
+	for d := 0; d <= max; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				trace = append(trace, snapshot(v, offset, d))
+				return backtrack(trace, a, b)
+			}
+		}
+		trace = append(trace, snapshot(v, offset, d))
+	}
+
+	return nil
+}
+
+// snapshot copies the furthest reaching x of the diagonals -d to d
+func snapshot(v []int, offset, d int) []int {
+	s := make([]int, 2*d+1)
+	copy(s, v[offset-d:offset+d+1])
+	return s
+}
+
+func backtrack(trace [][]int, a, b []string) []Edit {
+	x, y := len(a), len(b)
+	var edits []Edit
+
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d-1]
+		at := func(k int) int { return prev[k+d-1] }
+
+		k := x - y
+		var pk int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			pk = k + 1
+		} else {
+			pk = k - 1
+		}
+		px := at(pk)
+		py := px - pk
+
+		for x > px && y > py {
+			x--
+			y--
+			edits = append(edits, Edit{Type: EditEqual, OldPos: x, NewPos: y, Text: a[x]})
+		}
+
+		if x == px {
+			edits = append(edits, Edit{Type: EditInsert, OldPos: px, NewPos: py, Text: b[py]})
+		} else {
+			edits = append(edits, Edit{Type: EditDelete, OldPos: px, NewPos: py, Text: a[px]})
+		}
+		x, y = px, py
+	}
+
+	for x > 0 && y > 0 {
+		x--
+		y--
+		edits = append(edits, Edit{Type: EditEqual, OldPos: x, NewPos: y, Text: a[x]})
+	}
+
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
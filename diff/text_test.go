@@ -0,0 +1,157 @@
+package diff
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffLines(t *testing.T) {
+	cases := []struct {
+		Name string
+		A, B []string
+		Ops  string
+	}{
+		{"empty", nil, nil, ""},
+		{"insert-all", nil, []string{"a", "b"}, "++"},
+		{"delete-all", []string{"a", "b"}, nil, "--"},
+		{"equal", []string{"a", "b"}, []string{"a", "b"}, "=="},
+		{"replace", []string{"a", "b", "c"}, []string{"a", "x", "c"}, "=-+="},
+		{"classic", strings.Split("ABCABBA", ""), strings.Split("CBABAC", ""), "--=+==-=+"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			edits := DiffLines(tc.A, tc.B)
+
+			var ops strings.Builder
+			var old, cur []string
+			for _, e := range edits {
+				switch e.Type {
+				case EditEqual:
+					ops.WriteByte('=')
+					old = append(old, e.Text)
+					cur = append(cur, e.Text)
+					assert.Equal(t, tc.A[e.OldPos], e.Text)
+					assert.Equal(t, tc.B[e.NewPos], e.Text)
+				case EditDelete:
+					ops.WriteByte('-')
+					old = append(old, e.Text)
+					assert.Equal(t, tc.A[e.OldPos], e.Text)
+				case EditInsert:
+					ops.WriteByte('+')
+					cur = append(cur, e.Text)
+					assert.Equal(t, tc.B[e.NewPos], e.Text)
+				}
+			}
+
+			assert.Equal(t, len(tc.Ops), ops.Len())
+			assert.Equal(t, strings.Count(tc.Ops, "="), strings.Count(ops.String(), "="))
+			assert.Equal(t, strings.Join(tc.A, ""), strings.Join(old, ""))
+			assert.Equal(t, strings.Join(tc.B, ""), strings.Join(cur, ""))
+		})
+	}
+}
+
+func TestUnified(t *testing.T) {
+	a := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n"
+	b := "one\n2\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\neleven"
+
+	patch := Unified("a.txt", "b.txt", a, b, 2)
+	assert.Equal(t, `--- a.txt
++++ b.txt
+@@ -1,4 +1,4 @@
+ one
+-two
++2
+ three
+ four
+@@ -9,2 +9,3 @@
+ nine
+ ten
++eleven
+\ No newline at end of file
+`, patch)
+
+	out, err := ApplyUnified(a, patch)
+	require.NoError(t, err)
+	assert.Equal(t, b, out)
+
+	assert.Empty(t, Unified("a", "b", a, a, 3))
+}
+
+func TestUnifiedNoContext(t *testing.T) {
+	a := "a\nb\nc\n"
+	b := "a\nc\nd\n"
+
+	patch := Unified("a", "b", a, b, 0)
+	assert.Equal(t, "--- a\n+++ b\n@@ -2 +1,0 @@\n-b\n@@ -3,0 +3 @@\n+d\n", patch)
+
+	out, err := ApplyUnified(a, patch)
+	require.NoError(t, err)
+	assert.Equal(t, b, out)
+}
+
+func TestApplyUnifiedOffset(t *testing.T) {
+	a := "x\ny\nz\n"
+	b := "x\nY\nz\n"
+	patch := Unified("a", "b", a, b, 1)
+
+	out, err := ApplyUnified("header\n"+a, patch)
+	require.NoError(t, err)
+	assert.Equal(t, "header\n"+b, out)
+
+	_, err = ApplyUnified("x\nq\nz\n", patch)
+	assert.True(t, errors.Is(err, ErrPatchMismatch))
+}
+
+func TestParseUnifiedErrors(t *testing.T) {
+	_, err := ParseUnified("@@ -1,2 +1,2 @@\n a\n")
+	assert.True(t, errors.Is(err, ErrInvalidPatch))
+
+	_, err = ParseUnified("@@ -a +1 @@\n")
+	assert.True(t, errors.Is(err, ErrInvalidPatch))
+
+	_, err = ParseUnified("@@ -1 +1 @@\n*a\n")
+	assert.True(t, errors.Is(err, ErrInvalidPatch))
+
+	hunks, err := ParseUnified("diff --git a/x b/x\n--- a/x\n+++ b/x\n@@ -1 +1 @@\n-a\n+b\n")
+	require.NoError(t, err)
+	require.Len(t, hunks, 1)
+	assert.Equal(t, "@@ -1 +1 @@", hunks[0].Header())
+}
+
+func TestHighlightWords(t *testing.T) {
+	old, cur := HighlightWords("price = 10.00 EUR", "price = 10.50 USD",
+		func(s string) string { return "[-" + s + "-]" },
+		func(s string) string { return "{+" + s + "+}" })
+	assert.Equal(t, "price = 10.[-00-] [-EUR-]", old)
+	assert.Equal(t, "price = 10.{+50+} {+USD+}", cur)
+
+	assert.Equal(t, []string{"a", ",", " ", "bc_1", "  ", "(", ")"}, splitWords("a, bc_1  ()"))
+}
+
+func TestUnifiedRoundTrip(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	randomText := func() string {
+		var buf strings.Builder
+		for i := rnd.Intn(30); i > 0; i-- {
+			buf.WriteString(string(rune('a' + rnd.Intn(4))))
+			buf.WriteByte('\n')
+		}
+		return buf.String()
+	}
+
+	for i := 0; i < 200; i++ {
+		a, b := randomText(), randomText()
+		for _, context := range []int{0, 1, 3} {
+			out, err := ApplyUnified(a, Unified("a", "b", a, b, context))
+			require.NoError(t, err)
+			require.Equal(t, b, out)
+		}
+	}
+}
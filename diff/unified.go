@@ -0,0 +1,314 @@
+package diff
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const noNewline = "\\ No newline at end of file"
+
+var (
+	// ErrInvalidPatch The unified diff could not be parsed
+	ErrInvalidPatch = NewError("invalid unified diff")
+	// ErrPatchMismatch The unified diff does not apply to the given text
+	ErrPatchMismatch = NewError("unified diff does not match text")
+)
+
+// Hunk stores a group of nearby line edits with their surrounding context.
+// Starts are one based line numbers as written in a unified diff header.
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Edits    []Edit
+}
+
+// Header returns the "@@ -l,s +l,s @@" line of the hunk
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%s +%s @@", hunkRange(h.OldStart, h.OldLines), hunkRange(h.NewStart, h.NewLines))
+}
+
+// Hunks groups edits into hunks with up to context unchanged lines around
+// every change. Changes closer than twice the context share a hunk.
+func Hunks(edits []Edit, context int) []Hunk {
+	if context < 0 {
+		context = 0
+	}
+
+	var hunks []Hunk
+	for i := 0; i < len(edits); {
+		for i < len(edits) && edits[i].Type == EditEqual {
+			i++
+		}
+		if i == len(edits) {
+			break
+		}
+
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+
+		end := i
+		for end < len(edits) {
+			for end < len(edits) && edits[end].Type != EditEqual {
+				end++
+			}
+			next := end
+			for next < len(edits) && edits[next].Type == EditEqual {
+				next++
+			}
+			if next == len(edits) || next-end > 2*context {
+				break
+			}
+			end = next
+		}
+
+		stop := end + context
+		if stop > len(edits) {
+			stop = len(edits)
+		}
+
+		hunks = append(hunks, newHunk(edits[start:stop]))
+		i = stop
+	}
+
+	return hunks
+}
+
+func newHunk(edits []Edit) Hunk {
+	h := Hunk{
+		OldStart: edits[0].OldPos + 1,
+		NewStart: edits[0].NewPos + 1,
+		Edits:    edits,
+	}
+	for _, e := range edits {
+		switch e.Type {
+		case EditEqual:
+			h.OldLines++
+			h.NewLines++
+		case EditDelete:
+			h.OldLines++
+		case EditInsert:
+			h.NewLines++
+		}
+	}
+
+	// an empty range is addressed by the line preceding it
+	if h.OldLines == 0 {
+		h.OldStart--
+	}
+	if h.NewLines == 0 {
+		h.NewStart--
+	}
+
+	return h
+}
+
+// Unified returns the unified diff turning a into b, with context unchanged
+// lines around every change. An empty string is returned if a equals b.
+func Unified(oldName, newName, a, b string, context int) string {
+	hunks := Hunks(DiffText(a, b), context)
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", oldName, newName)
+	for _, h := range hunks {
+		buf.WriteString(h.Header())
+		buf.WriteByte('\n')
+		for _, e := range h.Edits {
+			switch e.Type {
+			case EditEqual:
+				buf.WriteByte(' ')
+			case EditDelete:
+				buf.WriteByte('-')
+			case EditInsert:
+				buf.WriteByte('+')
+			}
+			buf.WriteString(e.Text)
+			if !strings.HasSuffix(e.Text, "\n") {
+				buf.WriteString("\n" + noNewline + "\n")
+			}
+		}
+	}
+
+	return buf.String()
+}
+
+// ParseUnified parses the hunks of a unified diff. File headers and any text
+// before the first hunk are skipped.
+func ParseUnified(patch string) ([]Hunk, error) {
+	var hunks []Hunk
+	var h *Hunk
+	var oldSeen, newSeen int
+
+	for n, line := range SplitLines(patch) {
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			if h != nil && (oldSeen != h.OldLines || newSeen != h.NewLines) {
+				return nil, NewErrorf("hunk %q has wrong line counts", h.Header()).WithCause(ErrInvalidPatch)
+			}
+			parsed, err := parseHunkHeader(line)
+			if err != nil {
+				return nil, NewErrorf("line %d", n+1).WithCause(err)
+			}
+			hunks = append(hunks, parsed)
+			h = &hunks[len(hunks)-1]
+			oldSeen, newSeen = 0, 0
+		case h == nil:
+			continue
+		case strings.HasPrefix(line, "\\"):
+			if len(h.Edits) == 0 {
+				return nil, NewErrorf("line %d: misplaced end of file marker", n+1).WithCause(ErrInvalidPatch)
+			}
+			last := &h.Edits[len(h.Edits)-1]
+			last.Text = strings.TrimSuffix(last.Text, "\n")
+		case oldSeen == h.OldLines && newSeen == h.NewLines:
+			// trailing text after a complete hunk, e.g. the next file header
+			continue
+		default:
+			e := Edit{OldPos: h.OldStart - 1 + oldSeen, NewPos: h.NewStart - 1 + newSeen}
+			if line == "\n" {
+				// some tools strip the leading space of empty context lines
+				line = " \n"
+			}
+			e.Text = line[1:]
+			switch line[0] {
+			case ' ':
+				e.Type = EditEqual
+				oldSeen++
+				newSeen++
+			case '-':
+				e.Type = EditDelete
+				oldSeen++
+			case '+':
+				e.Type = EditInsert
+				newSeen++
+			default:
+				return nil, NewErrorf("line %d: unexpected %q", n+1, line).WithCause(ErrInvalidPatch)
+			}
+			h.Edits = append(h.Edits, e)
+		}
+	}
+
+	if h != nil && (oldSeen != h.OldLines || newSeen != h.NewLines) {
+		return nil, NewErrorf("hunk %q has wrong line counts", h.Header()).WithCause(ErrInvalidPatch)
+	}
+
+	return hunks, nil
+}
+
+// ApplyUnified applies a unified diff to src. Context and removed lines must
+// match src exactly, a hunk may however be found at an offset from the line
+// number in its header, as happens when earlier parts of src have changed.
+func ApplyUnified(src, patch string) (string, error) {
+	hunks, err := ParseUnified(patch)
+	if err != nil {
+		return "", err
+	}
+
+	lines := SplitLines(src)
+	var out []string
+	pos := 0
+	for _, h := range hunks {
+		var old, cur []string
+		for _, e := range h.Edits {
+			if e.Type != EditInsert {
+				old = append(old, e.Text)
+			}
+			if e.Type != EditDelete {
+				cur = append(cur, e.Text)
+			}
+		}
+
+		want := h.OldStart - 1
+		if h.OldLines == 0 {
+			want = h.OldStart
+		}
+		at := findLines(lines, old, pos, want)
+		if at < 0 {
+			return "", NewErrorf("hunk %q", h.Header()).WithCause(ErrPatchMismatch)
+		}
+
+		out = append(out, lines[pos:at]...)
+		out = append(out, cur...)
+		pos = at + len(old)
+	}
+	out = append(out, lines[pos:]...)
+
+	return strings.Join(out, ""), nil
+}
+
+// findLines looks for needle in lines at or after from, starting at want and
+// moving outwards. It returns -1 if there is no match.
+func findLines(lines, needle []string, from, want int) int {
+	if want < from {
+		want = from
+	}
+	for delta := 0; ; delta++ {
+		before, after := want-delta, want+delta
+		if before < from && after+len(needle) > len(lines) {
+			return -1
+		}
+		if after+len(needle) <= len(lines) && matchLines(lines[after:], needle) {
+			return after
+		}
+		if delta > 0 && before >= from && matchLines(lines[before:], needle) {
+			return before
+		}
+	}
+}
+
+func matchLines(lines, needle []string) bool {
+	for i := range needle {
+		if lines[i] != needle[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return strconv.Itoa(start)
+	}
+	return strconv.Itoa(start) + "," + strconv.Itoa(count)
+}
+
+func parseHunkHeader(line string) (Hunk, error) {
+	var h Hunk
+	fields := strings.Fields(line)
+	if len(fields) < 4 || fields[0] != "@@" || fields[3] != "@@" ||
+		!strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+		return h, NewErrorf("bad hunk header %q", strings.TrimSpace(line)).WithCause(ErrInvalidPatch)
+	}
+
+	var err error
+	if h.OldStart, h.OldLines, err = parseHunkRange(fields[1][1:]); err != nil {
+		return h, err
+	}
+	if h.NewStart, h.NewLines, err = parseHunkRange(fields[2][1:]); err != nil {
+		return h, err
+	}
+	return h, nil
+}
+
+func parseHunkRange(s string) (int, int, error) {
+	start, count, found := strings.Cut(s, ",")
+	l, err := strconv.Atoi(start)
+	if err != nil {
+		return 0, 0, NewErrorf("bad hunk range %q", s).WithCause(ErrInvalidPatch)
+	}
+	if !found {
+		return l, 1, nil
+	}
+	c, err := strconv.Atoi(count)
+	if err != nil {
+		return 0, 0, NewErrorf("bad hunk range %q", s).WithCause(ErrInvalidPatch)
+	}
+	return l, c, nil
+}
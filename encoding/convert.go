@@ -0,0 +1,688 @@
+package encoding
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/tp-life/utils/lang"
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/vmihailenco/msgpack/v5/msgpcode"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// FormatJson is the JSON format.
+	FormatJson Format = "json"
+	// FormatYaml is the YAML format.
+	FormatYaml Format = "yaml"
+	// FormatToml is the TOML format, keys are sorted since the TOML library doesn't keep the order.
+	FormatToml Format = "toml"
+	// FormatMsgpack is the msgpack format.
+	FormatMsgpack Format = "msgpack"
+	// FormatProperties is the Java-style properties format as parsed by conf.LoadProperties,
+	// nested keys are joined with dots and all values are strings.
+	FormatProperties Format = "properties"
+
+	propertiesKeySep = "."
+)
+
+var (
+	// ErrUnknownFormat indicates an unsupported format.
+	ErrUnknownFormat = errors.New("unknown format")
+	// ErrUnsupportedRoot indicates that the target format requires a map at the root.
+	ErrUnsupportedRoot = errors.New("format requires a map at the root")
+
+	formatAliases = map[string]Format{
+		"json":       FormatJson,
+		"yaml":       FormatYaml,
+		"yml":        FormatYaml,
+		"toml":       FormatToml,
+		"msgpack":    FormatMsgpack,
+		"mp":         FormatMsgpack,
+		"properties": FormatProperties,
+		"props":      FormatProperties,
+	}
+)
+
+type (
+	// Format is a data format that Convert supports.
+	Format string
+
+	// orderedMap is a map that keeps the insertion order of keys.
+	orderedMap struct {
+		keys   []string
+		values map[string]any
+	}
+)
+
+// ParseFormat returns the Format of the given name, file extension or file name,
+// like "yml", ".toml" or "config.json".
+func ParseFormat(name string) (Format, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if ext := filepath.Ext(name); len(ext) > 0 {
+		name = ext[1:]
+	}
+
+	if format, ok := formatAliases[name]; ok {
+		return format, nil
+	}
+
+	return "", fmt.Errorf("%w: %q", ErrUnknownFormat, name)
+}
+
+// Convert converts data from one format to another. Numbers are kept as
+// json.Number to preserve precision, and key order is kept if both formats allow.
+func Convert(data []byte, from, to Format) ([]byte, error) {
+	val, err := decode(data, from)
+	if err != nil {
+		return nil, err
+	}
+
+	return encode(val, to)
+}
+
+func decode(data []byte, format Format) (any, error) {
+	switch format {
+	case FormatJson:
+		return decodeJson(data)
+	case FormatYaml:
+		return decodeYaml(data)
+	case FormatToml:
+		return decodeToml(data)
+	case FormatMsgpack:
+		return decodeMsgpack(data)
+	case FormatProperties:
+		return decodeProperties(data)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+}
+
+func encode(val any, format Format) ([]byte, error) {
+	switch format {
+	case FormatJson:
+		return encodeJson(val)
+	case FormatYaml:
+		return yaml.Marshal(toYamlValue(val))
+	case FormatToml:
+		return encodeToml(val)
+	case FormatMsgpack:
+		return encodeMsgpack(val)
+	case FormatProperties:
+		return encodeProperties(val)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+}
+
+func newOrderedMap() *orderedMap {
+	return &orderedMap{
+		values: make(map[string]any),
+	}
+}
+
+func (m *orderedMap) get(key string) (any, bool) {
+	val, ok := m.values[key]
+	return val, ok
+}
+
+func (m *orderedMap) set(key string, val any) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = val
+}
+
+func decodeJson(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	val, err := decodeJsonValue(decoder)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = decoder.Token(); err != io.EOF {
+		return nil, errors.New("invalid json: unexpected data after top-level value")
+	}
+
+	return val, nil
+}
+
+func decodeJsonValue(decoder *json.Decoder) (any, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		m := newOrderedMap()
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			val, err := decodeJsonValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			m.set(key.(string), val)
+		}
+		_, err = decoder.Token()
+		return m, err
+	case json.Delim('['):
+		list := make([]any, 0)
+		for decoder.More() {
+			val, err := decodeJsonValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, val)
+		}
+		_, err = decoder.Token()
+		return list, err
+	default:
+		return token, nil
+	}
+}
+
+func decodeYaml(data []byte) (any, error) {
+	var val any
+	if err := yaml.Unmarshal(data, &val); err != nil {
+		return nil, err
+	}
+
+	// yaml.MapSlice keeps the key order, but only works with a map at the root,
+	// other roots like lists of maps are decoded into it without errors, but lose the data.
+	if _, ok := val.(map[any]any); ok {
+		var ordered yaml.MapSlice
+		if err := yaml.Unmarshal(data, &ordered); err != nil {
+			return nil, err
+		}
+
+		return fromYamlValue(ordered), nil
+	}
+
+	return fromYamlValue(val), nil
+}
+
+func fromYamlValue(val any) any {
+	switch v := val.(type) {
+	case yaml.MapSlice:
+		m := newOrderedMap()
+		for _, item := range v {
+			m.set(lang.Repr(item.Key), fromYamlValue(item.Value))
+		}
+		return m
+	case map[any]any:
+		m := newOrderedMap()
+		for _, key := range sortedKeys(v) {
+			m.set(lang.Repr(key), fromYamlValue(v[key]))
+		}
+		return m
+	case []any:
+		list := make([]any, len(v))
+		for i, item := range v {
+			list[i] = fromYamlValue(item)
+		}
+		return list
+	default:
+		return fromScalar(v)
+	}
+}
+
+func decodeToml(data []byte) (any, error) {
+	var val map[string]any
+	if err := toml.NewDecoder(bytes.NewReader(data)).Decode(&val); err != nil {
+		return nil, err
+	}
+
+	return fromTomlValue(val), nil
+}
+
+func fromTomlValue(val any) any {
+	switch v := val.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		m := newOrderedMap()
+		for _, key := range keys {
+			m.set(key, fromTomlValue(v[key]))
+		}
+		return m
+	case []any:
+		list := make([]any, len(v))
+		for i, item := range v {
+			list[i] = fromTomlValue(item)
+		}
+		return list
+	default:
+		return fromScalar(v)
+	}
+}
+
+func decodeMsgpack(data []byte) (any, error) {
+	reader := bytes.NewReader(data)
+	decoder := msgpack.NewDecoder(reader)
+	val, err := decodeMsgpackValue(decoder)
+	if err != nil {
+		return nil, err
+	}
+	if reader.Len() > 0 {
+		return nil, errors.New("invalid msgpack: unexpected data after top-level value")
+	}
+
+	return val, nil
+}
+
+func decodeMsgpackValue(decoder *msgpack.Decoder) (any, error) {
+	code, err := decoder.PeekCode()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case msgpcode.IsFixedMap(code) || code == msgpcode.Map16 || code == msgpcode.Map32:
+		n, err := decoder.DecodeMapLen()
+		if err != nil {
+			return nil, err
+		}
+
+		m := newOrderedMap()
+		for i := 0; i < n; i++ {
+			key, err := decoder.DecodeInterface()
+			if err != nil {
+				return nil, err
+			}
+			val, err := decodeMsgpackValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			m.set(lang.Repr(fromScalar(key)), val)
+		}
+		return m, nil
+	case msgpcode.IsFixedArray(code) || code == msgpcode.Array16 || code == msgpcode.Array32:
+		n, err := decoder.DecodeArrayLen()
+		if err != nil {
+			return nil, err
+		}
+
+		list := make([]any, 0, n)
+		for i := 0; i < n; i++ {
+			val, err := decodeMsgpackValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, val)
+		}
+		return list, nil
+	default:
+		val, err := decoder.DecodeInterface()
+		if err != nil {
+			return nil, err
+		}
+		return fromScalar(val), nil
+	}
+}
+
+// decodeProperties parses key=value lines like conf.LoadProperties, and
+// builds nested maps from the dotted keys. Maps with keys 0..n-1 become arrays.
+func decodeProperties(data []byte) (any, error) {
+	root := newOrderedMap()
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if len(text) == 0 || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		key, val, ok := strings.Cut(text, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid property format at line %d: %s", line, text)
+		}
+		if err := setProperty(root, strings.TrimSpace(key), strings.TrimSpace(val)); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return propertiesToArrays(root), nil
+}
+
+func setProperty(root *orderedMap, key, val string) error {
+	parts := strings.Split(key, propertiesKeySep)
+	m := root
+	for i, part := range parts[:len(parts)-1] {
+		child, ok := m.get(part)
+		if !ok {
+			next := newOrderedMap()
+			m.set(part, next)
+			m = next
+			continue
+		}
+
+		next, ok := child.(*orderedMap)
+		if !ok {
+			return fmt.Errorf("property %q conflicts with %q", key, strings.Join(parts[:i+1], propertiesKeySep))
+		}
+		m = next
+	}
+
+	last := parts[len(parts)-1]
+	if child, ok := m.get(last); ok {
+		if _, ok := child.(*orderedMap); ok {
+			return fmt.Errorf("property %q conflicts with its nested properties", key)
+		}
+	}
+	m.set(last, val)
+
+	return nil
+}
+
+func propertiesToArrays(val any) any {
+	m, ok := val.(*orderedMap)
+	if !ok {
+		return val
+	}
+
+	for _, key := range m.keys {
+		m.values[key] = propertiesToArrays(m.values[key])
+	}
+
+	list := make([]any, len(m.keys))
+	for _, key := range m.keys {
+		index, err := strconv.Atoi(key)
+		if err != nil || index < 0 || index >= len(list) || list[index] != nil ||
+			strconv.Itoa(index) != key {
+			return m
+		}
+		list[index] = m.values[key]
+	}
+	if len(list) == 0 {
+		return m
+	}
+
+	return list
+}
+
+// fromScalar converts numbers into json.Number and other values into
+// JSON compatible scalars.
+func fromScalar(val any) any {
+	switch v := val.(type) {
+	case nil, bool, string, json.Number:
+		return v
+	case float32:
+		return floatNumber(float64(v))
+	case float64:
+		return floatNumber(v)
+	case int, uint, int8, uint8, int16, uint16, int32, uint32, int64, uint64:
+		return json.Number(lang.Repr(v))
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return lang.Repr(v)
+	}
+}
+
+func floatNumber(f float64) any {
+	// NaN and Inf are not valid JSON numbers
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return lang.Repr(f)
+	}
+
+	return json.Number(strconv.FormatFloat(f, 'g', -1, 64))
+}
+
+// numberValue converts n into int64, uint64 or float64, whichever keeps the value.
+func numberValue(n json.Number) any {
+	if i, err := strconv.ParseInt(n.String(), 10, 64); err == nil {
+		return i
+	}
+	if u, err := strconv.ParseUint(n.String(), 10, 64); err == nil {
+		return u
+	}
+	if f, err := n.Float64(); err == nil {
+		return f
+	}
+
+	return n.String()
+}
+
+func encodeJson(val any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeJson(&buf, val); err != nil {
+		return nil, err
+	}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, buf.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+	indented.WriteByte('\n')
+
+	return indented.Bytes(), nil
+}
+
+func writeJson(buf *bytes.Buffer, val any) error {
+	switch v := val.(type) {
+	case *orderedMap:
+		buf.WriteByte('{')
+		for i, key := range v.keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJsonString(buf, key); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := writeJson(buf, v.values[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case []any:
+		buf.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJson(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case json.Number:
+		buf.WriteString(v.String())
+	case string:
+		return writeJsonString(buf, v)
+	default:
+		content, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(content)
+	}
+
+	return nil
+}
+
+func writeJsonString(buf *bytes.Buffer, s string) error {
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(s); err != nil {
+		return err
+	}
+	// Encode appends a newline
+	buf.Truncate(buf.Len() - 1)
+
+	return nil
+}
+
+func toYamlValue(val any) any {
+	switch v := val.(type) {
+	case *orderedMap:
+		slice := make(yaml.MapSlice, 0, len(v.keys))
+		for _, key := range v.keys {
+			slice = append(slice, yaml.MapItem{Key: key, Value: toYamlValue(v.values[key])})
+		}
+		return slice
+	case []any:
+		list := make([]any, len(v))
+		for i, item := range v {
+			list[i] = toYamlValue(item)
+		}
+		return list
+	case json.Number:
+		return numberValue(v)
+	default:
+		return v
+	}
+}
+
+func encodeToml(val any) ([]byte, error) {
+	if _, ok := val.(*orderedMap); !ok {
+		return nil, fmt.Errorf("toml: %w", ErrUnsupportedRoot)
+	}
+
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(toTomlValue(val)); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func toTomlValue(val any) any {
+	switch v := val.(type) {
+	case *orderedMap:
+		m := make(map[string]any, len(v.keys))
+		for _, key := range v.keys {
+			// TOML has no null
+			if item := v.values[key]; item != nil {
+				m[key] = toTomlValue(item)
+			}
+		}
+		return m
+	case []any:
+		list := make([]any, 0, len(v))
+		for _, item := range v {
+			if item != nil {
+				list = append(list, toTomlValue(item))
+			}
+		}
+		return list
+	case json.Number:
+		return numberValue(v)
+	default:
+		return v
+	}
+}
+
+func encodeMsgpack(val any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeMsgpack(msgpack.NewEncoder(&buf), val); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeMsgpack(encoder *msgpack.Encoder, val any) error {
+	switch v := val.(type) {
+	case *orderedMap:
+		if err := encoder.EncodeMapLen(len(v.keys)); err != nil {
+			return err
+		}
+		for _, key := range v.keys {
+			if err := encoder.EncodeString(key); err != nil {
+				return err
+			}
+			if err := writeMsgpack(encoder, v.values[key]); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []any:
+		if err := encoder.EncodeArrayLen(len(v)); err != nil {
+			return err
+		}
+		for _, item := range v {
+			if err := writeMsgpack(encoder, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case json.Number:
+		return encoder.Encode(numberValue(v))
+	default:
+		return encoder.Encode(v)
+	}
+}
+
+func encodeProperties(val any) ([]byte, error) {
+	if _, ok := val.(*orderedMap); !ok {
+		return nil, fmt.Errorf("properties: %w", ErrUnsupportedRoot)
+	}
+
+	var buf bytes.Buffer
+	writeProperties(&buf, "", val)
+	return buf.Bytes(), nil
+}
+
+func writeProperties(buf *bytes.Buffer, prefix string, val any) {
+	join := func(key string) string {
+		if len(prefix) == 0 {
+			return key
+		}
+		return prefix + propertiesKeySep + key
+	}
+
+	switch v := val.(type) {
+	case *orderedMap:
+		for _, key := range v.keys {
+			writeProperties(buf, join(key), v.values[key])
+		}
+	case []any:
+		for i, item := range v {
+			writeProperties(buf, join(strconv.Itoa(i)), item)
+		}
+	default:
+		buf.WriteString(prefix)
+		buf.WriteByte('=')
+		buf.WriteString(lang.Repr(v))
+		buf.WriteByte('\n')
+	}
+}
+
+// sortedKeys returns the keys of m sorted by their string representations.
+func sortedKeys(m map[any]any) []any {
+	keys := make([]any, 0, len(m))
+	reprs := make(map[any]string, len(m))
+	for key := range m {
+		keys = append(keys, key)
+		reprs[key] = lang.Repr(key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return reprs[keys[i]] < reprs[keys[j]]
+	})
+
+	return keys
+}
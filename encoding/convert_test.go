@@ -0,0 +1,141 @@
+package encoding
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		expect Format
+	}{
+		{name: "json", expect: FormatJson},
+		{name: "YML", expect: FormatYaml},
+		{name: ".toml", expect: FormatToml},
+		{name: "data.mp", expect: FormatMsgpack},
+		{name: "conf/app.properties", expect: FormatProperties},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			format, err := ParseFormat(test.name)
+			assert.Nil(t, err)
+			assert.Equal(t, test.expect, format)
+		})
+	}
+
+	_, err := ParseFormat("xml")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		from   Format
+		to     Format
+		expect string
+	}{
+		{
+			name:   "json to yaml keeps order",
+			input:  `{"b":1,"a":{"y":"foo","x":[1,2.5]}}`,
+			from:   FormatJson,
+			to:     FormatYaml,
+			expect: "b: 1\na:\n  \"y\": foo\n  x:\n  - 1\n  - 2.5\n",
+		},
+		{
+			name:   "yaml to json keeps order",
+			input:  "b: 1\na:\n  \"y\": foo\n  x: [1, 2.5]\n",
+			from:   FormatYaml,
+			to:     FormatJson,
+			expect: "{\n  \"b\": 1,\n  \"a\": {\n    \"y\": \"foo\",\n    \"x\": [\n      1,\n      2.5\n    ]\n  }\n}\n",
+		},
+		{
+			name:   "yaml list of maps to json",
+			input:  "- x: 1\n  \"y\": 2\n- x: 3\n",
+			from:   FormatYaml,
+			to:     FormatJson,
+			expect: "[\n  {\n    \"x\": 1,\n    \"y\": 2\n  },\n  {\n    \"x\": 3\n  }\n]\n",
+		},
+		{
+			name:   "yaml non-string keys to json",
+			input:  "- 1: a\n  true: b\n",
+			from:   FormatYaml,
+			to:     FormatJson,
+			expect: "[\n  {\n    \"1\": \"a\",\n    \"true\": \"b\"\n  }\n]\n",
+		},
+		{
+			name:   "json keeps big numbers",
+			input:  `{"id":12345678901234567890,"ratio":0.1000000000000000055511151231257827}`,
+			from:   FormatJson,
+			to:     FormatJson,
+			expect: "{\n  \"id\": 12345678901234567890,\n  \"ratio\": 0.1000000000000000055511151231257827\n}\n",
+		},
+		{
+			name:   "toml to properties",
+			input:  "name = \"app\"\n[server]\nport = 8080\nhosts = [\"a\", \"b\"]\n",
+			from:   FormatToml,
+			to:     FormatProperties,
+			expect: "name=app\nserver.hosts.0=a\nserver.hosts.1=b\nserver.port=8080\n",
+		},
+		{
+			name:   "properties to json",
+			input:  "# comment\nname = app\n\nserver.port = 8080\nserver.hosts.0 = a\nserver.hosts.1 = b\n",
+			from:   FormatProperties,
+			to:     FormatJson,
+			expect: "{\n  \"name\": \"app\",\n  \"server\": {\n    \"port\": \"8080\",\n    \"hosts\": [\n      \"a\",\n      \"b\"\n    ]\n  }\n}\n",
+		},
+		{
+			name:   "json to toml",
+			input:  `{"name":"app","server":{"port":8080,"debug":true,"ignored":null}}`,
+			from:   FormatJson,
+			to:     FormatToml,
+			expect: "name = 'app'\n\n[server]\ndebug = true\nport = 8080\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := Convert([]byte(test.input), test.from, test.to)
+			assert.Nil(t, err)
+			assert.Equal(t, test.expect, string(actual))
+		})
+	}
+}
+
+func TestConvertRoundTrip(t *testing.T) {
+	const input = `{"z":"last","a":[1,-2,3.25,"x",true,null],"m":{"k":"v","big":18446744073709551615}}`
+	formats := []Format{FormatYaml, FormatMsgpack, FormatJson}
+
+	for _, format := range formats {
+		format := format
+		t.Run(string(format), func(t *testing.T) {
+			data, err := Convert([]byte(input), FormatJson, format)
+			assert.Nil(t, err)
+			back, err := Convert(data, format, FormatJson)
+			assert.Nil(t, err)
+			expect, err := Convert([]byte(input), FormatJson, FormatJson)
+			assert.Nil(t, err)
+			assert.Equal(t, string(expect), string(back))
+		})
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	_, err := Convert([]byte(`[1,2]`), FormatJson, FormatToml)
+	assert.ErrorIs(t, err, ErrUnsupportedRoot)
+	_, err = Convert([]byte(`"foo"`), FormatJson, FormatProperties)
+	assert.ErrorIs(t, err, ErrUnsupportedRoot)
+	_, err = Convert([]byte(`{}`), FormatJson, Format("xml"))
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+	_, err = Convert([]byte(`{} {}`), FormatJson, FormatYaml)
+	assert.NotNil(t, err)
+	_, err = Convert([]byte("a\n"), FormatProperties, FormatJson)
+	assert.NotNil(t, err)
+	_, err = Convert([]byte("a=1\na.b=2\n"), FormatProperties, FormatJson)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,201 @@
+package httpx
+
+import (
+	"errors"
+	"net/http"
+	"net/textproto"
+	"strings"
+
+	"github.com/tp-life/utils/mapping"
+	"github.com/tp-life/utils/validation"
+)
+
+const (
+	formKey   = "form"
+	headerKey = "header"
+	pathKey   = "path"
+
+	contentType     = "Content-Type"
+	acceptLanguage  = "Accept-Language"
+	applicationJson = "application/json"
+
+	maxMemory  = 32 << 20 // 32MB
+	maxBodyLen = 8 << 20  // 8MB
+)
+
+type pathValuer struct {
+	r    *http.Request
+	vars map[string]string
+}
+
+// Bind fills v from r, v must be a pointer to struct:
+//
+//	type Request struct {
+//		ID      int64    `path:"id"`
+//		Page    int      `form:"page,default=1,range=[1:100]"`
+//		Tags    []string `form:"tags,optional"`
+//		TraceID string   `header:"X-Trace-Id,optional"`
+//		Name    string   `json:"name" validate:"required,max=32"`
+//	}
+//
+// The fields tagged with path are filled from the path vars set by WithPathVars,
+// or the wildcards of the http.ServeMux patterns. The fields tagged with form are filled
+// from the query and the form, the fields tagged with header are filled from the headers,
+// and the fields tagged with json are filled from the json body.
+// The options in the tags, like default, range and optional, work like mapping.Unmarshaler,
+// and opts are used to customize the unmarshalers, like mapping.WithTypeDecoder.
+// After filled, v is validated by validation.StructCtx with the locale from the Accept-Language header,
+// and by its own Validate or ValidateCtx method if implemented.
+func Bind(r *http.Request, v any, opts ...mapping.UnmarshalOption) error {
+	if err := BindPath(r, v, opts...); err != nil {
+		return err
+	}
+
+	if err := BindForm(r, v, opts...); err != nil {
+		return err
+	}
+
+	if err := BindHeaders(r, v, opts...); err != nil {
+		return err
+	}
+
+	if err := BindJsonBody(r, v, opts...); err != nil {
+		return err
+	}
+
+	return validate(r, v)
+}
+
+// BindForm fills the fields tagged with form in v from the query and the form of r.
+func BindForm(r *http.Request, v any, opts ...mapping.UnmarshalOption) error {
+	params, err := GetFormValues(r)
+	if err != nil {
+		return err
+	}
+
+	return newUnmarshaler(formKey, opts, mapping.WithStringValues(), mapping.WithOpaqueKeys()).
+		Unmarshal(params, v)
+}
+
+// BindHeaders fills the fields tagged with header in v from the headers of r,
+// the header keys are case-insensitive.
+func BindHeaders(r *http.Request, v any, opts ...mapping.UnmarshalOption) error {
+	m := make(map[string]any, len(r.Header))
+	for k, values := range r.Header {
+		if len(values) == 1 {
+			m[k] = values[0]
+		} else {
+			m[k] = values
+		}
+	}
+
+	return newUnmarshaler(headerKey, opts, mapping.WithStringValues(),
+		mapping.WithCanonicalKeyFunc(textproto.CanonicalMIMEHeaderKey)).Unmarshal(m, v)
+}
+
+// BindJsonBody fills the fields tagged with json in v from the json body of r,
+// the defaults are filled and the required fields are checked even if there is no json body.
+// The bodies larger than 8MB are rejected with http.MaxBytesError.
+func BindJsonBody(r *http.Request, v any, opts ...mapping.UnmarshalOption) error {
+	if withJsonBody(r) {
+		reader := http.MaxBytesReader(nil, r.Body, maxBodyLen)
+		return mapping.UnmarshalJsonReader(reader, v, opts...)
+	}
+
+	return mapping.UnmarshalJsonMap(nil, v, opts...)
+}
+
+// BindPath fills the fields tagged with path in v from the path vars of r.
+func BindPath(r *http.Request, v any, opts ...mapping.UnmarshalOption) error {
+	return newUnmarshaler(pathKey, opts, mapping.WithStringValues(), mapping.WithOpaqueKeys()).
+		UnmarshalValuer(pathValuer{
+			r:    r,
+			vars: PathVars(r),
+		}, v)
+}
+
+// GetFormValues returns the query and form values of r, the empty values are ignored.
+func GetFormValues(r *http.Request) (map[string]any, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+
+	if err := r.ParseMultipartForm(maxMemory); err != nil {
+		if !errors.Is(err, http.ErrNotMultipart) {
+			return nil, err
+		}
+	}
+
+	params := make(map[string]any, len(r.Form))
+	for name, values := range r.Form {
+		filtered := make([]string, 0, len(values))
+		for _, val := range values {
+			if len(val) > 0 {
+				filtered = append(filtered, val)
+			}
+		}
+
+		switch len(filtered) {
+		case 0:
+		case 1:
+			params[name] = filtered[0]
+		default:
+			params[name] = filtered
+		}
+	}
+
+	return params, nil
+}
+
+// Value returns the path var of key, the wildcards of the http.ServeMux patterns are used as fallback.
+func (pv pathValuer) Value(key string) (any, bool) {
+	if val, ok := pv.vars[key]; ok {
+		return val, true
+	}
+
+	if val := pv.r.PathValue(key); len(val) > 0 {
+		return val, true
+	}
+
+	return nil, false
+}
+
+func newUnmarshaler(key string, opts []mapping.UnmarshalOption,
+	defaults ...mapping.UnmarshalOption) *mapping.Unmarshaler {
+	return mapping.NewUnmarshaler(key, append(defaults, opts...)...)
+}
+
+// parseLocale returns the first language in the Accept-Language header, like zh-CN in zh-CN,zh;q=0.9.
+func parseLocale(header string) string {
+	locale, _, _ := strings.Cut(header, ",")
+	locale, _, _ = strings.Cut(locale, ";")
+	return strings.TrimSpace(locale)
+}
+
+func validate(r *http.Request, v any) error {
+	ctx := r.Context()
+	if len(validation.LocaleFromContext(ctx)) == 0 {
+		if locale := parseLocale(r.Header.Get(acceptLanguage)); len(locale) > 0 {
+			ctx = validation.ContextWithLocale(ctx, locale)
+		}
+	}
+
+	if err := validation.StructCtx(ctx, v); err != nil {
+		return err
+	}
+
+	switch val := v.(type) {
+	case validation.ContextValidator:
+		return val.ValidateCtx(ctx)
+	case validation.Validator:
+		return val.Validate()
+	default:
+		return nil
+	}
+}
+
+// withJsonBody checks the body instead of ContentLength, which is -1 for the chunked bodies.
+func withJsonBody(r *http.Request) bool {
+	return r.Body != nil && r.Body != http.NoBody && r.ContentLength != 0 &&
+		strings.Contains(r.Header.Get(contentType), applicationJson)
+}
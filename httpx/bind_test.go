@@ -0,0 +1,157 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tp-life/utils/search"
+	"github.com/tp-life/utils/validation"
+)
+
+type (
+	bindRequest struct {
+		ID      int64    `path:"id"`
+		Page    int      `form:"page,default=1,range=[1:100]"`
+		Tags    []string `form:"tags,optional"`
+		TraceID string   `header:"X-Trace-Id,optional"`
+		Name    string   `json:"name" validate:"required,max=8"`
+		Age     int      `json:"age,optional" validate:"gte=0"`
+	}
+
+	ctxRequest struct {
+		Name string `json:"name"`
+	}
+
+	ctxKey struct{}
+)
+
+func (r ctxRequest) ValidateCtx(ctx context.Context) error {
+	if r.Name == ctx.Value(ctxKey{}) {
+		return errors.New("name is taken")
+	}
+
+	return nil
+}
+
+func TestBind(t *testing.T) {
+	tree := search.NewTree()
+	assert.NoError(t, tree.Add("/users/:id", "user"))
+	result, ok := tree.Search("/users/123")
+	assert.True(t, ok)
+
+	r := httptest.NewRequest(http.MethodPost, "/users/123?page=2&tags=a",
+		strings.NewReader(`{"name": "kevin"}`))
+	r.Header.Set(contentType, "application/json; charset=utf-8")
+	r.Header.Set("x-trace-id", "trace")
+	r = WithPathVars(r, result.Params)
+
+	var req bindRequest
+	assert.NoError(t, Bind(r, &req))
+	assert.Equal(t, bindRequest{
+		ID:      123,
+		Page:    2,
+		Tags:    []string{"a"},
+		TraceID: "trace",
+		Name:    "kevin",
+	}, req)
+}
+
+func TestBindServeMux(t *testing.T) {
+	var req bindRequest
+	var err error
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		err = Bind(r, &req)
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/users/1?tags=a&tags=b", nil)
+	mux.ServeHTTP(httptest.NewRecorder(), r)
+	// name is required by the json tag
+	assert.EqualError(t, err, `field "name" is not set`)
+	assert.Equal(t, int64(1), req.ID)
+	assert.Equal(t, 1, req.Page)
+	assert.Equal(t, []string{"a", "b"}, req.Tags)
+}
+
+func TestBindForm(t *testing.T) {
+	form := url.Values{
+		"name": {"kevin"},
+		"page": {"200"},
+	}
+	r := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(form.Encode()))
+	r.Header.Set(contentType, "application/x-www-form-urlencoded")
+
+	var req struct {
+		Name string `form:"name"`
+		Page int    `form:"page,range=[1:100]"`
+	}
+	assert.Error(t, Bind(r, &req))
+	assert.Equal(t, "kevin", req.Name)
+
+	params, err := GetFormValues(httptest.NewRequest(http.MethodGet, "/?a=1&b=&c=1&c=2", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"a": "1",
+		"c": []string{"1", "2"},
+	}, params)
+}
+
+func TestBindErrors(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/users/abc", strings.NewReader(`{"name": "kevin"}`))
+	r.Header.Set(contentType, applicationJson)
+	var req bindRequest
+	assert.Error(t, Bind(WithPathVars(r, map[string]string{"id": "abc"}), &req))
+
+	r = httptest.NewRequest(http.MethodPost, "/users/1", strings.NewReader(`{"name": "kevin-long-name", "age": -1}`))
+	r.Header.Set(contentType, applicationJson)
+	r.Header.Set(acceptLanguage, "zh-CN,zh;q=0.9,en;q=0.8")
+	err := Bind(WithPathVars(r, map[string]string{"id": "1"}), &req)
+	var fes validation.FieldErrors
+	assert.True(t, errors.As(err, &fes))
+	assert.EqualError(t, err, "name不能大于8\nage必须大于或等于0")
+}
+
+func TestBindJsonBody(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": "kevin"}`))
+	r.Header.Set(contentType, applicationJson)
+	// chunked or unknown length
+	r.ContentLength = -1
+	var req ctxRequest
+	assert.NoError(t, BindJsonBody(r, &req))
+	assert.Equal(t, "kevin", req.Name)
+
+	body := `{"name": "` + strings.Repeat("a", maxBodyLen) + `"}`
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	r.Header.Set(contentType, applicationJson)
+	var mbe *http.MaxBytesError
+	assert.ErrorAs(t, BindJsonBody(r, &req), &mbe)
+}
+
+func TestBindValidateCtx(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": "admin"}`))
+	r.Header.Set(contentType, applicationJson)
+	r = r.WithContext(context.WithValue(r.Context(), ctxKey{}, "admin"))
+
+	var req ctxRequest
+	assert.EqualError(t, Bind(r, &req), "name is taken")
+	assert.Equal(t, "admin", req.Name)
+}
+
+func TestPathVars(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.Nil(t, PathVars(r))
+	r = WithPathVars(r, map[string]string{"id": "1"})
+	assert.Equal(t, map[string]string{"id": "1"}, PathVars(r))
+}
+
+func TestParseLocale(t *testing.T) {
+	assert.Equal(t, "zh-CN", parseLocale("zh-CN,zh;q=0.9"))
+	assert.Equal(t, "en", parseLocale(" en;q=0.8"))
+	assert.Equal(t, "", parseLocale(""))
+}
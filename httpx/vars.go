@@ -0,0 +1,24 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+)
+
+type pathVarsKey struct{}
+
+// PathVars returns the path vars in r, which are set by WithPathVars.
+func PathVars(r *http.Request) map[string]string {
+	vars, _ := r.Context().Value(pathVarsKey{}).(map[string]string)
+	return vars
+}
+
+// WithPathVars returns a shallow copy of r with the path vars, like the Params of search.Result:
+//
+//	result, ok := tree.Search(r.URL.Path)
+//	if ok {
+//		r = httpx.WithPathVars(r, result.Params)
+//	}
+func WithPathVars(r *http.Request, vars map[string]string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), pathVarsKey{}, vars))
+}
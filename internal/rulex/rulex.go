@@ -0,0 +1,167 @@
+// Package rulex holds the value checks shared by the mapping and validation packages,
+// so that the tag rules of both packages behave the same.
+package rulex
+
+import (
+	"cmp"
+	"net"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Compare compares the times, strings and numbers, returns false if left and right are not comparable.
+// The integers are compared exactly, the numbers of different kinds are compared as float64.
+func Compare(left, right reflect.Value) (int, bool) {
+	if left.Type() == timeType && right.Type() == timeType {
+		return left.Interface().(time.Time).Compare(right.Interface().(time.Time)), true
+	}
+
+	switch {
+	case isIntKind(left.Kind()) && isIntKind(right.Kind()):
+		return cmp.Compare(left.Int(), right.Int()), true
+	case isUintKind(left.Kind()) && isUintKind(right.Kind()):
+		return cmp.Compare(left.Uint(), right.Uint()), true
+	case left.Kind() == reflect.String && right.Kind() == reflect.String:
+		return cmp.Compare(left.String(), right.String()), true
+	}
+
+	l, lok := Number(left)
+	r, rok := Number(right)
+	if !lok || !rok {
+		return 0, false
+	}
+
+	return cmp.Compare(l, r), true
+}
+
+// FirstDuplicate returns the index of the first value that equals to a previous one, or -1 if all are unique.
+func FirstDuplicate(values []reflect.Value) int {
+	seen := make(map[any]struct{}, len(values))
+	for i, val := range values {
+		// the dynamic values of interfaces might not be comparable
+		if hashable(val.Type()) {
+			key := val.Interface()
+			if _, ok := seen[key]; ok {
+				return i
+			}
+			seen[key] = struct{}{}
+			continue
+		}
+
+		for j := 0; j < i; j++ {
+			if reflect.DeepEqual(val.Interface(), values[j].Interface()) {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// hashable reports whether the values of tp can be used as map keys without panics,
+// which is false for the types with interfaces, even if they are comparable.
+func hashable(tp reflect.Type) bool {
+	switch tp.Kind() {
+	case reflect.Interface:
+		return false
+	case reflect.Array:
+		return hashable(tp.Elem())
+	case reflect.Struct:
+		for i := 0; i < tp.NumField(); i++ {
+			if !hashable(tp.Field(i).Type) {
+				return false
+			}
+		}
+		return true
+	default:
+		return tp.Comparable()
+	}
+}
+
+// IsCIDR reports whether s is a CIDR notation IP address and prefix length.
+func IsCIDR(s string) bool {
+	_, _, err := net.ParseCIDR(s)
+	return err == nil
+}
+
+// IsDuration reports whether s is a duration, like 1m30s.
+func IsDuration(s string) bool {
+	_, err := time.ParseDuration(s)
+	return err == nil
+}
+
+// IsEmail reports whether s is a bare email address, like foo@example.com.
+func IsEmail(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Address == s
+}
+
+// IsHostPort reports whether s is a host and port, like localhost:80.
+func IsHostPort(s string) bool {
+	_, port, err := net.SplitHostPort(s)
+	if err != nil {
+		return false
+	}
+
+	_, err = strconv.ParseUint(port, 10, 16)
+	return err == nil
+}
+
+// IsIP reports whether s is an IPv4 or IPv6 address.
+func IsIP(s string) bool {
+	return net.ParseIP(s) != nil
+}
+
+// IsIPv4 reports whether s is an IPv4 address.
+func IsIPv4(s string) bool {
+	return IsIP(s) && !strings.Contains(s, ":")
+}
+
+// IsIPv6 reports whether s is an IPv6 address.
+func IsIPv6(s string) bool {
+	return IsIP(s) && strings.Contains(s, ":")
+}
+
+// IsURL reports whether s is an absolute URL with scheme and host.
+func IsURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && len(u.Scheme) > 0 && len(u.Host) > 0
+}
+
+// Number returns the value of the integers and floats as float64.
+func Number(v reflect.Value) (float64, bool) {
+	switch {
+	case isIntKind(v.Kind()):
+		return float64(v.Int()), true
+	case isUintKind(v.Kind()):
+		return float64(v.Uint()), true
+	case v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64:
+		return v.Float(), true
+	default:
+		return 0, false
+	}
+}
+
+func isIntKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	default:
+		return false
+	}
+}
+
+func isUintKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,73 @@
+package rulex
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompare(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		left, right any
+		expected    int
+		ok          bool
+	}{
+		{left: 1, right: int64(2), expected: -1, ok: true},
+		{left: uint64(1<<63 + 1), right: uint64(1 << 63), expected: 1, ok: true},
+		{left: 1.5, right: 1, expected: 1, ok: true},
+		{left: "a", right: "a", expected: 0, ok: true},
+		{left: now, right: now.Add(time.Second), expected: -1, ok: true},
+		{left: "1", right: 1},
+		{left: []int{1}, right: []int{1}},
+	}
+
+	for _, test := range tests {
+		actual, ok := Compare(reflect.ValueOf(test.left), reflect.ValueOf(test.right))
+		assert.Equal(t, test.ok, ok, test.left)
+		assert.Equal(t, test.expected, actual, test.left)
+	}
+}
+
+func TestFirstDuplicate(t *testing.T) {
+	values := func(items ...any) []reflect.Value {
+		v := reflect.ValueOf(items)
+		result := make([]reflect.Value, v.Len())
+		for i := range result {
+			result[i] = v.Index(i)
+		}
+		return result
+	}
+
+	assert.Equal(t, -1, FirstDuplicate(nil))
+	assert.Equal(t, -1, FirstDuplicate(values(1, "1", []int{1})))
+	assert.Equal(t, 2, FirstDuplicate(values(1, 2, 1)))
+	// the unhashable dynamic values are compared deeply
+	assert.Equal(t, 1, FirstDuplicate(values([]int{1}, []int{1})))
+
+	// comparable structs with interfaces might hold unhashable values
+	type item struct {
+		V any
+	}
+	items := reflect.ValueOf([]item{{V: []int{1}}, {V: map[string]int{}}, {V: []int{1}}})
+	assert.Equal(t, 2, FirstDuplicate([]reflect.Value{items.Index(0), items.Index(1), items.Index(2)}))
+	arrays := reflect.ValueOf([][1]any{{1}, {[]int{1}}})
+	assert.Equal(t, -1, FirstDuplicate([]reflect.Value{arrays.Index(0), arrays.Index(1)}))
+}
+
+func TestFormats(t *testing.T) {
+	assert.True(t, IsEmail("foo@example.com"))
+	assert.False(t, IsEmail("Foo <foo@example.com>"))
+	assert.True(t, IsURL("https://example.com"))
+	assert.False(t, IsURL("example.com"))
+	assert.True(t, IsIPv4("10.0.0.1"))
+	assert.False(t, IsIPv4("::1"))
+	assert.True(t, IsIPv6("::1"))
+	assert.True(t, IsCIDR("10.0.0.0/8"))
+	assert.True(t, IsHostPort("localhost:80"))
+	assert.False(t, IsHostPort("localhost:70000"))
+	assert.True(t, IsDuration("1m30s"))
+	assert.False(t, IsDuration("1x"))
+}
@@ -0,0 +1,115 @@
+package jsonx
+
+import (
+	"encoding/json"
+	"io"
+	"sync/atomic"
+
+	"github.com/bytedance/sonic"
+	jsoniter "github.com/json-iterator/go"
+)
+
+var (
+	// Sonic is the engine backed by github.com/bytedance/sonic, it's the default engine.
+	Sonic Engine = sonicEngine{}
+	// Jsoniter is the engine backed by github.com/json-iterator/go,
+	// configured to be compatible with the standard library.
+	Jsoniter Engine = jsoniterEngine{}
+	// Std is the engine backed by encoding/json.
+	Std Engine = stdEngine{}
+
+	engine atomic.Value
+)
+
+type (
+	// Engine is the json implementation used by jsonx.
+	Engine interface {
+		Marshal(v any) ([]byte, error)
+		NewDecoder(reader io.Reader) Decoder
+		NewEncoder(writer io.Writer) Encoder
+	}
+
+	// Decoder reads and decodes json values from an input stream.
+	Decoder interface {
+		Decode(v any) error
+		UseNumber()
+	}
+
+	// Encoder writes json values to an output stream, each value is followed by a newline.
+	Encoder interface {
+		Encode(v any) error
+		SetEscapeHTML(on bool)
+		SetIndent(prefix, indent string)
+	}
+
+	sonicEngine    struct{}
+	jsoniterEngine struct{}
+	stdEngine      struct{}
+
+	engineHolder struct {
+		Engine
+	}
+)
+
+func init() {
+	SetEngine(Sonic)
+}
+
+// GetEngine returns the engine in use.
+func GetEngine() Engine {
+	return engine.Load().(engineHolder).Engine
+}
+
+// SetEngine sets the engine used by jsonx, it should be called at init.
+// A nil engine is ignored.
+func SetEngine(e Engine) {
+	if e != nil {
+		engine.Store(engineHolder{Engine: e})
+	}
+}
+
+// NewDecoder returns a decoder of the engine in use that reads from reader.
+func NewDecoder(reader io.Reader) Decoder {
+	return GetEngine().NewDecoder(reader)
+}
+
+// NewEncoder returns an encoder of the engine in use that writes to writer.
+func NewEncoder(writer io.Writer) Encoder {
+	return GetEngine().NewEncoder(writer)
+}
+
+func (sonicEngine) Marshal(v any) ([]byte, error) {
+	return sonic.Marshal(v)
+}
+
+func (sonicEngine) NewDecoder(reader io.Reader) Decoder {
+	return sonic.ConfigDefault.NewDecoder(reader)
+}
+
+func (sonicEngine) NewEncoder(writer io.Writer) Encoder {
+	return sonic.ConfigDefault.NewEncoder(writer)
+}
+
+func (jsoniterEngine) Marshal(v any) ([]byte, error) {
+	return jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(v)
+}
+
+func (jsoniterEngine) NewDecoder(reader io.Reader) Decoder {
+	return jsoniter.ConfigCompatibleWithStandardLibrary.NewDecoder(reader)
+}
+
+func (jsoniterEngine) NewEncoder(writer io.Writer) Encoder {
+	return jsoniter.ConfigCompatibleWithStandardLibrary.NewEncoder(writer)
+}
+
+func (stdEngine) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (stdEngine) NewDecoder(reader io.Reader) Decoder {
+	return json.NewDecoder(reader)
+}
+
+func (stdEngine) NewEncoder(writer io.Writer) Encoder {
+	return json.NewEncoder(writer)
+}
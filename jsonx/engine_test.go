@@ -0,0 +1,57 @@
+package jsonx
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEngines(t *testing.T) {
+	engines := map[string]Engine{
+		"sonic":    Sonic,
+		"jsoniter": Jsoniter,
+		"std":      Std,
+	}
+
+	for name, e := range engines {
+		e := e
+		t.Run(name, func(t *testing.T) {
+			bs, err := e.Marshal(map[string]any{"name": "John"})
+			assert.Nil(t, err)
+			assert.Equal(t, `{"name":"John"}`, string(bs))
+
+			var v map[string]any
+			decoder := e.NewDecoder(bytes.NewReader([]byte(`{"age":12345678901234567890}`)))
+			decoder.UseNumber()
+			assert.Nil(t, decoder.Decode(&v))
+			assert.Equal(t, json.Number("12345678901234567890"), v["age"])
+
+			var buf bytes.Buffer
+			encoder := e.NewEncoder(&buf)
+			encoder.SetIndent("", " ")
+			assert.Nil(t, encoder.Encode([]int{1}))
+			assert.Equal(t, "[\n 1\n]\n", buf.String())
+		})
+	}
+}
+
+func TestSetEngine(t *testing.T) {
+	defer SetEngine(GetEngine())
+
+	SetEngine(Std)
+	assert.Equal(t, Std, GetEngine())
+	bs, err := Marshal("<a>")
+	assert.Nil(t, err)
+	assert.Equal(t, `"\u003ca\u003e"`, string(bs))
+
+	SetEngine(nil)
+	assert.Equal(t, Std, GetEngine())
+
+	var v struct {
+		Age int `json:"age"`
+	}
+	assert.Nil(t, UnmarshalFromString(`{"age":30}`, &v))
+	assert.Equal(t, 30, v.Age)
+}
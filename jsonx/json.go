@@ -5,13 +5,11 @@ import (
 	"fmt"
 	"io"
 	"strings"
-
-	"github.com/bytedance/sonic"
 )
 
 // Marshal marshals v into json bytes.
 func Marshal(v any) ([]byte, error) {
-	return sonic.Marshal(v)
+	return GetEngine().Marshal(v)
 }
 
 // MarshalToString marshals v into a string.
@@ -26,8 +24,7 @@ func MarshalToString(v any) (string, error) {
 
 // Unmarshal unmarshals data bytes into v.
 func Unmarshal(data []byte, v any) error {
-
-	decoder := sonic.ConfigDefault.NewDecoder(bytes.NewReader(data))
+	decoder := NewDecoder(bytes.NewReader(data))
 	if err := unmarshalUseNumber(decoder, v); err != nil {
 		return formatError(string(data), err)
 	}
@@ -37,7 +34,7 @@ func Unmarshal(data []byte, v any) error {
 
 // UnmarshalFromString unmarshals v from str.
 func UnmarshalFromString(str string, v any) error {
-	decoder := sonic.ConfigDefault.NewDecoder(strings.NewReader(str))
+	decoder := NewDecoder(strings.NewReader(str))
 	if err := unmarshalUseNumber(decoder, v); err != nil {
 		return formatError(str, err)
 	}
@@ -49,7 +46,7 @@ func UnmarshalFromString(str string, v any) error {
 func UnmarshalFromReader(reader io.Reader, v any) error {
 	var buf strings.Builder
 	teeReader := io.TeeReader(reader, &buf)
-	decoder := sonic.ConfigDefault.NewDecoder(teeReader)
+	decoder := NewDecoder(teeReader)
 	if err := unmarshalUseNumber(decoder, v); err != nil {
 		return formatError(buf.String(), err)
 	}
@@ -57,7 +54,7 @@ func UnmarshalFromReader(reader io.Reader, v any) error {
 	return nil
 }
 
-func unmarshalUseNumber(decoder sonic.Decoder, v any) error {
+func unmarshalUseNumber(decoder Decoder, v any) error {
 	decoder.UseNumber()
 	return decoder.Decode(v)
 }
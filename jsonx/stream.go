@@ -0,0 +1,73 @@
+package jsonx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+)
+
+// ErrNotArray indicates that the json value to iterate is not an array.
+var ErrNotArray = errors.New("json value is not an array")
+
+// Tokens iterates the json tokens in reader, the iteration stops at the first error.
+// Numbers are returned as json.Number.
+func Tokens(reader io.Reader) iter.Seq2[json.Token, error] {
+	return func(yield func(json.Token, error) bool) {
+		decoder := json.NewDecoder(reader)
+		decoder.UseNumber()
+		for {
+			token, err := decoder.Token()
+			if err == io.EOF {
+				return
+			}
+			if !yield(token, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Iterate iterates the elements of the json array in reader without loading
+// the whole array into memory, each element is unmarshaled into T by the engine in use.
+// If an element cannot be unmarshaled into T, the error is yielded and the iteration
+// goes on with the next element, syntax errors end the iteration.
+func Iterate[T any](reader io.Reader) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		decoder := json.NewDecoder(reader)
+		token, err := decoder.Token()
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		if token != json.Delim('[') {
+			yield(zero, fmt.Errorf("%w: %v", ErrNotArray, token))
+			return
+		}
+
+		for decoder.More() {
+			var raw json.RawMessage
+			if err := decoder.Decode(&raw); err != nil {
+				yield(zero, err)
+				return
+			}
+
+			var val T
+			if err := Unmarshal(raw, &val); err != nil {
+				if !yield(zero, err) {
+					return
+				}
+				continue
+			}
+			if !yield(val, nil) {
+				return
+			}
+		}
+
+		if _, err := decoder.Token(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package jsonx
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIterate(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+	}
+
+	var names []string
+	for val, err := range Iterate[user](strings.NewReader(`[{"name":"John"}, {"name":"Jane"}]`)) {
+		assert.Nil(t, err)
+		names = append(names, val.Name)
+	}
+	assert.Equal(t, []string{"John", "Jane"}, names)
+
+	var nums []int
+	var errs int
+	for val, err := range Iterate[int](strings.NewReader(`[1, "a", 3]`)) {
+		if err != nil {
+			errs++
+			continue
+		}
+		nums = append(nums, val)
+	}
+	assert.Equal(t, []int{1, 3}, nums)
+	assert.Equal(t, 1, errs)
+
+	nums = nil
+	for val := range Iterate[int](strings.NewReader(`[1, 2, 3]`)) {
+		nums = append(nums, val)
+		break
+	}
+	assert.Equal(t, []int{1}, nums)
+}
+
+func TestIterateError(t *testing.T) {
+	for _, err := range Iterate[int](strings.NewReader(`{"a":1}`)) {
+		assert.ErrorIs(t, err, ErrNotArray)
+	}
+
+	var count int
+	for _, err := range Iterate[int](strings.NewReader(`[1, 2`)) {
+		count++
+		if count == 3 {
+			assert.NotNil(t, err)
+		}
+	}
+	assert.Equal(t, 3, count)
+}
+
+func TestTokens(t *testing.T) {
+	var tokens []json.Token
+	for token, err := range Tokens(strings.NewReader(`{"a":[1,true]}`)) {
+		assert.Nil(t, err)
+		tokens = append(tokens, token)
+	}
+	assert.Equal(t, []json.Token{json.Delim('{'), "a", json.Delim('['), json.Number("1"), true,
+		json.Delim(']'), json.Delim('}')}, tokens)
+
+	var errs int
+	for _, err := range Tokens(strings.NewReader(`{"a":}`)) {
+		if err != nil {
+			errs++
+		}
+	}
+	assert.Equal(t, 1, errs)
+}
@@ -1,7 +1,6 @@
 package logx
 
 import (
-	"compress/gzip"
 	"errors"
 	"fmt"
 	"log"
@@ -13,6 +12,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/tp-life/utils/codec"
 	"github.com/tp-life/utils/fs"
 	"github.com/tp-life/utils/lang"
 )
@@ -455,7 +455,10 @@ func gzipFile(file string, fsys fileSystem) (err error) {
 		}
 	}()
 
-	w := gzip.NewWriter(out)
+	w, err := codec.GzipCompressor.NewWriter(out)
+	if err != nil {
+		return err
+	}
 	if _, err = fsys.Copy(w, in); err != nil {
 		// failed to copy, no need to close w
 		return err
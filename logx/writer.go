@@ -331,6 +331,8 @@ func wrapLevelWithColor(level string) string {
 	return color.WithColorPadding(level, colour)
 }
 
+// writeJson uses encoding/json rather than the jsonx engine, to keep the log output stable,
+// the map keys are sorted and the html characters are escaped.
 func writeJson(writer io.Writer, info any) {
 	if content, err := json.Marshal(info); err != nil {
 		log.Printf("err: %s\n\n%s", err.Error(), debug.Stack())
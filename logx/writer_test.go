@@ -145,6 +145,16 @@ func TestWriteJson(t *testing.T) {
 	assert.Contains(t, buf.String(), "runtime/debug.Stack")
 }
 
+func TestWriteJsonStable(t *testing.T) {
+	var buf bytes.Buffer
+	writeJson(&buf, map[string]any{
+		"c": 1,
+		"a": "<b>&",
+		"b": true,
+	})
+	assert.Equal(t, `{"a":"\u003cb\u003e\u0026","b":true,"c":1}`+"\n", buf.String())
+}
+
 func TestWritePlainAny(t *testing.T) {
 	var buf bytes.Buffer
 	log.SetOutput(&buf)
@@ -0,0 +1,343 @@
+package mapping
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tp-life/utils/internal/rulex"
+)
+
+const (
+	lenOption        = "len"
+	regexOption      = "regex"
+	formatOption     = "format"
+	uniqueOption     = "unique"
+	requiredIfOption = "required_if"
+	gtFieldOption    = "gtfield"
+	gteFieldOption   = "gtefield"
+	ltFieldOption    = "ltfield"
+	lteFieldOption   = "ltefield"
+
+	conditionSeparator = ":"
+
+	emailFormat    = "email"
+	urlFormat      = "url"
+	ipFormat       = "ip"
+	ipv4Format     = "ipv4"
+	ipv6Format     = "ipv6"
+	cidrFormat     = "cidr"
+	hostPortFormat = "hostport"
+	durationFormat = "duration"
+)
+
+var formatValidators = map[string]func(string) bool{
+	emailFormat:    rulex.IsEmail,
+	urlFormat:      rulex.IsURL,
+	ipFormat:       rulex.IsIP,
+	ipv4Format:     rulex.IsIPv4,
+	ipv6Format:     rulex.IsIPv6,
+	cidrFormat:     rulex.IsCIDR,
+	hostPortFormat: rulex.IsHostPort,
+	durationFormat: rulex.IsDuration,
+}
+
+type (
+	// constraints are the validation rules beyond options and range, like len, regex and gtfield.
+	constraints struct {
+		Len        *numberRange
+		Regex      *regexp.Regexp
+		Format     string
+		Unique     bool
+		RequiredIf *fieldCondition
+		Compares   []fieldCompare
+	}
+
+	// fieldCondition is the condition of required_if, like `required_if=Mode:prod`.
+	fieldCondition struct {
+		field string
+		value string
+	}
+
+	// fieldCompare is the rule to compare with another field, like `gtfield=Min`.
+	fieldCompare struct {
+		op    string
+		field string
+	}
+
+	// constraintError is the error of violating a constraint, the constraint is reported in FieldError.
+	constraintError struct {
+		constraint string
+		msg        string
+	}
+)
+
+func (e *constraintError) Error() string {
+	return e.msg
+}
+
+func (c *constraints) hasCrossRules() bool {
+	return c != nil && (c.RequiredIf != nil || len(c.Compares) > 0)
+}
+
+// parseConstraint parses the option into fieldOpts, returns false if it's not a constraint.
+func parseConstraint(fieldOpts *fieldOptions, fieldName, option string) (bool, error) {
+	name, val, _ := strings.Cut(option, equalToken)
+	name = strings.TrimSpace(name)
+	val = strings.TrimSpace(val)
+
+	switch name {
+	case lenOption, regexOption, formatOption, requiredIfOption,
+		gtFieldOption, gteFieldOption, ltFieldOption, lteFieldOption:
+		if len(val) == 0 {
+			return true, fmt.Errorf("field %q has wrong tag value %q", fieldName, name)
+		}
+	case uniqueOption:
+	default:
+		return false, nil
+	}
+
+	if fieldOpts.Constraints == nil {
+		fieldOpts.Constraints = new(constraints)
+	}
+	c := fieldOpts.Constraints
+
+	switch name {
+	case lenOption:
+		nr, err := parseNumberRange(val)
+		if err != nil {
+			return true, err
+		}
+		c.Len = nr
+	case regexOption:
+		re, err := regexp.Compile(val)
+		if err != nil {
+			return true, fmt.Errorf("field %q has wrong regex %q, %w", fieldName, val, err)
+		}
+		c.Regex = re
+	case formatOption:
+		if _, ok := formatValidators[val]; !ok {
+			return true, fmt.Errorf("field %q has unknown format %q", fieldName, val)
+		}
+		c.Format = val
+	case uniqueOption:
+		c.Unique = true
+	case requiredIfOption:
+		field, value, _ := strings.Cut(val, conditionSeparator)
+		c.RequiredIf = &fieldCondition{
+			field: strings.TrimSpace(field),
+			value: strings.TrimSpace(value),
+		}
+		// the field is optional unless the condition is met
+		fieldOpts.Optional = true
+	default:
+		c.Compares = append(c.Compares, fieldCompare{
+			op:    name,
+			field: val,
+		})
+	}
+
+	return true, nil
+}
+
+// validateConstraints validates the value set into the field.
+func validateConstraints(value reflect.Value, opts *fieldOptionsWithContext, fullName string) error {
+	if opts == nil {
+		return nil
+	}
+
+	value = indirectValue(value)
+	if !value.IsValid() {
+		return nil
+	}
+
+	if opts.Range != nil && value.Type() == durationType {
+		if err := validateNumberRange(float64(value.Int()), opts.Range); err != nil {
+			return &constraintError{
+				constraint: rangeOption + equalToken + opts.Range.durationString(),
+				msg: fmt.Sprintf("value %s for field %q is out of range %s",
+					time.Duration(value.Int()), fullName, opts.Range.durationString()),
+			}
+		}
+	}
+
+	c := opts.Constraints
+	if c == nil {
+		return nil
+	}
+
+	if c.Len != nil {
+		var n int
+		switch value.Kind() {
+		case reflect.String:
+			n = utf8.RuneCountInString(value.String())
+		case reflect.Array, reflect.Map, reflect.Slice:
+			n = value.Len()
+		default:
+			return fmt.Errorf("field %q of %s doesn't support len", fullName, value.Type())
+		}
+		if validateNumberRange(float64(n), c.Len) != nil {
+			return &constraintError{
+				constraint: lenOption + equalToken + c.Len.String(),
+				msg: fmt.Sprintf("length %d of field %q is out of range %s",
+					n, fullName, c.Len.String()),
+			}
+		}
+	}
+
+	if c.Regex != nil || len(c.Format) > 0 {
+		if value.Kind() != reflect.String {
+			return fmt.Errorf("field %q of %s doesn't support regex and format", fullName, value.Type())
+		}
+
+		s := value.String()
+		if c.Regex != nil && !c.Regex.MatchString(s) {
+			return &constraintError{
+				constraint: regexOption + equalToken + c.Regex.String(),
+				msg:        fmt.Sprintf("value %q for field %q doesn't match %q", s, fullName, c.Regex.String()),
+			}
+		}
+		if len(c.Format) > 0 && !formatValidators[c.Format](s) {
+			return &constraintError{
+				constraint: formatOption + equalToken + c.Format,
+				msg:        fmt.Sprintf("value %q for field %q is not a valid %s", s, fullName, c.Format),
+			}
+		}
+	}
+
+	if c.Unique {
+		if err := validateUnique(value, fullName); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateUnique(value reflect.Value, fullName string) error {
+	switch value.Kind() {
+	case reflect.Array, reflect.Slice:
+	default:
+		return fmt.Errorf("field %q of %s doesn't support unique", fullName, value.Type())
+	}
+
+	values := make([]reflect.Value, value.Len())
+	for i := range values {
+		values[i] = value.Index(i)
+	}
+	if i := rulex.FirstDuplicate(values); i >= 0 {
+		return &constraintError{
+			constraint: uniqueOption,
+			msg:        fmt.Sprintf("field %q has duplicate value %v at %d", fullName, Repr(values[i].Interface()), i),
+		}
+	}
+
+	return nil
+}
+
+// validateCrossFields validates the cross-field rules, like required_if and gtfield, of the field,
+// set reports whether the field is set by the value or the default value.
+func validateCrossFields(tagKey string, tp reflect.Type, value reflect.Value, field reflect.StructField,
+	set bool, c *constraints, fullName string) error {
+	if cond := c.RequiredIf; cond != nil && !set {
+		other, ok := siblingValue(tagKey, tp, value, cond.field)
+		if !ok {
+			return fmt.Errorf("field %q in required_if of %q doesn't exist", cond.field, fullName)
+		}
+		if other = indirectValue(other); other.IsValid() && Repr(other.Interface()) == cond.value {
+			return &constraintError{
+				constraint: requiredIfOption + equalToken + cond.field + conditionSeparator + cond.value,
+				msg:        fmt.Sprintf("field %q is required if %q is %q", fullName, cond.field, cond.value),
+			}
+		}
+	}
+
+	// the values of unset fields are not compared
+	if !set {
+		return nil
+	}
+
+	fv := value.FieldByIndex(field.Index)
+	for _, rule := range c.Compares {
+		other, ok := siblingValue(tagKey, tp, value, rule.field)
+		if !ok {
+			return fmt.Errorf("field %q in %s of %q doesn't exist", rule.field, rule.op, fullName)
+		}
+
+		left, right := indirectValue(fv), indirectValue(other)
+		if !left.IsValid() || !right.IsValid() {
+			continue
+		}
+
+		result, err := compareValues(left, right)
+		if err != nil {
+			return fmt.Errorf("field %q, %w", fullName, err)
+		}
+
+		var valid bool
+		switch rule.op {
+		case gtFieldOption:
+			valid = result > 0
+		case gteFieldOption:
+			valid = result >= 0
+		case ltFieldOption:
+			valid = result < 0
+		default:
+			valid = result <= 0
+		}
+		if !valid {
+			return &constraintError{
+				constraint: rule.op + equalToken + rule.field,
+				msg: fmt.Sprintf("value %v for field %q doesn't satisfy %s %q, which is %v",
+					Repr(left.Interface()), fullName, rule.op, rule.field, Repr(right.Interface())),
+			}
+		}
+	}
+
+	return nil
+}
+
+func compareValues(left, right reflect.Value) (int, error) {
+	result, ok := rulex.Compare(left, right)
+	if !ok {
+		return 0, fmt.Errorf("cannot compare %s with %s", left.Type(), right.Type())
+	}
+
+	return result, nil
+}
+
+// indirectValue dereferences v, returns the zero Value on nil pointers.
+func indirectValue(v reflect.Value) reflect.Value {
+	for v.IsValid() && v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+
+	return v
+}
+
+// siblingValue returns the value of the field in the struct, which is named by the field name or the key.
+func siblingValue(tagKey string, tp reflect.Type, value reflect.Value, name string) (reflect.Value, bool) {
+	if field, ok := tp.FieldByName(name); ok {
+		return value.FieldByIndex(field.Index), true
+	}
+
+	for i := 0; i < tp.NumField(); i++ {
+		field := tp.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		key, _, err := parseKeyAndOptions(tagKey, field)
+		if err == nil && key == name {
+			return value.Field(i), true
+		}
+	}
+
+	return reflect.Value{}, false
+}
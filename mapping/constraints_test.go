@@ -0,0 +1,270 @@
+package mapping
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnmarshalLenConstraint(t *testing.T) {
+	type inner struct {
+		Name  string            `key:"name,len=[1:4]"`
+		Tags  []string          `key:"tags,len=(0:2],optional"`
+		Attrs map[string]string `key:"attrs,len=[:1],optional"`
+	}
+
+	var in inner
+	assert.NoError(t, UnmarshalKey(map[string]any{
+		"name": "中文名字",
+		"tags": []any{"a", "b"},
+	}, &in))
+	assert.Equal(t, "中文名字", in.Name)
+
+	err := UnmarshalKey(map[string]any{"name": "abcde"}, &in)
+	var ce *constraintError
+	assert.True(t, errors.As(err, &ce))
+	assert.Equal(t, "len=[1:4]", ce.constraint)
+
+	assert.Error(t, UnmarshalKey(map[string]any{"name": "a", "tags": []any{}}, &in))
+	assert.Error(t, UnmarshalKey(map[string]any{
+		"name":  "a",
+		"attrs": map[string]any{"a": "1", "b": "2"},
+	}, &in))
+}
+
+func TestUnmarshalRegexConstraint(t *testing.T) {
+	type inner struct {
+		Name string `key:"name,regex=^[a-z][a-z0-9-]{0\\,7}$"`
+	}
+
+	var in inner
+	assert.NoError(t, UnmarshalKey(map[string]any{"name": "svc-1"}, &in))
+	assert.Error(t, UnmarshalKey(map[string]any{"name": "1svc"}, &in))
+	assert.Error(t, UnmarshalKey(map[string]any{"name": "service-name"}, &in))
+
+	type wrong struct {
+		Name string `key:"name,regex=[a-"`
+	}
+	var w wrong
+	assert.Error(t, UnmarshalKey(map[string]any{"name": "a"}, &w))
+}
+
+func TestUnmarshalFormatConstraint(t *testing.T) {
+	tests := []struct {
+		format  string
+		valid   []string
+		invalid []string
+	}{
+		{
+			format:  emailFormat,
+			valid:   []string{"foo@example.com"},
+			invalid: []string{"foo", "Foo <foo@example.com>"},
+		},
+		{
+			format:  urlFormat,
+			valid:   []string{"https://example.com/path?q=1"},
+			invalid: []string{"example.com", "/path"},
+		},
+		{
+			format:  ipFormat,
+			valid:   []string{"127.0.0.1", "::1"},
+			invalid: []string{"localhost"},
+		},
+		{
+			format:  ipv4Format,
+			valid:   []string{"10.0.0.1"},
+			invalid: []string{"::1", "10.0.0.256"},
+		},
+		{
+			format:  ipv6Format,
+			valid:   []string{"fe80::1"},
+			invalid: []string{"10.0.0.1"},
+		},
+		{
+			format:  cidrFormat,
+			valid:   []string{"10.0.0.0/8", "fe80::/10"},
+			invalid: []string{"10.0.0.1"},
+		},
+		{
+			format:  hostPortFormat,
+			valid:   []string{"localhost:8080", ":80", "[::1]:443"},
+			invalid: []string{"localhost", "localhost:http", "localhost:70000"},
+		},
+		{
+			format:  durationFormat,
+			valid:   []string{"1h30m"},
+			invalid: []string{"1 hour"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			validate := formatValidators[test.format]
+			for _, v := range test.valid {
+				assert.True(t, validate(v), v)
+			}
+			for _, v := range test.invalid {
+				assert.False(t, validate(v), v)
+			}
+		})
+	}
+
+	type inner struct {
+		Email string `json:",format=email"`
+	}
+	var in inner
+	err := UnmarshalJsonBytes([]byte(`{"Email": "foo"}`), &in)
+	assert.EqualError(t, err, `value "foo" for field "Email" is not a valid email`)
+
+	type unknown struct {
+		Email string `json:",format=phone"`
+	}
+	var u unknown
+	assert.Error(t, UnmarshalJsonBytes([]byte(`{"Email": "foo"}`), &u))
+}
+
+func TestUnmarshalDurationRange(t *testing.T) {
+	type inner struct {
+		Timeout time.Duration `json:",range=[100ms:1m]"`
+	}
+
+	var in inner
+	assert.NoError(t, UnmarshalJsonBytes([]byte(`{"Timeout": "3s"}`), &in))
+	assert.Equal(t, 3*time.Second, in.Timeout)
+
+	err := UnmarshalJsonBytes([]byte(`{"Timeout": "2m"}`), &in)
+	var ce *constraintError
+	assert.True(t, errors.As(err, &ce))
+	assert.Equal(t, "range=[100ms:1m0s]", ce.constraint)
+}
+
+func TestUnmarshalUniqueConstraint(t *testing.T) {
+	type inner struct {
+		Hosts []string         `json:",unique"`
+		Rules []map[string]any `json:",unique,optional"`
+	}
+
+	var in inner
+	assert.NoError(t, UnmarshalJsonBytes([]byte(`{"Hosts": ["a", "b"]}`), &in))
+	assert.EqualError(t, UnmarshalJsonBytes([]byte(`{"Hosts": ["a", "b", "a"]}`), &in),
+		`field "Hosts" has duplicate value a at 2`)
+	assert.Error(t, UnmarshalJsonBytes([]byte(`{"Hosts": [], "Rules": [{"a": 1}, {"a": 1}]}`), &in))
+}
+
+func TestUnmarshalRequiredIf(t *testing.T) {
+	type inner struct {
+		Mode     string
+		CertFile string `json:",required_if=Mode:tls"`
+		Port     int    `json:"port,optional"`
+		Proxy    string `json:",required_if=port:8080"`
+	}
+
+	var in inner
+	assert.NoError(t, UnmarshalJsonBytes([]byte(`{"Mode": "plain"}`), &in))
+	assert.NoError(t, UnmarshalJsonBytes([]byte(`{"Mode": "tls", "CertFile": "a.pem"}`), &in))
+	assert.EqualError(t, UnmarshalJsonBytes([]byte(`{"Mode": "tls"}`), &in),
+		`field "CertFile" is required if "Mode" is "tls"`)
+	// the sibling field can be referenced by key
+	assert.Error(t, UnmarshalJsonBytes([]byte(`{"Mode": "plain", "port": 8080}`), &in))
+
+	type wrong struct {
+		CertFile string `json:",required_if=Unknown:tls"`
+	}
+	var w wrong
+	assert.Error(t, UnmarshalJsonBytes([]byte(`{}`), &w))
+}
+
+func TestUnmarshalCompareFields(t *testing.T) {
+	type inner struct {
+		Min     int
+		Max     int           `json:",gtfield=Min"`
+		Limit   float64       `json:",gtefield=Min,ltefield=Max"`
+		Start   time.Time     `json:",optional"`
+		End     time.Time     `json:",optional,gtfield=Start"`
+		Timeout time.Duration `json:",ltfield=Idle"`
+		Idle    time.Duration
+	}
+
+	var in inner
+	assert.NoError(t, UnmarshalJsonBytes([]byte(`{"Min": 1, "Max": 3, "Limit": 1.5, "Timeout": "1s", "Idle": "1m"}`), &in))
+	assert.Error(t, UnmarshalJsonBytes([]byte(`{"Min": 3, "Max": 3, "Limit": 3, "Timeout": "1s", "Idle": "1m"}`), &in))
+	assert.Error(t, UnmarshalJsonBytes([]byte(`{"Min": 1, "Max": 3, "Limit": 4, "Timeout": "1s", "Idle": "1m"}`), &in))
+	assert.Error(t, UnmarshalJsonBytes([]byte(`{"Min": 1, "Max": 3, "Limit": 2, "Timeout": "2m", "Idle": "1m"}`), &in))
+
+	type wrong struct {
+		Name string
+		Max  int `json:",gtfield=Name"`
+	}
+	var w wrong
+	assert.Error(t, UnmarshalJsonBytes([]byte(`{"Name": "a", "Max": 1}`), &w))
+}
+
+func TestUnmarshalConstraintsAggregated(t *testing.T) {
+	type service struct {
+		Name string   `json:",len=[1:8]"`
+		Addr string   `json:",format=hostport"`
+		Tags []string `json:",unique,optional"`
+	}
+	type config struct {
+		Min      int
+		Max      int `json:",gtfield=Min"`
+		Services []service
+	}
+
+	var c config
+	err := UnmarshalJsonBytes([]byte(`{
+  "Min": 5,
+  "Max": 1,
+  "Services": [
+    {"Name": "a", "Addr": "localhost:80"},
+    {"Name": "too-long-name", "Addr": "localhost", "Tags": ["x", "x"]}
+  ]
+}`), &c, WithAggregatedErrors())
+
+	var fes FieldErrors
+	assert.True(t, errors.As(err, &fes))
+	constraints := make(map[string]string)
+	for _, fe := range fes {
+		constraints[fe.Path] = fe.Constraint
+	}
+	assert.Equal(t, map[string]string{
+		"Max":              "gtfield=Min",
+		"Services[1].Name": "len=[1:8]",
+		"Services[1].Addr": "format=hostport",
+		"Services[1].Tags": "unique",
+	}, constraints)
+}
+
+func TestJsonSchemaConstraints(t *testing.T) {
+	type inner struct {
+		Name    string        `json:",len=(0:64),regex=^[a-z]+$"`
+		Email   string        `json:",format=email"`
+		Hosts   []string      `json:",len=[1:],unique"`
+		Timeout time.Duration `json:",range=[1s:1m]"`
+	}
+
+	content, err := JsonSchema(inner{})
+	assert.NoError(t, err)
+
+	var schema map[string]any
+	assert.NoError(t, json.Unmarshal(content, &schema))
+	props := schema["properties"].(map[string]any)
+	assert.Equal(t, map[string]any{
+		"type":      "string",
+		"minLength": 1.0,
+		"maxLength": 63.0,
+		"pattern":   "^[a-z]+$",
+	}, props["Name"])
+	assert.Equal(t, "email", props["Email"].(map[string]any)["format"])
+	assert.Equal(t, 1.0, props["Hosts"].(map[string]any)["minItems"])
+	assert.Equal(t, true, props["Hosts"].(map[string]any)["uniqueItems"])
+	assert.NotContains(t, props["Timeout"], "minimum")
+
+	doc, err := MarkdownDoc(inner{})
+	assert.NoError(t, err)
+	assert.Contains(t, doc, "| Timeout | duration | yes |  | range: [1s:1m0s] |  |  |")
+	assert.Contains(t, doc, "| Hosts | []string | yes |  | len: [1:], unique |  |  |")
+}
@@ -0,0 +1,131 @@
+package mapping
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+)
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// typeDecoder decodes the value from the map into the registered type.
+type typeDecoder func(v any) (reflect.Value, error)
+
+// WithTypeDecoder customizes an Unmarshaler to decode the fields of type T with decode,
+// which takes precedence over encoding.TextUnmarshaler and json.Unmarshaler.
+// The value passed to decode is the raw value from the map, like string, json.Number,
+// bool, map[string]any or []any, or the string from environment variables and defaults.
+// Fields of *T, []T and map[string]T are decoded with it as well.
+func WithTypeDecoder[T any](decode func(v any) (T, error)) UnmarshalOption {
+	return func(opts *unmarshalOptions) {
+		if opts.typeDecoders == nil {
+			opts.typeDecoders = make(map[reflect.Type]typeDecoder)
+		}
+
+		opts.typeDecoders[reflect.TypeOf((*T)(nil)).Elem()] = func(v any) (reflect.Value, error) {
+			val, err := decode(v)
+			if err != nil {
+				return emptyValue, err
+			}
+
+			return reflect.ValueOf(&val).Elem(), nil
+		}
+	}
+}
+
+// processFieldDecoder decodes mapValue into value with the registered type decoders,
+// encoding.TextUnmarshaler or json.Unmarshaler, returns false if none of them applies.
+func (u *Unmarshaler) processFieldDecoder(fieldType reflect.Type, value reflect.Value,
+	mapValue any) (bool, error) {
+	if decode, ok := u.opts.typeDecoders[fieldType]; ok {
+		target, err := decode(mapValue)
+		if err != nil {
+			return true, err
+		}
+
+		value.Set(target)
+		return true, nil
+	}
+
+	derefedType := Deref(fieldType)
+	if decode, ok := u.opts.typeDecoders[derefedType]; ok {
+		target, err := decode(mapValue)
+		if err != nil {
+			return true, err
+		}
+
+		SetValue(fieldType, value, target)
+		return true, nil
+	}
+
+	if derefedType.Kind() == reflect.Interface {
+		return false, nil
+	}
+
+	ptrType := reflect.PointerTo(derefedType)
+	isText := ptrType.Implements(textUnmarshalerType)
+	isJson := ptrType.Implements(jsonUnmarshalerType)
+	// the structs with the mapping tags are decoded field by field from maps,
+	// to keep the options like default and range working.
+	if _, ok := mapValue.(map[string]any); ok && u.hasTaggedFields(derefedType) {
+		isJson = false
+	}
+	if !isText && !isJson {
+		return false, nil
+	}
+
+	var text []byte
+	switch mv := mapValue.(type) {
+	case string:
+		text = []byte(mv)
+	case []byte:
+		text = mv
+	case json.Number:
+		text = []byte(mv)
+	default:
+		if !isJson {
+			return false, nil
+		}
+	}
+
+	target := reflect.New(derefedType)
+	if isText && text != nil {
+		if err := target.Interface().(encoding.TextUnmarshaler).UnmarshalText(text); err != nil {
+			return true, err
+		}
+	} else {
+		content, err := json.Marshal(mapValue)
+		if err != nil {
+			return true, err
+		}
+
+		if err = target.Interface().(json.Unmarshaler).UnmarshalJSON(content); err != nil {
+			return true, err
+		}
+	}
+
+	SetValue(fieldType, value, target.Elem())
+	return true, nil
+}
+
+// hasTaggedFields checks if tp is a struct with any field tagged with the key of u.
+func (u *Unmarshaler) hasTaggedFields(tp reflect.Type) bool {
+	if tp.Kind() != reflect.Struct {
+		return false
+	}
+
+	for i := 0; i < tp.NumField(); i++ {
+		if _, ok := tp.Field(i).Tag.Lookup(u.key); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func implementsTextUnmarshaler(tp reflect.Type) bool {
+	return tp.Kind() != reflect.Interface && reflect.PointerTo(tp).Implements(textUnmarshalerType)
+}
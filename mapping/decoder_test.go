@@ -0,0 +1,195 @@
+package mapping
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	byteSize int64
+
+	logLevel int
+)
+
+func (s *byteSize) UnmarshalText(text []byte) error {
+	str := strings.ToUpper(string(text))
+	unit := int64(1)
+	switch {
+	case strings.HasSuffix(str, "KB"):
+		unit = 1 << 10
+	case strings.HasSuffix(str, "MB"):
+		unit = 1 << 20
+	}
+
+	n, err := strconv.ParseInt(strings.TrimRight(str, "KMB"), 10, 64)
+	if err != nil {
+		return err
+	}
+
+	*s = byteSize(n * unit)
+	return nil
+}
+
+func (l *logLevel) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		var n int
+		if err = json.Unmarshal(data, &n); err != nil {
+			return err
+		}
+
+		*l = logLevel(n)
+		return nil
+	}
+
+	switch name {
+	case "debug":
+		*l = 0
+	case "info":
+		*l = 1
+	case "error":
+		*l = 2
+	default:
+		return fmt.Errorf("unknown level %q", name)
+	}
+
+	return nil
+}
+
+// taggedJsonConfig implements json.Unmarshaler, but has the mapping tags.
+type taggedJsonConfig struct {
+	Name     string `json:"name"`
+	MaxConns int    `json:"maxConns,default=10,range=[1:100]"`
+}
+
+func (c *taggedJsonConfig) UnmarshalJSON(data []byte) error {
+	type plain taggedJsonConfig
+	return json.Unmarshal(data, (*plain)(c))
+}
+
+func TestUnmarshalTextUnmarshaler(t *testing.T) {
+	type inner struct {
+		IP      net.IP
+		IPs     []net.IP            `json:",optional"`
+		Peers   map[string]*net.IP  `json:",optional"`
+		Size    byteSize            `json:",default=1KB"`
+		MaxSize *byteSize           `json:",optional"`
+		Sizes   map[string]byteSize `json:",optional"`
+		Start   time.Time
+	}
+
+	var in inner
+	assert.NoError(t, UnmarshalJsonBytes([]byte(`{
+  "IP": "10.0.0.1",
+  "IPs": ["10.0.0.2", "::1"],
+  "Peers": {"a": "10.0.0.3"},
+  "MaxSize": "10MB",
+  "Sizes": {"a": "2kb", "b": 10},
+  "Start": "2024-01-02T03:04:05Z"
+}`), &in))
+	assert.Equal(t, "10.0.0.1", in.IP.String())
+	assert.Equal(t, []net.IP{net.ParseIP("10.0.0.2"), net.ParseIP("::1")}, in.IPs)
+	assert.Equal(t, "10.0.0.3", in.Peers["a"].String())
+	assert.Equal(t, byteSize(1024), in.Size)
+	assert.Equal(t, byteSize(10<<20), *in.MaxSize)
+	assert.Equal(t, map[string]byteSize{"a": 2048, "b": 10}, in.Sizes)
+	assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), in.Start)
+
+	assert.Error(t, UnmarshalJsonBytes([]byte(`{"IP": "10.0.0.1", "Start": "2024", "Size": "a"}`), &in))
+}
+
+func TestUnmarshalJsonUnmarshaler(t *testing.T) {
+	type inner struct {
+		Level  logLevel
+		Levels []logLevel          `json:",optional"`
+		Raw    json.RawMessage     `json:",optional"`
+		ByName map[string]logLevel `json:",optional"`
+	}
+
+	var in inner
+	assert.NoError(t, UnmarshalJsonBytes([]byte(`{
+  "Level": "error",
+  "Levels": ["info", 0],
+  "Raw": {"a": [1, 2]},
+  "ByName": {"a": "info"}
+}`), &in))
+	assert.Equal(t, logLevel(2), in.Level)
+	assert.Equal(t, []logLevel{1, 0}, in.Levels)
+	assert.JSONEq(t, `{"a": [1, 2]}`, string(in.Raw))
+	assert.Equal(t, map[string]logLevel{"a": 1}, in.ByName)
+
+	err := UnmarshalJsonBytes([]byte(`{"Levels": ["info", "warn"], "Level": "info"}`), &in,
+		WithAggregatedErrors())
+	var fes FieldErrors
+	assert.True(t, errors.As(err, &fes))
+	assert.Equal(t, "Levels[1]", fes[0].Path)
+}
+
+func TestUnmarshalTaggedJsonUnmarshaler(t *testing.T) {
+	type inner struct {
+		Conf taggedJsonConfig
+	}
+
+	var in inner
+	assert.NoError(t, UnmarshalJsonBytes([]byte(`{"Conf": {"name": "a"}}`), &in))
+	assert.Equal(t, taggedJsonConfig{Name: "a", MaxConns: 10}, in.Conf)
+	assert.Error(t, UnmarshalJsonBytes([]byte(`{"Conf": {"name": "a", "maxConns": 200}}`), &in))
+}
+
+func TestUnmarshalWithTypeDecoder(t *testing.T) {
+	type inner struct {
+		Endpoint url.URL
+		Backup   *url.URL   `json:",optional"`
+		Mirrors  []*url.URL `json:",optional"`
+		Day      time.Time  `json:",default=2024-01-02"`
+		Size     byteSize   `json:",env=TEST_DECODER_SIZE"`
+	}
+
+	parseURL := WithTypeDecoder(func(v any) (url.URL, error) {
+		s, ok := v.(string)
+		if !ok {
+			return url.URL{}, errTypeMismatch
+		}
+
+		u, err := url.Parse(s)
+		if err != nil {
+			return url.URL{}, err
+		}
+
+		return *u, nil
+	})
+	parseDay := WithTypeDecoder(func(v any) (time.Time, error) {
+		return time.Parse(time.DateOnly, fmt.Sprint(v))
+	})
+	// registered decoders take precedence over the UnmarshalText methods
+	parseSize := WithTypeDecoder(func(v any) (byteSize, error) {
+		n, err := strconv.ParseInt(fmt.Sprint(v), 10, 64)
+		return byteSize(n * 2), err
+	})
+
+	t.Setenv("TEST_DECODER_SIZE", "5")
+	var in inner
+	assert.NoError(t, UnmarshalJsonBytes([]byte(`{
+  "Endpoint": "https://example.com/api",
+  "Backup": "https://backup.example.com",
+  "Mirrors": ["https://a.example.com", "https://b.example.com"]
+}`), &in, parseURL, parseDay, parseSize))
+	assert.Equal(t, "example.com", in.Endpoint.Host)
+	assert.Equal(t, "/api", in.Endpoint.Path)
+	assert.Equal(t, "backup.example.com", in.Backup.Host)
+	assert.Equal(t, "b.example.com", in.Mirrors[1].Host)
+	assert.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), in.Day)
+	assert.Equal(t, byteSize(10), in.Size)
+
+	assert.ErrorIs(t, UnmarshalJsonBytes([]byte(`{"Endpoint": 1}`), &in, parseURL, parseDay, parseSize),
+		errTypeMismatch)
+}
@@ -0,0 +1,329 @@
+package mapping
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	encodingx "github.com/tp-life/utils/encoding"
+)
+
+// errNilElement indicates a nil element in a slice, which can't be marshaled into toml, and can't be skipped.
+var errNilElement = errors.New("nil element in slice is not supported")
+
+type (
+	// MarshalOption defines the method to customize the marshaling of MarshalJson, MarshalYaml and MarshalToml.
+	MarshalOption func(opts *marshalOptions)
+
+	marshalOptions struct {
+		omitDefaults bool
+	}
+
+	encoder struct {
+		opts marshalOptions
+		buf  bytes.Buffer
+	}
+)
+
+// WithOmitDefaults customizes the marshaling to omit the fields with the default values,
+// and the optional fields with zero values, so that only the customized values are kept.
+func WithOmitDefaults() MarshalOption {
+	return func(opts *marshalOptions) {
+		opts.omitDefaults = true
+	}
+}
+
+// MarshalJson marshals v into indented json, honoring the keys and options in json tags,
+// the result can be unmarshaled by UnmarshalJsonBytes. Durations are marshaled as strings like 3s.
+func MarshalJson(v any, opts ...MarshalOption) ([]byte, error) {
+	content, err := marshalJsonCompact(v, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err = json.Indent(&buf, content, "", "  "); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+
+	return buf.Bytes(), nil
+}
+
+// MarshalToml is like MarshalJson, but marshals v into toml, the keys are sorted.
+func MarshalToml(v any, opts ...MarshalOption) ([]byte, error) {
+	content, err := marshalJsonCompact(v, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return encodingx.Convert(content, encodingx.FormatJson, encodingx.FormatToml)
+}
+
+// MarshalYaml is like MarshalJson, but marshals v into yaml, the keys are in the order of the fields.
+func MarshalYaml(v any, opts ...MarshalOption) ([]byte, error) {
+	content, err := marshalJsonCompact(v, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return encodingx.Convert(content, encodingx.FormatJson, encodingx.FormatYaml)
+}
+
+func marshalJsonCompact(v any, opts ...MarshalOption) ([]byte, error) {
+	var e encoder
+	for _, opt := range opts {
+		opt(&e.opts)
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, errValueNotStruct
+	}
+
+	if _, err := e.encodeStruct(rv); err != nil {
+		return nil, err
+	}
+
+	return e.buf.Bytes(), nil
+}
+
+// encodeStruct writes the fields of v as a json object, returns the number of the written fields.
+func (e *encoder) encodeStruct(v reflect.Value) (int, error) {
+	e.buf.WriteByte('{')
+	n, err := e.encodeFields(v, 0)
+	if err != nil {
+		return 0, err
+	}
+	e.buf.WriteByte('}')
+
+	return n, nil
+}
+
+func (e *encoder) encodeFields(v reflect.Value, written int) (int, error) {
+	tp := v.Type()
+	for i := 0; i < tp.NumField(); i++ {
+		field := tp.Field(i)
+		if usingDifferentKeys(jsonTagKey, field) {
+			continue
+		}
+
+		key, opts, err := parseKeyAndOptions(jsonTagKey, field)
+		if err != nil {
+			return 0, err
+		}
+		if key == ignoreKey {
+			continue
+		}
+
+		fv := v.Field(i)
+		// anonymous structs are flattened, like the Unmarshaler does
+		if field.Anonymous && Deref(field.Type).Kind() == reflect.Struct {
+			if fv = indirectValue(fv); !fv.IsValid() {
+				continue
+			}
+			if written, err = e.encodeFields(fv, written); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		omit, err := e.omitField(field, fv, opts)
+		if err != nil {
+			return 0, err
+		}
+		if omit {
+			continue
+		}
+
+		mark := e.buf.Len()
+		if written > 0 {
+			e.buf.WriteByte(',')
+		}
+		if err = e.encodeScalar(key); err != nil {
+			return 0, err
+		}
+		e.buf.WriteByte(':')
+
+		empty, err := e.encodeValue(fv, opts != nil && opts.FromString)
+		if err != nil {
+			return 0, err
+		}
+		// nested objects with all the fields omitted are omitted as well
+		if empty && e.opts.omitDefaults {
+			e.buf.Truncate(mark)
+			continue
+		}
+
+		written++
+	}
+
+	return written, nil
+}
+
+// encodeValue writes v as json, returns true if v is an object without fields.
+func (e *encoder) encodeValue(v reflect.Value, fromString bool) (bool, error) {
+	if v = indirectValue(v); !v.IsValid() {
+		e.buf.WriteString("null")
+		return false, nil
+	}
+
+	if v.Kind() == reflect.Interface {
+		return e.encodeValue(v.Elem(), fromString)
+	}
+
+	switch {
+	case v.Type() == durationType:
+		return false, e.encodeScalar(time.Duration(v.Int()).String())
+	case v.Type() == timeType:
+		return false, e.encodeScalar(v.Interface().(time.Time).Format(time.RFC3339Nano))
+	}
+
+	if v.CanInterface() {
+		switch val := v.Interface().(type) {
+		case json.Marshaler:
+			content, err := val.MarshalJSON()
+			if err != nil {
+				return false, err
+			}
+			e.buf.Write(content)
+			return false, nil
+		case encoding.TextMarshaler:
+			content, err := val.MarshalText()
+			if err != nil {
+				return false, err
+			}
+			return false, e.encodeScalar(string(content))
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		n, err := e.encodeStruct(v)
+		return n == 0, err
+	case reflect.Map:
+		return e.encodeMap(v)
+	case reflect.Array, reflect.Slice:
+		e.buf.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			elem := v.Index(i)
+			if isNilValue(elem) {
+				return false, fmt.Errorf("%w, index %d", errNilElement, i)
+			}
+			if i > 0 {
+				e.buf.WriteByte(',')
+			}
+			if _, err := e.encodeValue(elem, fromString); err != nil {
+				return false, err
+			}
+		}
+		e.buf.WriteByte(']')
+		return false, nil
+	default:
+		if fromString {
+			return false, e.encodeScalar(fmt.Sprint(v.Interface()))
+		}
+
+		return false, e.encodeScalar(v.Interface())
+	}
+}
+
+func (e *encoder) encodeMap(v reflect.Value) (bool, error) {
+	keys := make([]string, 0, v.Len())
+	values := make(map[string]reflect.Value, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		// nil values are omitted like the nil fields
+		if isNilValue(iter.Value()) {
+			continue
+		}
+
+		key := fmt.Sprint(iter.Key().Interface())
+		keys = append(keys, key)
+		values[key] = iter.Value()
+	}
+	// sort the keys to make the output stable
+	sort.Strings(keys)
+
+	e.buf.WriteByte('{')
+	for i, key := range keys {
+		if i > 0 {
+			e.buf.WriteByte(',')
+		}
+		if err := e.encodeScalar(key); err != nil {
+			return false, err
+		}
+		e.buf.WriteByte(':')
+		if _, err := e.encodeValue(values[key], false); err != nil {
+			return false, err
+		}
+	}
+	e.buf.WriteByte('}')
+
+	return len(keys) == 0, nil
+}
+
+func (e *encoder) encodeScalar(v any) error {
+	content, err := marshalCompact(v)
+	if err != nil {
+		return err
+	}
+
+	e.buf.Write(content)
+	return nil
+}
+
+func isNilValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	default:
+		return false
+	}
+}
+
+// omitField reports whether the field should be omitted. Nil values are always omitted,
+// because they can't be marshaled into toml, and they're the same as missing on unmarshaling.
+func (e *encoder) omitField(field reflect.StructField, v reflect.Value, opts *fieldOptions) (bool, error) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return true, nil
+		}
+	case reflect.Map, reflect.Slice:
+		if v.IsNil() && opts != nil && opts.Optional {
+			return true, nil
+		}
+	}
+
+	if !e.opts.omitDefaults || opts == nil {
+		return false, nil
+	}
+
+	if opts.Optional && v.IsZero() {
+		return true, nil
+	}
+
+	if len(opts.Default) == 0 {
+		return false, nil
+	}
+
+	def := reflect.New(field.Type).Elem()
+	if err := jsonUnmarshaler.processNamedFieldWithoutValue(field.Type, def,
+		&opts.fieldOptionsWithContext, field.Name); err != nil {
+		return false, err
+	}
+
+	return reflect.DeepEqual(def.Interface(), v.Interface()), nil
+}
@@ -0,0 +1,193 @@
+package mapping
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	encodeLogConf struct {
+		Level string `json:",default=info,options=debug|info|error"`
+		Path  string `json:",optional"`
+	}
+
+	encodeBase struct {
+		Name string
+	}
+
+	encodeUpstream struct {
+		Addr   string
+		Weight int `json:",default=1"`
+	}
+
+	encodeConf struct {
+		encodeBase
+		Host      string            `json:",default=0.0.0.0"`
+		Port      int               `json:"port"`
+		Ratio     float64           `json:",default=0.5"`
+		Timeout   time.Duration     `json:",default=3s"`
+		Verbose   bool              `json:",optional"`
+		Hosts     []string          `json:",default=[a,b]"`
+		Labels    map[string]string `json:",optional"`
+		Log       encodeLogConf
+		Upstreams []encodeUpstream `json:",optional"`
+		Backup    *encodeUpstream  `json:",optional"`
+		Count     int64            `json:",string,optional"`
+		Ignored   string           `json:"-"`
+		Other     string           `path:"other"`
+	}
+)
+
+func newEncodeConf() encodeConf {
+	return encodeConf{
+		encodeBase: encodeBase{Name: "app"},
+		Host:       "127.0.0.1",
+		Port:       8080,
+		Ratio:      0.5,
+		Timeout:    1500 * time.Millisecond,
+		Hosts:      []string{"a", "b"},
+		Labels:     map[string]string{"zone": "z1", "env": "prod"},
+		Log:        encodeLogConf{Level: "info"},
+		Upstreams: []encodeUpstream{
+			{Addr: "10.0.0.1:80", Weight: 1},
+			{Addr: "10.0.0.2:80", Weight: 2},
+		},
+		Count:   10,
+		Ignored: "ignored",
+		Other:   "other",
+	}
+}
+
+func TestMarshalJson(t *testing.T) {
+	content, err := MarshalJson(newEncodeConf())
+	assert.NoError(t, err)
+	assert.Equal(t, `{
+  "Name": "app",
+  "Host": "127.0.0.1",
+  "port": 8080,
+  "Ratio": 0.5,
+  "Timeout": "1.5s",
+  "Verbose": false,
+  "Hosts": [
+    "a",
+    "b"
+  ],
+  "Labels": {
+    "env": "prod",
+    "zone": "z1"
+  },
+  "Log": {
+    "Level": "info",
+    "Path": ""
+  },
+  "Upstreams": [
+    {
+      "Addr": "10.0.0.1:80",
+      "Weight": 1
+    },
+    {
+      "Addr": "10.0.0.2:80",
+      "Weight": 2
+    }
+  ],
+  "Count": "10"
+}
+`, string(content))
+
+	_, err = MarshalJson(1)
+	assert.Equal(t, errValueNotStruct, err)
+}
+
+func TestMarshalOmitDefaults(t *testing.T) {
+	content, err := MarshalYaml(newEncodeConf(), WithOmitDefaults())
+	assert.NoError(t, err)
+	assert.Equal(t, `Name: app
+Host: 127.0.0.1
+port: 8080
+Timeout: 1.5s
+Labels:
+  env: prod
+  zone: z1
+Upstreams:
+- Addr: 10.0.0.1:80
+- Addr: 10.0.0.2:80
+  Weight: 2
+Count: "10"
+`, string(content))
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		marshal   func(v any, opts ...MarshalOption) ([]byte, error)
+		unmarshal func(content []byte, v any, opts ...UnmarshalOption) error
+	}{
+		{
+			name:      "json",
+			marshal:   MarshalJson,
+			unmarshal: UnmarshalJsonBytes,
+		},
+		{
+			name:      "yaml",
+			marshal:   MarshalYaml,
+			unmarshal: UnmarshalYamlBytes,
+		},
+		{
+			name:      "toml",
+			marshal:   MarshalToml,
+			unmarshal: UnmarshalTomlBytes,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, opts := range [][]MarshalOption{nil, {WithOmitDefaults()}} {
+				expect := newEncodeConf()
+				expect.Backup = &encodeUpstream{Addr: "10.0.0.3:80", Weight: 1}
+				content, err := test.marshal(expect, opts...)
+				assert.NoError(t, err)
+
+				var actual encodeConf
+				assert.NoError(t, test.unmarshal(content, &actual), string(content))
+				expect.Ignored = ""
+				expect.Other = ""
+				assert.Equal(t, expect, actual)
+			}
+		})
+	}
+}
+
+func TestMarshalNilElements(t *testing.T) {
+	type conf struct {
+		Peers map[string]*encodeUpstream
+		Tags  map[string]any
+	}
+
+	expect := conf{
+		Peers: map[string]*encodeUpstream{
+			"a": {Addr: "10.0.0.1:80", Weight: 1},
+			"b": nil,
+		},
+		Tags: map[string]any{
+			"x": "y",
+			"z": nil,
+		},
+	}
+	content, err := MarshalToml(expect)
+	assert.NoError(t, err)
+
+	var actual conf
+	assert.NoError(t, UnmarshalTomlBytes(content, &actual), string(content))
+	delete(expect.Peers, "b")
+	delete(expect.Tags, "z")
+	assert.Equal(t, expect, actual)
+
+	_, err = MarshalToml(struct {
+		Upstreams []*encodeUpstream
+	}{
+		Upstreams: []*encodeUpstream{{Addr: "10.0.0.1:80"}, nil},
+	})
+	assert.ErrorIs(t, err, errNilElement)
+}
@@ -0,0 +1,259 @@
+package mapping
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/tp-life/utils/proc"
+)
+
+const requiredConstraint = "required"
+
+type (
+	// A FieldError describes an error of a field, it's returned in FieldErrors
+	// if the Unmarshaler is created with WithAggregatedErrors.
+	FieldError struct {
+		// Path is the full key path of the field, like Services[2].Timeout.
+		Path string
+		// Expected is the type of the field, like int, duration or []string.
+		Expected string
+		// Actual is the type of the value, like string, number, object or array,
+		// it's empty if the value is missing.
+		Actual string
+		// Constraint is the violated constraint, like required, range=[1:10] or options=a|b.
+		Constraint string
+		// Line and Column are the position of the value in the source, 0 if unknown.
+		// They're available on unmarshaling yaml and toml contents.
+		Line   int
+		Column int
+		// Err is the underlying error.
+		Err error
+	}
+
+	// FieldErrors is the list of all the field errors found on unmarshaling.
+	FieldErrors []*FieldError
+)
+
+func (e *FieldError) Error() string {
+	var buf strings.Builder
+	buf.WriteString(e.Path)
+	if e.Line > 0 {
+		fmt.Fprintf(&buf, " (line %d, column %d)", e.Line, e.Column)
+	}
+	buf.WriteString(": ")
+	buf.WriteString(e.Err.Error())
+
+	var details []string
+	if len(e.Expected) > 0 {
+		details = append(details, "expect "+e.Expected)
+	}
+	if len(e.Actual) > 0 {
+		details = append(details, "actual "+e.Actual)
+	}
+	if len(e.Constraint) > 0 {
+		details = append(details, "constraint "+e.Constraint)
+	}
+	if len(details) > 0 {
+		buf.WriteString(" [")
+		buf.WriteString(strings.Join(details, ", "))
+		buf.WriteByte(']')
+	}
+
+	return buf.String()
+}
+
+// Unwrap returns the underlying error.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Error returns the errors in lines.
+func (e FieldErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, fe := range e {
+		msgs = append(msgs, fe.Error())
+	}
+
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns the underlying errors, so that errors.Is and errors.As work on them.
+func (e FieldErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, fe := range e {
+		errs = append(errs, fe)
+	}
+
+	return errs
+}
+
+// locate fills the positions of the errors, missing fields are located at their nearest parents.
+func (e FieldErrors) locate(positions map[string]position) {
+	for _, fe := range e {
+		for path := normalizePath(fe.Path); len(path) > 0; path = parentPath(path) {
+			pos, ok := positions[path]
+			if !ok {
+				pos, ok = positions[strings.ToLower(path)]
+			}
+			if ok {
+				fe.Line, fe.Column = pos.line, pos.column
+				break
+			}
+		}
+	}
+}
+
+// collectErrors appends err to errs as the error of name, returns false if the errors are not aggregated.
+func (u *Unmarshaler) collectErrors(errs *FieldErrors, name string, err error) bool {
+	if !u.opts.aggregateErrors {
+		return false
+	}
+
+	var fes FieldErrors
+	if errors.As(err, &fes) {
+		*errs = append(*errs, fes...)
+	} else {
+		*errs = append(*errs, &FieldError{
+			Path: name,
+			Err:  err,
+		})
+	}
+
+	return true
+}
+
+// newFieldErrors returns the field errors of err, which is returned on processing field.
+func (u *Unmarshaler) newFieldErrors(field reflect.StructField, m valuerWithParent,
+	fullName string, err error) FieldErrors {
+	var fes FieldErrors
+	if errors.As(err, &fes) {
+		return fes
+	}
+
+	key, opts, perr := u.parseOptionsWithContext(field, m, fullName)
+	if perr != nil || field.Anonymous {
+		return FieldErrors{{
+			Path: fullName,
+			Err:  err,
+		}}
+	}
+
+	fe := &FieldError{
+		Path:     join(fullName, key),
+		Expected: docTypeName(field.Type),
+		Err:      err,
+	}
+
+	canonicalKey := key
+	if u.opts.canonicalKey != nil {
+		canonicalKey = u.opts.canonicalKey(key)
+	}
+	mapValue, hasValue := getValue(createValuer(m, opts), canonicalKey, u.opts.opaqueKeys)
+	if opts != nil && len(opts.EnvVar) > 0 {
+		if envVal := proc.Env(opts.EnvVar); len(envVal) > 0 {
+			mapValue, hasValue = envVal, true
+		}
+	}
+
+	var ce *constraintError
+	switch {
+	case errors.As(err, &ce):
+		fe.Constraint = ce.constraint
+		if hasValue && mapValue != nil {
+			fe.Actual = valueTypeName(mapValue)
+		}
+	case !hasValue:
+		fe.Constraint = requiredConstraint
+	case mapValue != nil:
+		fe.Actual = valueTypeName(mapValue)
+		if opts == nil {
+			break
+		}
+		if opts.Range != nil && errors.Is(err, errNumberRange) {
+			fe.Constraint = rangeOption + equalToken + opts.Range.String()
+		} else if options := opts.options(); len(options) > 0 &&
+			validateValueInOptions(mapValue, options) != nil {
+			fe.Constraint = optionsOption + equalToken + strings.Join(options, optionSeparator)
+		}
+	default:
+		fe.Actual = "null"
+	}
+
+	return FieldErrors{fe}
+}
+
+// normalizePath converts the map keys in path to the dotted form, like Labels[env] to Labels.env,
+// because the positions are keyed by the dotted paths.
+func normalizePath(path string) string {
+	var buf strings.Builder
+	for {
+		start := strings.IndexByte(path, '[')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(path[start:], ']')
+		if end < 0 {
+			break
+		}
+
+		end += start
+		buf.WriteString(path[:start])
+		if key := path[start+1 : end]; isIndex(key) {
+			buf.WriteString(path[start : end+1])
+		} else {
+			buf.WriteByte(delimiter)
+			buf.WriteString(key)
+		}
+		path = path[end+1:]
+	}
+	buf.WriteString(path)
+
+	return buf.String()
+}
+
+func isIndex(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func parentPath(path string) string {
+	if strings.HasSuffix(path, "]") {
+		if pos := strings.LastIndexByte(path, '['); pos >= 0 {
+			return path[:pos]
+		}
+	}
+	if pos := strings.LastIndexByte(path, delimiter); pos >= 0 {
+		return path[:pos]
+	}
+
+	return ""
+}
+
+func valueTypeName(v any) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case json.Number:
+		return numberTypeString
+	case bool:
+		return "bool"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	default:
+		return reflect.TypeOf(v).String()
+	}
+}
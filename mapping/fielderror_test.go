@@ -0,0 +1,168 @@
+package mapping
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	aggregatedService struct {
+		Name    string
+		Port    int           `json:",range=[1:65535]"`
+		Timeout time.Duration `json:",optional"`
+	}
+
+	aggregatedConfig struct {
+		Name     string
+		Mode     string `json:",options=dev|prod"`
+		Replicas int    `json:",default=1"`
+		Log      struct {
+			Level string
+		}
+		Services []aggregatedService
+		Backends map[string]aggregatedService `json:",optional"`
+	}
+)
+
+func TestUnmarshalAggregatedErrors(t *testing.T) {
+	m := map[string]any{
+		"Mode":     "test",
+		"Replicas": "3",
+		"Log":      map[string]any{},
+		"Services": []any{
+			map[string]any{"Name": "a", "Port": json.Number("80")},
+			map[string]any{"Name": "b", "Port": json.Number("0"), "Timeout": "5 secs"},
+			"c",
+		},
+		"Backends": map[string]any{
+			"db": map[string]any{"Port": json.Number("3306")},
+		},
+	}
+
+	var c aggregatedConfig
+	err := UnmarshalJsonMap(m, &c, WithAggregatedErrors())
+	var fes FieldErrors
+	assert.True(t, errors.As(err, &fes))
+
+	byPath := make(map[string]*FieldError)
+	for _, fe := range fes {
+		byPath[fe.Path] = fe
+	}
+	assert.Len(t, byPath, 8)
+	assert.Equal(t, requiredConstraint, byPath["Name"].Constraint)
+	assert.Equal(t, "options=dev|prod", byPath["Mode"].Constraint)
+	assert.Equal(t, "string", byPath["Mode"].Actual)
+	assert.Equal(t, "int", byPath["Replicas"].Expected)
+	assert.Equal(t, "string", byPath["Replicas"].Actual)
+	assert.Equal(t, requiredConstraint, byPath["Log.Level"].Constraint)
+	assert.Equal(t, "range=[1:65535]", byPath["Services[1].Port"].Constraint)
+	assert.ErrorIs(t, byPath["Services[1].Port"], errNumberRange)
+	assert.Equal(t, "duration", byPath["Services[1].Timeout"].Expected)
+	assert.Equal(t, "string", byPath["Services[1].Timeout"].Actual)
+	assert.Equal(t, "aggregatedService", byPath["Services[2]"].Expected)
+	assert.Equal(t, "string", byPath["Services[2]"].Actual)
+	assert.Equal(t, requiredConstraint, byPath["Backends[db].Name"].Constraint)
+	assert.Contains(t, err.Error(), "Services[1].Port: wrong number range setting [expect int, actual number, constraint range=[1:65535]]")
+
+	// without the option, it stops at the first error
+	err = UnmarshalJsonMap(m, &c)
+	assert.False(t, errors.As(err, &fes))
+	assert.NotNil(t, err)
+}
+
+func TestUnmarshalYamlAggregatedErrors(t *testing.T) {
+	const content = `Name: app
+Mode: prod
+Log:
+  Level: info
+Services:
+  - Name: a
+    Port: 80
+  - Name: b
+    Port: 70000
+    Timeout: 5 secs
+`
+
+	var c aggregatedConfig
+	err := UnmarshalYamlBytes([]byte(content), &c, WithAggregatedErrors())
+	var fes FieldErrors
+	assert.True(t, errors.As(err, &fes))
+	assert.Len(t, fes, 2)
+	for _, fe := range fes {
+		switch fe.Path {
+		case "Services[1].Port":
+			assert.Equal(t, 9, fe.Line)
+			assert.Equal(t, 5, fe.Column)
+		case "Services[1].Timeout":
+			assert.Equal(t, 10, fe.Line)
+		default:
+			t.Errorf("unexpected error: %v", fe)
+		}
+	}
+}
+
+func TestUnmarshalYamlAggregatedErrorsMissing(t *testing.T) {
+	const content = `Mode: prod
+Log: {}
+Services: []
+`
+
+	var c aggregatedConfig
+	err := UnmarshalYamlBytes([]byte(content), &c, WithAggregatedErrors())
+	var fes FieldErrors
+	assert.True(t, errors.As(err, &fes))
+	assert.Len(t, fes, 2)
+	assert.Equal(t, "Name", fes[0].Path)
+	assert.Equal(t, 0, fes[0].Line)
+	// missing fields are located at their parents
+	assert.Equal(t, "Log.Level", fes[1].Path)
+	assert.Equal(t, 2, fes[1].Line)
+}
+
+func TestUnmarshalTomlAggregatedErrors(t *testing.T) {
+	const content = `Name = "app"
+Mode = "stage"
+
+[Log]
+Level = "info"
+
+[[Services]]
+Name = "a"
+Port = 80
+
+[[Services]]
+Name = "b"
+Port = 0
+
+[Backends.db]
+Port = 3306
+`
+
+	var c aggregatedConfig
+	err := UnmarshalTomlBytes([]byte(content), &c, WithAggregatedErrors())
+	var fes FieldErrors
+	assert.True(t, errors.As(err, &fes))
+	assert.Len(t, fes, 3)
+
+	lines := make(map[string]int)
+	for _, fe := range fes {
+		lines[fe.Path] = fe.Line
+	}
+	assert.Equal(t, map[string]int{
+		"Mode":              2,
+		"Services[1].Port":  13,
+		"Backends[db].Name": 15,
+	}, lines)
+}
+
+func TestNormalizePath(t *testing.T) {
+	assert.Equal(t, "a[1].b.key.c", normalizePath("a[1].b[key].c"))
+	assert.Equal(t, "a[", normalizePath("a["))
+	assert.Equal(t, "", parentPath("a"))
+	assert.Equal(t, "a[1]", parentPath("a[1].b"))
+	assert.Equal(t, "a", parentPath("a[1]"))
+}
@@ -8,13 +8,14 @@ type (
 	// use context and OptionalDep option to determine the value of Optional
 	// nothing to do with context.Context
 	fieldOptionsWithContext struct {
-		Inherit    bool
-		FromString bool
-		Optional   bool
-		Options    []string
-		Default    string
-		EnvVar     string
-		Range      *numberRange
+		Inherit     bool
+		FromString  bool
+		Optional    bool
+		Options     []string
+		Default     string
+		EnvVar      string
+		Range       *numberRange
+		Constraints *constraints
 	}
 
 	fieldOptions struct {
@@ -103,10 +104,11 @@ func (o *fieldOptions) toOptionsWithContext(key string, m Valuer, fullName strin
 	}
 
 	return &fieldOptionsWithContext{
-		FromString: o.FromString,
-		Optional:   optional,
-		Options:    o.Options,
-		Default:    o.Default,
-		EnvVar:     o.EnvVar,
+		FromString:  o.FromString,
+		Optional:    optional,
+		Options:     o.Options,
+		Default:     o.Default,
+		EnvVar:      o.EnvVar,
+		Constraints: o.Constraints,
 	}, nil
 }
@@ -0,0 +1,191 @@
+package mapping
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2/unstable"
+	"github.com/tp-life/utils/encoding"
+	"gopkg.in/yaml.v3"
+)
+
+const yamlMergeKey = "<<"
+
+// position is the position of a key in the source, keyed by the full key path.
+type position struct {
+	line   int
+	column int
+}
+
+// LocateErrors fills the positions of the field errors in err with content in format,
+// like UnmarshalYamlBytes and UnmarshalTomlBytes do. It's used when content is converted
+// before unmarshaling, only yaml and toml are supported, err is returned as is for other formats.
+func LocateErrors(format encoding.Format, content []byte, err error) error {
+	switch format {
+	case encoding.FormatYaml:
+		return locateYamlErrors(content, err)
+	case encoding.FormatToml:
+		return locateTomlErrors(content, err)
+	default:
+		return err
+	}
+}
+
+// locateTomlErrors fills the positions of the field errors in err with the toml content.
+func locateTomlErrors(content []byte, err error) error {
+	var fes FieldErrors
+	if errors.As(err, &fes) {
+		fes.locate(tomlPositions(content))
+	}
+
+	return err
+}
+
+// locateYamlErrors fills the positions of the field errors in err with the yaml content.
+func locateYamlErrors(content []byte, err error) error {
+	var fes FieldErrors
+	if errors.As(err, &fes) {
+		fes.locate(yamlPositions(content))
+	}
+
+	return err
+}
+
+func addPosition(positions map[string]position, path string, pos position) {
+	if _, ok := positions[path]; !ok {
+		positions[path] = pos
+	}
+	if lower := strings.ToLower(path); lower != path {
+		if _, ok := positions[lower]; !ok {
+			positions[lower] = pos
+		}
+	}
+}
+
+func tomlPositions(content []byte) map[string]position {
+	positions := make(map[string]position)
+	// the last indexes of the array tables
+	arrays := make(map[string]int)
+	var p unstable.Parser
+	p.Reset(content)
+
+	var table string
+	for p.NextExpression() {
+		expr := p.Expression()
+		switch expr.Kind {
+		case unstable.Table, unstable.ArrayTable:
+			table = ""
+			it := expr.Key()
+			for it.Next() {
+				key := it.Node()
+				table = join(table, string(key.Data))
+				last := it.IsLast()
+				if last && expr.Kind == unstable.ArrayTable {
+					idx, ok := arrays[table]
+					if ok {
+						idx++
+					}
+					arrays[table] = idx
+					addPosition(positions, table, tomlPosition(&p, key))
+					table = fmt.Sprintf("%s[%d]", table, idx)
+				} else if idx, ok := arrays[table]; ok {
+					table = fmt.Sprintf("%s[%d]", table, idx)
+				}
+				addPosition(positions, table, tomlPosition(&p, key))
+			}
+		case unstable.KeyValue:
+			addTomlKeyValue(&p, positions, table, expr)
+		}
+	}
+
+	return positions
+}
+
+func addTomlKeyValue(p *unstable.Parser, positions map[string]position, prefix string, expr *unstable.Node) {
+	path := prefix
+	var pos position
+	it := expr.Key()
+	for it.Next() {
+		key := it.Node()
+		path = join(path, string(key.Data))
+		pos = tomlPosition(p, key)
+		addPosition(positions, path, pos)
+	}
+
+	// elements of inline tables and arrays are located by their keys
+	value := expr.Value()
+	switch value.Kind {
+	case unstable.InlineTable:
+		children := value.Children()
+		for children.Next() {
+			addTomlKeyValue(p, positions, path, children.Node())
+		}
+	case unstable.Array:
+		var i int
+		children := value.Children()
+		for children.Next() {
+			child := children.Node()
+			elem := fmt.Sprintf("%s[%d]", path, i)
+			addPosition(positions, elem, pos)
+			if child.Kind == unstable.InlineTable {
+				fields := child.Children()
+				for fields.Next() {
+					addTomlKeyValue(p, positions, elem, fields.Node())
+				}
+			}
+			i++
+		}
+	}
+}
+
+func tomlPosition(p *unstable.Parser, node *unstable.Node) position {
+	shape := p.Shape(node.Raw)
+	return position{
+		line:   shape.Start.Line,
+		column: shape.Start.Column,
+	}
+}
+
+func yamlPositions(content []byte) map[string]position {
+	positions := make(map[string]position)
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return positions
+	}
+
+	addYamlNode(positions, "", &root)
+	return positions
+}
+
+func addYamlNode(positions map[string]position, path string, node *yaml.Node) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			addYamlNode(positions, path, child)
+		}
+	case yaml.AliasNode:
+		if node.Alias != nil {
+			addYamlNode(positions, path, node.Alias)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Value == yamlMergeKey {
+				addYamlNode(positions, path, value)
+				continue
+			}
+
+			pos := position{line: key.Line, column: key.Column}
+			childPath := join(path, key.Value)
+			addPosition(positions, childPath, pos)
+			addYamlNode(positions, childPath, value)
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			elem := fmt.Sprintf("%s[%d]", path, i)
+			addPosition(positions, elem, position{line: child.Line, column: child.Column})
+			addYamlNode(positions, elem, child)
+		}
+	}
+}
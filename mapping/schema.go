@@ -0,0 +1,771 @@
+package mapping
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	descTagKey      = "desc"
+	jsonSchemaDraft = "http://json-schema.org/draft-07/schema#"
+	durationPattern = `^(-?([0-9]+(\.[0-9]*)?|\.[0-9]+)(ns|us|µs|ms|s|m|h))+$`
+)
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	// formatSamples are the sample values of the strings with the formats.
+	formatSamples = map[string]string{
+		emailFormat:    "user@example.com",
+		urlFormat:      "http://localhost",
+		ipFormat:       "127.0.0.1",
+		ipv4Format:     "127.0.0.1",
+		ipv6Format:     "::1",
+		cidrFormat:     "127.0.0.0/8",
+		hostPortFormat: "localhost:8080",
+		durationFormat: "1s",
+	}
+)
+
+const (
+	docString docType = iota
+	docBool
+	docInteger
+	docNumber
+	docDuration
+	docTime
+	docArray
+	docMap
+	docObject
+	docAny
+)
+
+type (
+	docType int
+
+	// fieldDoc describes a config field, parsed from the tags like the Unmarshaler does.
+	fieldDoc struct {
+		key      string
+		typ      docType
+		typeName string
+		required bool
+		optional bool
+		defVal   string
+		options  []string
+		rng      *numberRange
+		rules    *constraints
+		env      string
+		desc     string
+		// elem is the element of arrays and maps
+		elem *fieldDoc
+		// fields are the fields of objects
+		fields []*fieldDoc
+	}
+)
+
+// JsonSchema returns the JSON Schema of the config type of v, which can be used by IDEs to
+// validate and complete the yaml or json config files. The descriptions are read from the desc tags.
+func JsonSchema(v any) ([]byte, error) {
+	doc, err := describe(reflect.TypeOf(v))
+	if err != nil {
+		return nil, err
+	}
+
+	schema := doc.schema()
+	schema["$schema"] = jsonSchemaDraft
+	if name := Deref(reflect.TypeOf(v)).Name(); len(name) > 0 {
+		schema["title"] = name
+	}
+
+	return marshalIndent(schema)
+}
+
+// MarkdownDoc returns the reference table of the config type of v in Markdown,
+// nested fields are listed with their full paths, like Log.Level.
+func MarkdownDoc(v any) (string, error) {
+	doc, err := describe(reflect.TypeOf(v))
+	if err != nil {
+		return "", err
+	}
+
+	var buf strings.Builder
+	buf.WriteString("| Key | Type | Required | Default | Constraints | Env | Description |\n")
+	buf.WriteString("| --- | --- | --- | --- | --- | --- | --- |\n")
+	doc.writeMarkdown(&buf, "")
+	return buf.String(), nil
+}
+
+// SampleYaml returns a sample yaml config of the config type of v, filled with
+// the default values, and each field is commented with its type and constraints.
+func SampleYaml(v any) (string, error) {
+	doc, err := describe(reflect.TypeOf(v))
+	if err != nil {
+		return "", err
+	}
+
+	var buf strings.Builder
+	doc.writeYamlFields(&buf, 0)
+	return buf.String(), nil
+}
+
+// SampleToml returns a sample toml config of the config type of v, filled with
+// the default values, and each field is commented with its type and constraints.
+func SampleToml(v any) (string, error) {
+	doc, err := describe(reflect.TypeOf(v))
+	if err != nil {
+		return "", err
+	}
+
+	var buf strings.Builder
+	doc.writeTomlTable(&buf, "")
+	return buf.String(), nil
+}
+
+func describe(tp reflect.Type) (*fieldDoc, error) {
+	if tp == nil || Deref(tp).Kind() != reflect.Struct {
+		return nil, errValueNotStruct
+	}
+
+	return describeType(tp, nil)
+}
+
+func describeType(tp reflect.Type, visiting map[reflect.Type]bool) (*fieldDoc, error) {
+	tp = Deref(tp)
+	doc := &fieldDoc{
+		typeName: docTypeName(tp),
+	}
+
+	switch {
+	case tp == durationType:
+		doc.typ = docDuration
+	case tp == timeType:
+		doc.typ = docTime
+	case implementsTextUnmarshaler(tp):
+		// the types like net.IP are unmarshaled from strings
+		doc.typ = docString
+	default:
+		switch tp.Kind() {
+		case reflect.String:
+			doc.typ = docString
+		case reflect.Bool:
+			doc.typ = docBool
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			doc.typ = docInteger
+		case reflect.Float32, reflect.Float64:
+			doc.typ = docNumber
+		case reflect.Array, reflect.Slice:
+			elem, err := describeType(tp.Elem(), visiting)
+			if err != nil {
+				return nil, err
+			}
+			doc.typ = docArray
+			doc.elem = elem
+		case reflect.Map:
+			elem, err := describeType(tp.Elem(), visiting)
+			if err != nil {
+				return nil, err
+			}
+			doc.typ = docMap
+			doc.elem = elem
+		case reflect.Struct:
+			// recursive types are documented as objects without fields
+			if visiting[tp] {
+				doc.typ = docObject
+				return doc, nil
+			}
+			if visiting == nil {
+				visiting = make(map[reflect.Type]bool)
+			}
+			visiting[tp] = true
+			defer delete(visiting, tp)
+
+			fields, err := describeFields(tp, visiting)
+			if err != nil {
+				return nil, err
+			}
+			doc.typ = docObject
+			doc.fields = fields
+		default:
+			doc.typ = docAny
+		}
+	}
+
+	return doc, nil
+}
+
+func describeFields(tp reflect.Type, visiting map[reflect.Type]bool) ([]*fieldDoc, error) {
+	var fields []*fieldDoc
+	for i := 0; i < tp.NumField(); i++ {
+		field := tp.Field(i)
+		// unexported anonymous structs are flattened as well, like the Unmarshaler does
+		if (!field.IsExported() && !field.Anonymous) || usingDifferentKeys(jsonTagKey, field) {
+			continue
+		}
+
+		key, opts, err := parseKeyAndOptions(jsonTagKey, field)
+		if err != nil {
+			return nil, err
+		}
+		if key == ignoreKey {
+			continue
+		}
+
+		// anonymous structs are flattened, like the Unmarshaler does
+		if field.Anonymous && Deref(field.Type).Kind() == reflect.Struct {
+			children, err := describeFields(Deref(field.Type), visiting)
+			if err != nil {
+				return nil, err
+			}
+			if opts != nil && opts.Optional {
+				for _, child := range children {
+					child.required = false
+				}
+			}
+			fields = append(fields, children...)
+			continue
+		}
+
+		doc, err := describeType(field.Type, visiting)
+		if err != nil {
+			return nil, err
+		}
+
+		doc.key = key
+		doc.desc = field.Tag.Get(descTagKey)
+		if opts != nil {
+			doc.optional = opts.Optional
+			doc.defVal = opts.Default
+			doc.options = opts.Options
+			doc.rng = opts.Range
+			doc.rules = opts.Constraints
+			doc.env = opts.EnvVar
+		}
+		if doc.required, err = fieldRequired(field, opts); err != nil {
+			return nil, err
+		}
+		fields = append(fields, doc)
+	}
+
+	return fields, nil
+}
+
+// fieldRequired reports whether the field must be set, the same as the Unmarshaler checks.
+func fieldRequired(field reflect.StructField, opts *fieldOptions) (bool, error) {
+	if opts != nil && (opts.Optional || len(opts.Default) > 0 || len(opts.EnvVar) > 0) {
+		return false, nil
+	}
+
+	ft := Deref(field.Type)
+	if ft.Kind() == reflect.Struct && ft != timeType {
+		return structValueRequired(jsonTagKey, ft)
+	}
+
+	return true, nil
+}
+
+func docTypeName(tp reflect.Type) string {
+	tp = Deref(tp)
+	switch {
+	case tp == durationType:
+		return "duration"
+	case tp == timeType:
+		return "time"
+	case implementsTextUnmarshaler(tp) && len(tp.Name()) > 0:
+		return tp.Name()
+	}
+
+	switch tp.Kind() {
+	case reflect.Array, reflect.Slice:
+		return "[]" + docTypeName(tp.Elem())
+	case reflect.Map:
+		return "map[" + docTypeName(tp.Key()) + "]" + docTypeName(tp.Elem())
+	case reflect.Struct:
+		if len(tp.Name()) > 0 {
+			return tp.Name()
+		}
+		return "object"
+	case reflect.Interface:
+		return "any"
+	default:
+		return tp.Kind().String()
+	}
+}
+
+func (d *fieldDoc) schema() map[string]any {
+	schema := make(map[string]any)
+	switch d.typ {
+	case docString:
+		schema["type"] = "string"
+	case docBool:
+		schema["type"] = "boolean"
+	case docInteger:
+		schema["type"] = "integer"
+	case docNumber:
+		schema["type"] = "number"
+	case docDuration:
+		schema["type"] = "string"
+		schema["pattern"] = durationPattern
+	case docTime:
+		schema["type"] = "string"
+		schema["format"] = "date-time"
+	case docArray:
+		schema["type"] = "array"
+		schema["items"] = d.elem.schema()
+	case docMap:
+		schema["type"] = "object"
+		schema["additionalProperties"] = d.elem.schema()
+	case docObject:
+		schema["type"] = "object"
+		if d.fields == nil {
+			break
+		}
+
+		properties := make(map[string]any)
+		var required []string
+		for _, field := range d.fields {
+			properties[field.key] = field.schema()
+			if field.required {
+				required = append(required, field.key)
+			}
+		}
+		schema["properties"] = properties
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+	}
+
+	if len(d.desc) > 0 {
+		schema["description"] = d.desc
+	}
+	if len(d.defVal) > 0 {
+		schema["default"] = d.typedValue(d.defVal)
+	}
+	if len(d.options) > 0 {
+		enum := make([]any, 0, len(d.options))
+		for _, opt := range d.options {
+			enum = append(enum, d.typedValue(opt))
+		}
+		schema["enum"] = enum
+	}
+	if d.rng != nil && (d.typ == docInteger || d.typ == docNumber) {
+		if d.rng.left > -math.MaxFloat64 {
+			if d.rng.leftInclude {
+				schema["minimum"] = d.rng.left
+			} else {
+				schema["exclusiveMinimum"] = d.rng.left
+			}
+		}
+		if d.rng.right < math.MaxFloat64 {
+			if d.rng.rightInclude {
+				schema["maximum"] = d.rng.right
+			} else {
+				schema["exclusiveMaximum"] = d.rng.right
+			}
+		}
+	}
+	if d.rules != nil {
+		d.rules.addSchema(schema, d.typ)
+	}
+
+	return schema
+}
+
+// addSchema adds the keywords of the constraints to the schema of the field in typ.
+func (c *constraints) addSchema(schema map[string]any, typ docType) {
+	if c.Len != nil {
+		var prefix string
+		switch typ {
+		case docString:
+			prefix = "Length"
+		case docArray:
+			prefix = "Items"
+		case docMap, docObject:
+			prefix = "Properties"
+		}
+		if len(prefix) > 0 {
+			if c.Len.left > -math.MaxFloat64 {
+				left := math.Ceil(c.Len.left)
+				if !c.Len.leftInclude && left == c.Len.left {
+					left++
+				}
+				schema["min"+prefix] = int(left)
+			}
+			if c.Len.right < math.MaxFloat64 {
+				right := math.Floor(c.Len.right)
+				if !c.Len.rightInclude && right == c.Len.right {
+					right--
+				}
+				schema["max"+prefix] = int(right)
+			}
+		}
+	}
+	if c.Regex != nil {
+		schema["pattern"] = c.Regex.String()
+	}
+	switch c.Format {
+	case emailFormat, ipv4Format, ipv6Format:
+		schema["format"] = c.Format
+	case urlFormat:
+		schema["format"] = "uri"
+	case durationFormat:
+		schema["pattern"] = durationPattern
+	}
+	if c.Unique {
+		schema["uniqueItems"] = true
+	}
+}
+
+// typedValue converts the value in tags to the json value of the field type.
+func (d *fieldDoc) typedValue(val string) any {
+	switch d.typ {
+	case docBool:
+		if b, err := strconv.ParseBool(val); err == nil {
+			return b
+		}
+	case docInteger, docNumber:
+		if _, err := strconv.ParseFloat(val, 64); err == nil {
+			return json.Number(val)
+		}
+	case docArray:
+		items := parseOptions(val)
+		list := make([]any, 0, len(items))
+		for _, item := range items {
+			list = append(list, d.elem.typedValue(item))
+		}
+		return list
+	}
+
+	return val
+}
+
+// sampleValue returns the json value used in the samples, the default value or the zero value,
+// the values are chosen to satisfy the options, ranges, lengths of strings and formats,
+// so that the samples can be loaded. The regexes and the lengths of arrays and maps are not considered.
+func (d *fieldDoc) sampleValue() any {
+	if len(d.defVal) > 0 {
+		return d.typedValue(d.defVal)
+	}
+	if len(d.options) > 0 {
+		return d.typedValue(d.options[0])
+	}
+
+	switch d.typ {
+	case docBool:
+		return false
+	case docInteger, docNumber:
+		return json.Number(strconv.FormatFloat(sampleInRange(d.rng, 1), 'f', -1, 64))
+	case docDuration:
+		return time.Duration(sampleInRange(d.rng, float64(time.Second))).String()
+	case docTime:
+		return time.Time{}.Format(time.RFC3339)
+	case docArray:
+		return []any{}
+	case docMap, docObject, docAny:
+		return map[string]any{}
+	default:
+		return d.sampleString()
+	}
+}
+
+func (d *fieldDoc) sampleString() string {
+	if d.rules == nil {
+		return ""
+	}
+	if val, ok := formatSamples[d.rules.Format]; ok {
+		return val
+	}
+	if d.rules.Len != nil {
+		return strings.Repeat("x", int(sampleInRange(d.rules.Len, 1)))
+	}
+
+	return ""
+}
+
+// sampleInRange returns 0 if it's in r, otherwise the bound of r, or the value next to the bound by step.
+func sampleInRange(r *numberRange, step float64) float64 {
+	if r == nil || r.contains(0) {
+		return 0
+	}
+
+	var val float64
+	switch {
+	case r.left > -math.MaxFloat64 && r.leftInclude:
+		val = r.left
+	case r.left > -math.MaxFloat64:
+		val = math.Floor(r.left) + step
+		if !r.contains(val) {
+			val = (r.left + r.right) / 2
+		}
+	case r.rightInclude:
+		val = r.right
+	default:
+		val = math.Ceil(r.right) - step
+	}
+
+	return val
+}
+
+func (c *constraints) describe() []string {
+	var items []string
+	if c.Len != nil {
+		items = append(items, lenOption+": "+c.Len.String())
+	}
+	if c.Regex != nil {
+		items = append(items, regexOption+": "+c.Regex.String())
+	}
+	if len(c.Format) > 0 {
+		items = append(items, formatOption+": "+c.Format)
+	}
+	if c.Unique {
+		items = append(items, uniqueOption)
+	}
+	if c.RequiredIf != nil {
+		items = append(items, requiredIfOption+": "+c.RequiredIf.field+conditionSeparator+c.RequiredIf.value)
+	}
+	for _, rule := range c.Compares {
+		items = append(items, rule.op+": "+rule.field)
+	}
+
+	return items
+}
+
+func (d *fieldDoc) constraints() string {
+	var items []string
+	if len(d.options) > 0 {
+		items = append(items, "options: "+strings.Join(d.options, "|"))
+	}
+	if d.rng != nil {
+		if d.typ == docDuration {
+			items = append(items, "range: "+d.rng.durationString())
+		} else {
+			items = append(items, "range: "+d.rng.String())
+		}
+	}
+	if d.rules != nil {
+		items = append(items, d.rules.describe()...)
+	}
+
+	return strings.Join(items, ", ")
+}
+
+// comment returns the description of the field used in the samples.
+func (d *fieldDoc) comment() string {
+	items := []string{d.typeName}
+	if d.required {
+		items = append(items, "required")
+	} else {
+		items = append(items, "optional")
+	}
+	if c := d.constraints(); len(c) > 0 {
+		items = append(items, c)
+	}
+	if len(d.env) > 0 {
+		items = append(items, "env: "+d.env)
+	}
+
+	comment := strings.Join(items, ", ")
+	if len(d.desc) > 0 {
+		comment = d.desc + " (" + comment + ")"
+	}
+
+	return comment
+}
+
+func (d *fieldDoc) writeMarkdown(buf *strings.Builder, prefix string) {
+	for _, field := range d.fields {
+		path := field.key
+		if len(prefix) > 0 {
+			path = prefix + "." + field.key
+		}
+
+		var def string
+		if len(field.defVal) > 0 {
+			def = "`" + field.defVal + "`"
+		}
+		var env string
+		if len(field.env) > 0 {
+			env = "`" + field.env + "`"
+		}
+		fmt.Fprintf(buf, "| %s | %s | %s | %s | %s | %s | %s |\n", escapeMarkdown(path),
+			escapeMarkdown(field.typeName), yesOrNo(field.required), def,
+			escapeMarkdown(field.constraints()), env, escapeMarkdown(field.desc))
+
+		switch {
+		case field.typ == docObject:
+			field.writeMarkdown(buf, path)
+		case field.typ == docArray && field.elem.typ == docObject:
+			field.elem.writeMarkdown(buf, path+"[]")
+		case field.typ == docMap && field.elem.typ == docObject:
+			field.elem.writeMarkdown(buf, path+".<key>")
+		}
+	}
+}
+
+func (d *fieldDoc) writeYamlFields(buf *strings.Builder, indent int) {
+	pad := strings.Repeat("  ", indent)
+	for _, field := range d.fields {
+		fmt.Fprintf(buf, "%s# %s\n", pad, field.comment())
+		switch {
+		case field.typ == docObject && len(field.fields) > 0:
+			fmt.Fprintf(buf, "%s%s:\n", pad, field.key)
+			field.writeYamlFields(buf, indent+1)
+		case field.typ == docArray && field.elem.typ == docObject && len(field.elem.fields) > 0 &&
+			len(field.defVal) == 0:
+			fmt.Fprintf(buf, "%s%s:\n", pad, field.key)
+			var item strings.Builder
+			field.elem.writeYamlFields(&item, indent+2)
+			// turn the first line of the element into a list item
+			lines := strings.SplitAfter(item.String(), "\n")
+			for i, line := range lines {
+				if i == 1 {
+					line = pad + "  - " + strings.TrimPrefix(line, pad+"    ")
+				}
+				buf.WriteString(line)
+			}
+		default:
+			fmt.Fprintf(buf, "%s%s: %s\n", pad, field.key, formatSampleValue(field.sampleValue()))
+		}
+	}
+}
+
+func (d *fieldDoc) writeTomlTable(buf *strings.Builder, table string) {
+	var tables, arrayTables []*fieldDoc
+	for _, field := range d.fields {
+		switch {
+		case field.typ == docObject && len(field.fields) > 0:
+			tables = append(tables, field)
+			continue
+		case field.typ == docArray && field.elem.typ == docObject && len(field.elem.fields) > 0 &&
+			len(field.defVal) == 0:
+			arrayTables = append(arrayTables, field)
+			continue
+		}
+
+		fmt.Fprintf(buf, "# %s\n", field.comment())
+		fmt.Fprintf(buf, "%s = %s\n", tomlKey(field.key), formatSampleValue(field.sampleValue()))
+	}
+
+	for _, field := range tables {
+		name := joinTomlKey(table, field.key)
+		fmt.Fprintf(buf, "\n# %s\n[%s]\n", field.comment(), name)
+		field.writeTomlTable(buf, name)
+	}
+	for _, field := range arrayTables {
+		name := joinTomlKey(table, field.key)
+		fmt.Fprintf(buf, "\n# %s\n[[%s]]\n", field.comment(), name)
+		field.elem.writeTomlTable(buf, name)
+	}
+}
+
+func (r *numberRange) contains(val float64) bool {
+	if val < r.left || val == r.left && !r.leftInclude {
+		return false
+	}
+
+	return val < r.right || val == r.right && r.rightInclude
+}
+
+// durationString returns the range with the bounds formatted as durations, like [1s:1m0s].
+func (r *numberRange) durationString() string {
+	return r.format(func(v float64) string {
+		return time.Duration(v).String()
+	})
+}
+
+func (r *numberRange) String() string {
+	return r.format(func(v float64) string {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	})
+}
+
+func (r *numberRange) format(bound func(float64) string) string {
+	var buf strings.Builder
+	if r.leftInclude {
+		buf.WriteByte('[')
+	} else {
+		buf.WriteByte('(')
+	}
+	if r.left > -math.MaxFloat64 {
+		buf.WriteString(bound(r.left))
+	}
+	buf.WriteByte(':')
+	if r.right < math.MaxFloat64 {
+		buf.WriteString(bound(r.right))
+	}
+	if r.rightInclude {
+		buf.WriteByte(']')
+	} else {
+		buf.WriteByte(')')
+	}
+
+	return buf.String()
+}
+
+// formatSampleValue formats val in json, which is valid in both yaml and toml for the sample values.
+func formatSampleValue(val any) string {
+	content, err := marshalCompact(val)
+	if err != nil {
+		return `""`
+	}
+
+	return string(content)
+}
+
+func marshalCompact(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+func marshalIndent(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func escapeMarkdown(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+func yesOrNo(b bool) string {
+	if b {
+		return "yes"
+	}
+
+	return "no"
+}
+
+func tomlKey(key string) string {
+	for _, r := range key {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return strconv.Quote(key)
+		}
+	}
+
+	return key
+}
+
+func joinTomlKey(table, key string) string {
+	if len(table) == 0 {
+		return tomlKey(key)
+	}
+
+	return table + "." + tomlKey(key)
+}
@@ -0,0 +1,155 @@
+package mapping
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tp-life/utils/encoding"
+)
+
+type (
+	schemaRule struct {
+		Name  string
+		Limit int `json:",range=(0:1000]"`
+	}
+
+	schemaBase struct {
+		ServiceName string `json:",optional" desc:"the service name"`
+	}
+
+	schemaConfig struct {
+		schemaBase
+		Host    string            `json:",default=0.0.0.0"`
+		Port    int               `json:"port"`
+		Mode    string            `json:",default=console,options=[console,file]"`
+		Sampler float64           `json:",default=1.0,range=[0:1]"`
+		Timeout time.Duration     `json:",default=3s"`
+		Verbose bool              `json:",optional"`
+		Hosts   []string          `json:",default=[a,b]"`
+		Labels  map[string]string `json:",optional"`
+		Secret  string            `json:",env=APP_SECRET"`
+		Ignored string            `json:"-"`
+		Log     struct {
+			Level string `json:",default=info,options=debug|info|error"`
+			Path  string `json:",optional"`
+		}
+		Rules []schemaRule `json:",optional"`
+	}
+)
+
+func TestJsonSchema(t *testing.T) {
+	content, err := JsonSchema(schemaConfig{})
+	assert.Nil(t, err)
+
+	var schema map[string]any
+	assert.Nil(t, json.Unmarshal(content, &schema))
+	assert.Equal(t, "http://json-schema.org/draft-07/schema#", schema["$schema"])
+	assert.Equal(t, "schemaConfig", schema["title"])
+	assert.Equal(t, []any{"port"}, schema["required"])
+
+	props := schema["properties"].(map[string]any)
+	assert.NotContains(t, props, "Ignored")
+	assert.Equal(t, map[string]any{"type": "string", "description": "the service name"}, props["ServiceName"])
+	assert.Equal(t, map[string]any{"type": "string", "default": "0.0.0.0"}, props["Host"])
+	assert.Equal(t, map[string]any{"type": "integer"}, props["port"])
+	assert.Equal(t, map[string]any{"type": "string", "default": "console", "enum": []any{"console", "file"}},
+		props["Mode"])
+	assert.Equal(t, map[string]any{"type": "number", "default": 1.0, "minimum": 0.0, "maximum": 1.0},
+		props["Sampler"])
+	assert.Equal(t, "3s", props["Timeout"].(map[string]any)["default"])
+	assert.Equal(t, []any{"a", "b"}, props["Hosts"].(map[string]any)["default"])
+	assert.Equal(t, map[string]any{"type": "string"},
+		props["Labels"].(map[string]any)["additionalProperties"])
+
+	log := props["Log"].(map[string]any)
+	assert.Nil(t, log["required"])
+	assert.Equal(t, []any{"debug", "info", "error"},
+		log["properties"].(map[string]any)["Level"].(map[string]any)["enum"])
+
+	rule := props["Rules"].(map[string]any)["items"].(map[string]any)
+	assert.Equal(t, []any{"Name", "Limit"}, rule["required"])
+	assert.Equal(t, map[string]any{"type": "integer", "exclusiveMinimum": 0.0, "maximum": 1000.0},
+		rule["properties"].(map[string]any)["Limit"])
+
+	_, err = JsonSchema(1)
+	assert.NotNil(t, err)
+}
+
+func TestMarkdownDoc(t *testing.T) {
+	doc, err := MarkdownDoc(&schemaConfig{})
+	assert.Nil(t, err)
+	assert.Contains(t, doc, "| Key | Type | Required | Default | Constraints | Env | Description |\n")
+	assert.Contains(t, doc, "| ServiceName | string | no |  |  |  | the service name |\n")
+	assert.Contains(t, doc, "| port | int | yes |  |  |  |  |\n")
+	assert.Contains(t, doc, "| Mode | string | no | `console` | options: console\\|file |  |  |\n")
+	assert.Contains(t, doc, "| Secret | string | no |  |  | `APP_SECRET` |  |\n")
+	assert.Contains(t, doc, "| Log.Level | string | no | `info` | options: debug\\|info\\|error |  |  |\n")
+	assert.Contains(t, doc, "| Rules[].Limit | int | yes |  | range: (0:1000] |  |  |\n")
+}
+
+func TestSampleYaml(t *testing.T) {
+	sample, err := SampleYaml(schemaConfig{})
+	assert.Nil(t, err)
+	assert.Contains(t, sample, "# the service name (string, optional)\nServiceName: \"\"\n")
+	assert.Contains(t, sample, "# string, optional, options: console|file\nMode: \"console\"\n")
+	assert.Contains(t, sample, "Log:\n  # string, optional, options: debug|info|error\n  Level: \"info\"\n")
+
+	var c schemaConfig
+	assert.Nil(t, UnmarshalYamlBytes([]byte(sample), &c))
+	assert.Equal(t, "0.0.0.0", c.Host)
+	assert.Equal(t, 3*time.Second, c.Timeout)
+	assert.Equal(t, []string{"a", "b"}, c.Hosts)
+	assert.Equal(t, "info", c.Log.Level)
+	assert.Equal(t, []schemaRule{{Limit: 1}}, c.Rules)
+}
+
+func TestSampleConstraints(t *testing.T) {
+	type config struct {
+		Name     string        `json:",len=[3:8]"`
+		Email    string        `json:",format=email"`
+		Addr     string        `json:",format=hostport"`
+		Interval time.Duration `json:",range=(0:1m]"`
+		Delay    time.Duration `json:",range=(0:500ms]"`
+		Start    time.Time
+		Ratio    float64 `json:",range=(0:1)"`
+	}
+
+	samples := []struct {
+		sample func(v any) (string, error)
+		toJson func(content []byte) ([]byte, error)
+	}{
+		{sample: SampleYaml, toJson: encoding.YamlToJson},
+		{sample: SampleToml, toJson: encoding.TomlToJson},
+	}
+	for _, s := range samples {
+		content, err := s.sample(config{})
+		assert.Nil(t, err)
+
+		data, err := s.toJson([]byte(content))
+		assert.Nil(t, err)
+		var c config
+		assert.Nil(t, UnmarshalJsonBytes(data, &c), content)
+		assert.Equal(t, "xxx", c.Name)
+		assert.Equal(t, time.Second, c.Interval)
+		assert.Equal(t, 250*time.Millisecond, c.Delay)
+		assert.Equal(t, 0.5, c.Ratio)
+	}
+}
+
+func TestSampleToml(t *testing.T) {
+	sample, err := SampleToml(schemaConfig{})
+	assert.Nil(t, err)
+	assert.Contains(t, sample, "# duration, optional\nTimeout = \"3s\"\n")
+	assert.Contains(t, sample, "\n[Log]\n")
+	assert.Contains(t, sample, "\n[[Rules]]\n")
+
+	data, err := encoding.TomlToJson([]byte(sample))
+	assert.Nil(t, err)
+	var c schemaConfig
+	assert.Nil(t, UnmarshalJsonBytes(data, &c))
+	assert.Equal(t, 1.0, c.Sampler)
+	assert.Equal(t, "console", c.Mode)
+	assert.Len(t, c.Rules, 1)
+}
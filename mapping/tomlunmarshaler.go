@@ -13,7 +13,7 @@ func UnmarshalTomlBytes(content []byte, v any, opts ...UnmarshalOption) error {
 		return err
 	}
 
-	return UnmarshalJsonBytes(b, v, opts...)
+	return locateTomlErrors(content, UnmarshalJsonBytes(b, v, opts...))
 }
 
 // UnmarshalTomlReader unmarshals TOML from the given io.Reader into the given v.
@@ -1,7 +1,6 @@
 package mapping
 
 import (
-	"encoding"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -50,10 +49,12 @@ type (
 	UnmarshalOption func(*unmarshalOptions)
 
 	unmarshalOptions struct {
-		fillDefault  bool
-		fromString   bool
-		opaqueKeys   bool
-		canonicalKey func(key string) string
+		fillDefault     bool
+		fromString      bool
+		opaqueKeys      bool
+		aggregateErrors bool
+		canonicalKey    func(key string) string
+		typeDecoders    map[reflect.Type]typeDecoder
 	}
 )
 
@@ -178,6 +179,7 @@ func (u *Unmarshaler) fillSlice(fieldType reflect.Type, value reflect.Value, map
 	}
 
 	var valid bool
+	var errs FieldErrors
 	for i := 0; i < refValue.Len(); i++ {
 		ithValue := refValue.Index(i).Interface()
 		if ithValue == nil {
@@ -187,15 +189,41 @@ func (u *Unmarshaler) fillSlice(fieldType reflect.Type, value reflect.Value, map
 		valid = true
 		sliceFullName := fmt.Sprintf("%s[%d]", fullName, i)
 
+		if yes, err := u.processFieldDecoder(baseType, conv.Index(i), ithValue); yes {
+			if err != nil {
+				if u.collectErrors(&errs, sliceFullName, err) {
+					continue
+				}
+
+				return err
+			}
+
+			continue
+		}
+
 		switch dereffedBaseKind {
 		case reflect.Struct:
 			target := reflect.New(dereffedBaseType)
 			val, ok := ithValue.(map[string]any)
 			if !ok {
+				if u.opts.aggregateErrors {
+					errs = append(errs, &FieldError{
+						Path:     sliceFullName,
+						Expected: docTypeName(baseType),
+						Actual:   valueTypeName(ithValue),
+						Err:      errTypeMismatch,
+					})
+					continue
+				}
+
 				return errTypeMismatch
 			}
 
 			if err := u.unmarshal(val, target.Interface(), sliceFullName); err != nil {
+				if u.collectErrors(&errs, sliceFullName, err) {
+					continue
+				}
+
 				return err
 			}
 
@@ -211,6 +239,10 @@ func (u *Unmarshaler) fillSlice(fieldType reflect.Type, value reflect.Value, map
 		}
 	}
 
+	if len(errs) > 0 {
+		return errs
+	}
+
 	if valid {
 		value.Set(conv)
 	}
@@ -228,7 +260,12 @@ func (u *Unmarshaler) fillSliceFromString(fieldType reflect.Type, value reflect.
 		}
 	case string:
 		if err := jsonx.UnmarshalFromString(v, &slice); err != nil {
-			return err
+			// with string values, like the form values, a value that is not a json array
+			// is taken as a slice of one element, like [draft] or a
+			if !u.opts.fromString {
+				return err
+			}
+			slice = []any{v}
 		}
 	default:
 		return errUnsupportedType
@@ -320,11 +357,26 @@ func (u *Unmarshaler) generateMap(keyType, elemType reflect.Type, mapValue any,
 	dereffedElemType := Deref(elemType)
 	dereffedElemKind := dereffedElemType.Kind()
 
+	var errs FieldErrors
 	for _, key := range refValue.MapKeys() {
 		keythValue := refValue.MapIndex(key)
 		keythData := keythValue.Interface()
 		mapFullName := fmt.Sprintf("%s[%s]", fullName, key.String())
 
+		target := reflect.New(elemType).Elem()
+		if yes, err := u.processFieldDecoder(elemType, target, keythData); yes {
+			if err != nil {
+				if u.collectErrors(&errs, mapFullName, err) {
+					continue
+				}
+
+				return emptyValue, err
+			}
+
+			targetValue.SetMapIndex(key, target)
+			continue
+		}
+
 		switch dereffedElemKind {
 		case reflect.Slice:
 			target := reflect.New(dereffedElemType)
@@ -341,6 +393,10 @@ func (u *Unmarshaler) generateMap(keyType, elemType reflect.Type, mapValue any,
 
 			target := reflect.New(dereffedElemType)
 			if err := u.unmarshal(keythMap, target.Interface(), mapFullName); err != nil {
+				if u.collectErrors(&errs, mapFullName, err) {
+					continue
+				}
+
 				return emptyValue, err
 			}
 
@@ -393,6 +449,10 @@ func (u *Unmarshaler) generateMap(keyType, elemType reflect.Type, mapValue any,
 		}
 	}
 
+	if len(errs) > 0 {
+		return emptyValue, errs
+	}
+
 	return targetValue, nil
 }
 
@@ -412,13 +472,14 @@ func (u *Unmarshaler) parseOptionsWithContext(field reflect.StructField, m Value
 			// need to create a new fieldOption, because the original one is shared through cache.
 			options = &fieldOptions{
 				fieldOptionsWithContext: fieldOptionsWithContext{
-					Inherit:    options.Inherit,
-					FromString: options.FromString,
-					Optional:   options.Optional,
-					Options:    options.Options,
-					Default:    options.Default,
-					EnvVar:     options.EnvVar,
-					Range:      options.Range,
+					Inherit:     options.Inherit,
+					FromString:  options.FromString,
+					Optional:    options.Optional,
+					Options:     options.Options,
+					Default:     options.Default,
+					EnvVar:      options.EnvVar,
+					Range:       options.Range,
+					Constraints: options.Constraints,
 				},
 				OptionalDep: u.opts.canonicalKey(options.OptionalDep),
 			}
@@ -472,11 +533,8 @@ func (u *Unmarshaler) processAnonymousFieldRequired(field reflect.StructField, v
 
 	switch derefedFieldType.Kind() {
 	case reflect.Struct:
-		for i := 0; i < derefedFieldType.NumField(); i++ {
-			if err := u.processField(derefedFieldType.Field(i), indirectValue.Field(i),
-				m, fullName); err != nil {
-				return err
-			}
+		if err := u.processFields(derefedFieldType, indirectValue, m, fullName); err != nil {
+			return err
 		}
 	default:
 		if err := u.processNamedField(field, indirectValue, m, fullName); err != nil {
@@ -541,6 +599,74 @@ func (u *Unmarshaler) processField(field reflect.StructField, value reflect.Valu
 	return u.processNamedField(field, value, m, fullName)
 }
 
+// processFields processes the fields of the struct value, stops at the first error,
+// or collects all the errors into FieldErrors if aggregateErrors is set.
+func (u *Unmarshaler) processFields(tp reflect.Type, value reflect.Value,
+	m valuerWithParent, fullName string) error {
+	var errs FieldErrors
+	var failed map[int]bool
+	for i := 0; i < tp.NumField(); i++ {
+		field := tp.Field(i)
+		if err := u.processField(field, value.Field(i), m, fullName); err != nil {
+			if !u.opts.aggregateErrors {
+				return err
+			}
+
+			errs = append(errs, u.newFieldErrors(field, m, fullName, err)...)
+			if failed == nil {
+				failed = make(map[int]bool)
+			}
+			failed[i] = true
+		}
+	}
+
+	// cross-field rules are validated after all the fields are set
+	if !u.opts.fillDefault {
+		for i := 0; i < tp.NumField(); i++ {
+			if failed[i] {
+				continue
+			}
+
+			field := tp.Field(i)
+			if err := u.processCrossFields(tp, value, field, m, fullName); err != nil {
+				if !u.opts.aggregateErrors {
+					return err
+				}
+
+				errs = append(errs, u.newFieldErrors(field, m, fullName, err)...)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (u *Unmarshaler) processCrossFields(tp reflect.Type, value reflect.Value, field reflect.StructField,
+	m valuerWithParent, fullName string) error {
+	if !field.IsExported() || field.Anonymous || usingDifferentKeys(u.key, field) {
+		return nil
+	}
+
+	key, opts, err := parseKeyAndOptions(u.key, field)
+	if err != nil || key == ignoreKey || opts == nil || !opts.Constraints.hasCrossRules() {
+		return nil
+	}
+
+	if u.opts.canonicalKey != nil {
+		key = u.opts.canonicalKey(key)
+	}
+	_, set := getValue(createValuer(m, &opts.fieldOptionsWithContext), key, u.opts.opaqueKeys)
+	if len(opts.Default) > 0 || len(opts.EnvVar) > 0 && len(proc.Env(opts.EnvVar)) > 0 {
+		set = true
+	}
+
+	return validateCrossFields(u.key, tp, value, field, set, opts.Constraints, join(fullName, key))
+}
+
 func (u *Unmarshaler) processFieldNotFromString(fieldType reflect.Type, value reflect.Value,
 	vp valueWithParent, opts *fieldOptionsWithContext, fullName string) error {
 	derefedFieldType := Deref(fieldType)
@@ -568,7 +694,12 @@ func (u *Unmarshaler) processFieldNotFromString(fieldType reflect.Type, value re
 	case valueKind == reflect.String && typeKind == reflect.Slice:
 		return u.fillSliceFromString(fieldType, value, mapValue, fullName)
 	case valueKind == reflect.String && derefedFieldType == durationType:
-		return fillDurationValue(fieldType, value, mapValue.(string))
+		// json.Number is also of string kind, which is handled as nanoseconds
+		if dur, ok := mapValue.(string); ok {
+			return fillDurationValue(fieldType, value, dur)
+		}
+
+		return u.processFieldPrimitive(fieldType, value, mapValue, opts, fullName)
 	default:
 		return u.processFieldPrimitive(fieldType, value, mapValue, opts, fullName)
 	}
@@ -660,40 +791,20 @@ func (u *Unmarshaler) processFieldStruct(fieldType reflect.Type, value reflect.V
 	return nil
 }
 
-func (u *Unmarshaler) processFieldTextUnmarshaler(fieldType reflect.Type, value reflect.Value,
-	mapValue any) (bool, error) {
-	var tval encoding.TextUnmarshaler
-	var ok bool
-
-	if fieldType.Kind() == reflect.Ptr {
-		if value.Elem().Kind() == reflect.Ptr {
-			target := reflect.New(Deref(fieldType))
-			SetValue(fieldType.Elem(), value, target)
-			tval, ok = target.Interface().(encoding.TextUnmarshaler)
-		} else {
-			tval, ok = value.Interface().(encoding.TextUnmarshaler)
-		}
-	} else {
-		tval, ok = value.Addr().Interface().(encoding.TextUnmarshaler)
-	}
-	if ok {
-		switch mv := mapValue.(type) {
-		case string:
-			return true, tval.UnmarshalText([]byte(mv))
-		case []byte:
-			return true, tval.UnmarshalText(mv)
-		}
-	}
-
-	return false, nil
-}
-
 func (u *Unmarshaler) processFieldWithEnvValue(fieldType reflect.Type, value reflect.Value,
 	envVal string, opts *fieldOptionsWithContext, fullName string) error {
 	if err := validateValueInOptions(envVal, opts.options()); err != nil {
 		return err
 	}
 
+	if yes, err := u.processFieldDecoder(fieldType, value, envVal); yes {
+		if err != nil {
+			return fmt.Errorf("unmarshal field %q with environment variable, %w", fullName, err)
+		}
+
+		return nil
+	}
+
 	fieldKind := fieldType.Kind()
 	switch fieldKind {
 	case reflect.Bool:
@@ -737,7 +848,11 @@ func (u *Unmarshaler) processNamedField(field reflect.StructField, value reflect
 	if opts != nil && len(opts.EnvVar) > 0 {
 		envVal := proc.Env(opts.EnvVar)
 		if len(envVal) > 0 {
-			return u.processFieldWithEnvValue(field.Type, value, envVal, opts, fullName)
+			if err := u.processFieldWithEnvValue(field.Type, value, envVal, opts, fullName); err != nil {
+				return err
+			}
+
+			return validateConstraints(value, opts, fullName)
 		}
 	}
 
@@ -759,10 +874,18 @@ func (u *Unmarshaler) processNamedField(field reflect.StructField, value reflect
 		return u.processNamedFieldWithoutValue(field.Type, value, opts, fullName)
 	}
 
-	return u.processNamedFieldWithValue(field.Type, value, valueWithParent{
+	if err := u.processNamedFieldWithValue(field.Type, value, valueWithParent{
 		value:  mapValue,
 		parent: valuer,
-	}, key, opts, fullName)
+	}, key, opts, fullName); err != nil {
+		return err
+	}
+
+	if mapValue == nil {
+		return nil
+	}
+
+	return validateConstraints(value, opts, fullName)
 }
 
 func (u *Unmarshaler) processNamedFieldWithValue(fieldType reflect.Type, value reflect.Value,
@@ -782,7 +905,7 @@ func (u *Unmarshaler) processNamedFieldWithValue(fieldType reflect.Type, value r
 
 	maybeNewValue(fieldType, value)
 
-	if yes, err := u.processFieldTextUnmarshaler(fieldType, value, mapValue); yes {
+	if yes, err := u.processFieldDecoder(fieldType, value, mapValue); yes {
 		return err
 	}
 
@@ -834,6 +957,10 @@ func (u *Unmarshaler) processNamedFieldWithoutValue(fieldType reflect.Type, valu
 	derefedType := Deref(fieldType)
 	fieldKind := derefedType.Kind()
 	if defaultValue, ok := opts.getDefault(); ok {
+		if yes, err := u.processFieldDecoder(fieldType, value, defaultValue); yes {
+			return err
+		}
+
 		if derefedType == durationType {
 			return fillDurationValue(fieldType, value, defaultValue)
 		}
@@ -905,16 +1032,15 @@ func (u *Unmarshaler) unmarshalWithFullName(m valuerWithParent, v any, fullName
 		valElem = target
 	}
 
-	numFields := baseType.NumField()
-	for i := 0; i < numFields; i++ {
-		typeField := baseType.Field(i)
-		valueField := valElem.Field(i)
-		if err := u.processField(typeField, valueField, m, fullName); err != nil {
-			return err
-		}
-	}
+	return u.processFields(baseType, valElem, m, fullName)
+}
 
-	return nil
+// WithAggregatedErrors customizes an Unmarshaler to collect all the errors instead of
+// stopping at the first one, the returned error is FieldErrors, which can be checked by errors.As.
+func WithAggregatedErrors() UnmarshalOption {
+	return func(opt *unmarshalOptions) {
+		opt.aggregateErrors = true
+	}
 }
 
 // WithStringValues customizes an Unmarshaler with number values from strings.
@@ -2481,6 +2481,31 @@ func TestUnmarshalStringOptionsWithStringOptionsIncorrect(t *testing.T) {
 	assert.Error(t, unmarshaler.Unmarshal(m, &in))
 }
 
+func TestUnmarshalStringValuesSingleSliceElement(t *testing.T) {
+	type inner struct {
+		Tags []string `key:"tags"`
+		IDs  []int    `key:"ids"`
+		Nums []int    `key:"nums"`
+		Raw  []string `key:"raw"`
+	}
+	m := map[string]any{
+		"tags": "a",
+		"ids":  []string{"1", "2"},
+		"nums": "[3,4]",
+		"raw":  "[draft]",
+	}
+
+	var in inner
+	unmarshaler := NewUnmarshaler(defaultKeyName, WithStringValues())
+	ast := assert.New(t)
+	if ast.NoError(unmarshaler.Unmarshal(m, &in)) {
+		ast.Equal([]string{"a"}, in.Tags)
+		ast.Equal([]int{1, 2}, in.IDs)
+		ast.Equal([]int{3, 4}, in.Nums)
+		ast.Equal([]string{"[draft]"}, in.Raw)
+	}
+}
+
 func TestUnmarshalStringOptionsWithStringOptionsIncorrectGrouped(t *testing.T) {
 	type inner struct {
 		Value   string `key:"value,options=[first,second]"`
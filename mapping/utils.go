@@ -9,6 +9,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/tp-life/utils/lang"
 	"github.com/tp-life/utils/stringx"
@@ -320,7 +321,7 @@ func parseNumberRange(str string) (*numberRange, error) {
 	var left float64
 	if len(fields[0]) > 0 {
 		var err error
-		if left, err = strconv.ParseFloat(fields[0], 64); err != nil {
+		if left, err = parseRangeBound(fields[0]); err != nil {
 			return nil, err
 		}
 	} else {
@@ -330,7 +331,7 @@ func parseNumberRange(str string) (*numberRange, error) {
 	var right float64
 	if len(fields[1]) > 0 {
 		var err error
-		if right, err = strconv.ParseFloat(fields[1], 64); err != nil {
+		if right, err = parseRangeBound(fields[1]); err != nil {
 			return nil, err
 		}
 	} else {
@@ -359,7 +360,25 @@ func parseNumberRange(str string) (*numberRange, error) {
 	}, nil
 }
 
+// parseRangeBound parses the bound of ranges, durations like 1s are parsed as nanoseconds.
+func parseRangeBound(str string) (float64, error) {
+	val, err := strconv.ParseFloat(str, 64)
+	if err == nil {
+		return val, nil
+	}
+
+	if dur, derr := time.ParseDuration(str); derr == nil {
+		return float64(dur), nil
+	}
+
+	return 0, err
+}
+
 func parseOption(fieldOpts *fieldOptions, fieldName, option string) error {
+	if ok, err := parseConstraint(fieldOpts, fieldName, option); ok {
+		return err
+	}
+
 	switch {
 	case option == inheritOption:
 		fieldOpts.Inherit = true
@@ -13,7 +13,7 @@ func UnmarshalYamlBytes(content []byte, v any, opts ...UnmarshalOption) error {
 		return err
 	}
 
-	return UnmarshalJsonBytes(b, v, opts...)
+	return locateYamlErrors(content, UnmarshalJsonBytes(b, v, opts...))
 }
 
 // UnmarshalYamlReader unmarshals content from reader into v.
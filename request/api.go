@@ -0,0 +1,237 @@
+package request
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/tp-life/utils/jsonx"
+	"github.com/tp-life/utils/validation"
+)
+
+const maxErrorBody = 512
+
+var (
+	// PlainDecoder 响应 body 即为数据, 状态码 >= 400 时返回 *APIError
+	PlainDecoder Decoder = DecoderFunc(decodePlain)
+
+	// DefaultEnvelope 解析 {"code": 0, "msg": "", "data": {}} 格式的响应, code 为0表示成功
+	DefaultEnvelope = Envelope{
+		CodeKey:    "code",
+		MessageKey: "msg",
+		DataKey:    "data",
+	}
+)
+
+type (
+	// Decoder 将响应解析到 out, 失败时返回 *APIError
+	Decoder interface {
+		Decode(status int, body []byte, out any) error
+	}
+
+	// DecoderFunc 函数形式的 Decoder
+	DecoderFunc func(status int, body []byte, out any) error
+
+	// Envelope 解析带有业务状态码的响应, 数据在 DataKey 字段中
+	Envelope struct {
+		CodeKey    string
+		MessageKey string
+		DataKey    string
+		// Success 判断业务状态码是否成功, 为空时 code 为0表示成功
+		Success func(code int) bool
+	}
+
+	// APIError 接口返回的错误
+	APIError struct {
+		// StatusCode HTTP 状态码
+		StatusCode int
+		// Code 业务状态码, 响应不是 Envelope 格式时为0
+		Code int
+		// Message 业务错误信息, 没有时为状态码的描述
+		Message string
+		// Body 原始响应
+		Body []byte
+	}
+
+	// Req 描述一次接口调用
+	Req struct {
+		Method     string
+		URL        string
+		PathParams map[string]string
+		Query      map[string]string
+		Header     map[string]string
+		Body       any
+		// Client 为空时使用默认 client
+		Client *resty.Client
+		// Decoder 为空时使用 PlainDecoder
+		Decoder Decoder
+	}
+)
+
+// Decode 实现 Decoder
+func (f DecoderFunc) Decode(status int, body []byte, out any) error {
+	return f(status, body, out)
+}
+
+// Error 实现 error
+func (e *APIError) Error() string {
+	body := e.Body
+	if len(body) > maxErrorBody {
+		body = body[:maxErrorBody]
+	}
+
+	return fmt.Sprintf("StatusCode: %d, Code: %d, Message: %s, Body: %s", e.StatusCode, e.Code, e.Message, body)
+}
+
+// Decode 实现 Decoder, 业务状态码失败或 HTTP 状态码 >= 400 时返回 *APIError
+func (e Envelope) Decode(status int, body []byte, out any) error {
+	var envelope map[string]json.RawMessage
+	if err := jsonx.Unmarshal(body, &envelope); err != nil {
+		if status >= http.StatusBadRequest {
+			return newAPIError(status, body)
+		}
+		return err
+	}
+
+	apiErr := newAPIError(status, body)
+	if raw, ok := envelope[e.CodeKey]; ok {
+		code, err := parseCode(raw)
+		if err != nil {
+			return fmt.Errorf("invalid envelope field %q: %w", e.CodeKey, err)
+		}
+		apiErr.Code = code
+	}
+	if raw, ok := envelope[e.MessageKey]; ok {
+		var msg string
+		if err := jsonx.Unmarshal(raw, &msg); err == nil && len(msg) > 0 {
+			apiErr.Message = msg
+		}
+	}
+	if status >= http.StatusBadRequest || !e.success(apiErr.Code) {
+		return apiErr
+	}
+
+	data, ok := envelope[e.DataKey]
+	if !ok || out == nil || string(data) == "null" {
+		return nil
+	}
+
+	return jsonx.Unmarshal(data, out)
+}
+
+// Do 发送请求并将响应解析为 Resp, 如果 Resp 实现了 validation.Validator, 解析后会校验
+func Do[Resp any](ctx context.Context, req Req) (Resp, error) {
+	var result Resp
+
+	cli := req.Client
+	if cli == nil {
+		cli = DefaultClient()
+	}
+	request := cli.R().SetContext(ctx).
+		SetPathParams(req.PathParams).
+		SetQueryParams(req.Query).
+		SetHeaders(req.Header)
+	if req.Body != nil {
+		request.SetBody(req.Body)
+	}
+
+	method := req.Method
+	if len(method) == 0 {
+		method = http.MethodGet
+	}
+	resp, err := request.Execute(method, req.URL)
+	if err != nil {
+		return result, err
+	}
+
+	decoder := req.Decoder
+	if decoder == nil {
+		decoder = PlainDecoder
+	}
+	if err = decoder.Decode(resp.StatusCode(), resp.Body(), &result); err != nil {
+		return result, err
+	}
+
+	if err = validate(&result); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// GetAs 使用 GET 请求, 将响应解析为 Resp
+func GetAs[Resp any](ctx context.Context, url string, query map[string]string, decoder Decoder) (Resp, error) {
+	return Do[Resp](ctx, Req{
+		Method:  http.MethodGet,
+		URL:     url,
+		Query:   query,
+		Decoder: decoder,
+	})
+}
+
+// PostAs 使用 POST 请求, 将响应解析为 Resp
+func PostAs[Resp any](ctx context.Context, url string, body any, decoder Decoder) (Resp, error) {
+	return Do[Resp](ctx, Req{
+		Method:  http.MethodPost,
+		URL:     url,
+		Body:    body,
+		Decoder: decoder,
+	})
+}
+
+func (e Envelope) success(code int) bool {
+	if e.Success != nil {
+		return e.Success(code)
+	}
+
+	return code == 0
+}
+
+// validate Resp 本身或其指针实现了 validation.Validator 时校验
+func validate[Resp any](result *Resp) error {
+	if v, ok := any(*result).(validation.Validator); ok {
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return nil
+		}
+		return v.Validate()
+	}
+	if v, ok := any(result).(validation.Validator); ok {
+		return v.Validate()
+	}
+
+	return nil
+}
+
+func decodePlain(status int, body []byte, out any) error {
+	if status >= http.StatusBadRequest {
+		return newAPIError(status, body)
+	}
+	if out == nil || len(body) == 0 {
+		return nil
+	}
+
+	return jsonx.Unmarshal(body, out)
+}
+
+func newAPIError(status int, body []byte) *APIError {
+	return &APIError{
+		StatusCode: status,
+		Message:    http.StatusText(status),
+		Body:       body,
+	}
+}
+
+// parseCode 业务状态码可能是数字或字符串
+func parseCode(raw json.RawMessage) (int, error) {
+	s := strings.Trim(strings.TrimSpace(string(raw)), `"`)
+	if len(s) == 0 || s == "null" {
+		return 0, nil
+	}
+
+	return strconv.Atoi(s)
+}
@@ -0,0 +1,141 @@
+package request
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	apiUser struct {
+		Id   int    `json:"id"`
+		Name string `json:"name"`
+	}
+
+	validatedUser struct {
+		apiUser
+	}
+)
+
+func (u validatedUser) Validate() error {
+	if len(u.Name) == 0 {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+func apiServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/plain/1", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":1,"name":"bob"}`))
+	})
+	mux.HandleFunc("/plain/noname", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":2}`))
+	})
+	mux.HandleFunc("/envelope/1", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":0,"msg":"ok","data":{"id":1,"name":"bob"}}`))
+	})
+	mux.HandleFunc("/envelope/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":"10001","msg":"user not found","data":null}`))
+	})
+	mux.HandleFunc("/envelope/500", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte(`{"code":502,"msg":"upstream down"}`))
+	})
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestDoPlain(t *testing.T) {
+	srv := apiServer(t)
+	cli := NewClient()
+
+	user, err := Do[apiUser](context.Background(), Req{URL: srv.URL + "/plain/{id}", PathParams: map[string]string{"id": "1"}, Client: cli})
+	assert.Nil(t, err)
+	assert.Equal(t, apiUser{Id: 1, Name: "bob"}, user)
+
+	ptr, err := PostAs[*apiUser](context.Background(), srv.URL+"/echo", apiUser{Id: 3, Name: "alice"}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, &apiUser{Id: 3, Name: "alice"}, ptr)
+
+	raw, err := GetAs[map[string]any](context.Background(), srv.URL+"/plain/1", nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, json.Number("1"), raw["id"])
+
+	_, err = GetAs[apiUser](context.Background(), srv.URL+"/missing", nil, nil)
+	var apiErr *APIError
+	if assert.True(t, errors.As(err, &apiErr)) {
+		assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+		assert.Equal(t, "Not Found", apiErr.Message)
+		assert.Contains(t, string(apiErr.Body), "404 page not found")
+	}
+}
+
+func TestDoEnvelope(t *testing.T) {
+	srv := apiServer(t)
+
+	user, err := GetAs[apiUser](context.Background(), srv.URL+"/envelope/1", nil, DefaultEnvelope)
+	assert.Nil(t, err)
+	assert.Equal(t, apiUser{Id: 1, Name: "bob"}, user)
+
+	_, err = GetAs[apiUser](context.Background(), srv.URL+"/envelope/fail", nil, DefaultEnvelope)
+	var apiErr *APIError
+	if assert.True(t, errors.As(err, &apiErr)) {
+		assert.Equal(t, http.StatusOK, apiErr.StatusCode)
+		assert.Equal(t, 10001, apiErr.Code)
+		assert.Equal(t, "user not found", apiErr.Message)
+	}
+
+	_, err = GetAs[apiUser](context.Background(), srv.URL+"/envelope/500", nil, DefaultEnvelope)
+	if assert.True(t, errors.As(err, &apiErr)) {
+		assert.Equal(t, http.StatusBadGateway, apiErr.StatusCode)
+		assert.Equal(t, 502, apiErr.Code)
+		assert.Equal(t, "upstream down", apiErr.Message)
+	}
+
+	// custom success codes
+	envelope := DefaultEnvelope
+	envelope.Success = func(code int) bool {
+		return code == 10001
+	}
+	user, err = GetAs[apiUser](context.Background(), srv.URL+"/envelope/fail", nil, envelope)
+	assert.Nil(t, err)
+	assert.Equal(t, apiUser{}, user)
+}
+
+func TestDoValidate(t *testing.T) {
+	srv := apiServer(t)
+
+	user, err := GetAs[validatedUser](context.Background(), srv.URL+"/plain/1", nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "bob", user.Name)
+
+	_, err = GetAs[validatedUser](context.Background(), srv.URL+"/plain/noname", nil, nil)
+	assert.EqualError(t, err, "name is required")
+	_, err = GetAs[*validatedUser](context.Background(), srv.URL+"/plain/noname", nil, nil)
+	assert.EqualError(t, err, "name is required")
+}
+
+func TestReadJSONError(t *testing.T) {
+	srv := apiServer(t)
+	err := GetAPI(context.Background(), srv.URL+"/missing", nil, nil)
+	var apiErr *APIError
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+}
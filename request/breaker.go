@@ -0,0 +1,192 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/tp-life/utils/breaker"
+	"github.com/tp-life/utils/syncx"
+)
+
+const breakerNamePrefix = "http.client:"
+
+var (
+	// ErrConcurrencyLimit 并发请求数超过限制, 请求被丢弃
+	ErrConcurrencyLimit = errors.New("http client concurrency limit exceeded")
+
+	errUnacceptableStatus = errors.New("unacceptable status code")
+)
+
+type (
+	// BreakerError 熔断器打开, 请求被拒绝
+	BreakerError struct {
+		// Name 熔断器名称, 即请求的 host 或路由模板
+		Name string
+	}
+
+	// KeyFunc 计算请求所属的熔断器或限流分组
+	KeyFunc func(r *http.Request) string
+
+	// FallbackFunc 熔断时的降级处理, err 为 *BreakerError
+	FallbackFunc func(r *http.Request, err error) (*http.Response, error)
+
+	// BreakerOption 自定义熔断中间件的方法
+	BreakerOption func(opt *breakerOptions)
+
+	breakerOptions struct {
+		key        KeyFunc
+		acceptable func(code int) bool
+		fallback   FallbackFunc
+	}
+
+	routeKey struct{}
+)
+
+// Error 实现 error
+func (e *BreakerError) Error() string {
+	return fmt.Sprintf("%s, upstream: %s", breaker.ErrServiceUnavailable.Error(), e.Name)
+}
+
+// Unwrap 使 errors.Is(err, breaker.ErrServiceUnavailable) 成立
+func (e *BreakerError) Unwrap() error {
+	return breaker.ErrServiceUnavailable
+}
+
+// WithRoute 设置请求的路由模板, 如 /users/{id}, 使用 RouteKey 时按路由模板熔断
+func WithRoute(ctx context.Context, route string) context.Context {
+	return context.WithValue(ctx, routeKey{}, route)
+}
+
+// HostKey 按 host 分组
+func HostKey(r *http.Request) string {
+	return r.URL.Host
+}
+
+// RouteKey 按 method、host 和路由模板分组, 没有设置路由模板时按 host 分组
+func RouteKey(r *http.Request) string {
+	route, ok := r.Context().Value(routeKey{}).(string)
+	if !ok || len(route) == 0 {
+		return HostKey(r)
+	}
+
+	return r.Method + " " + r.URL.Host + route
+}
+
+// WithBreakerKey 自定义熔断器分组, 默认按 host 分组
+func WithBreakerKey(key KeyFunc) BreakerOption {
+	return func(opt *breakerOptions) {
+		opt.key = key
+	}
+}
+
+// WithAcceptableStatus 自定义不计为失败的状态码, 默认 5xx 和 429 为失败
+func WithAcceptableStatus(acceptable func(code int) bool) BreakerOption {
+	return func(opt *breakerOptions) {
+		opt.acceptable = acceptable
+	}
+}
+
+// WithFallback 熔断时调用 fallback, 而不是直接返回 *BreakerError
+func WithFallback(fallback FallbackFunc) BreakerOption {
+	return func(opt *breakerOptions) {
+		opt.fallback = fallback
+	}
+}
+
+// CircuitBreaker 按分组使用 breaker.Breaker 熔断, 熔断器在所有 client 间共享
+// 不可接受的状态码计为失败, 但响应仍然正常返回给调用方
+func CircuitBreaker(opts ...BreakerOption) Middleware {
+	o := breakerOptions{
+		key:        HostKey,
+		acceptable: acceptableStatus,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			name := o.key(r)
+			var resp *http.Response
+			err := breaker.GetBreaker(breakerNamePrefix+name).DoWithFallbackAcceptable(func() error {
+				var err error
+				resp, err = next.RoundTrip(r)
+				if err != nil {
+					return err
+				}
+				if !o.acceptable(resp.StatusCode) {
+					return fmt.Errorf("%w: %d", errUnacceptableStatus, resp.StatusCode)
+				}
+				return nil
+			}, func(err error) error {
+				err = &BreakerError{Name: name}
+				if o.fallback == nil {
+					return err
+				}
+
+				resp, err = o.fallback(r, err)
+				return err
+			}, acceptableError)
+			if err != nil && !errors.Is(err, errUnacceptableStatus) {
+				return nil, err
+			}
+
+			return resp, nil
+		})
+	}
+}
+
+// ConcurrencyLimit 限制每个分组的并发请求数, 超过时返回 ErrConcurrencyLimit
+// key 为空时按 host 分组, 请求占用的名额在响应 body 关闭时释放
+func ConcurrencyLimit(n int, key KeyFunc) Middleware {
+	if key == nil {
+		key = HostKey
+	}
+
+	var lock sync.Mutex
+	limits := make(map[string]syncx.Limit)
+	getLimit := func(name string) syncx.Limit {
+		lock.Lock()
+		defer lock.Unlock()
+
+		limit, ok := limits[name]
+		if !ok {
+			limit = syncx.NewLimit(n)
+			limits[name] = limit
+		}
+		return limit
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			limit := getLimit(key(r))
+			if !limit.TryBorrow() {
+				return nil, ErrConcurrencyLimit
+			}
+
+			release := func() {
+				limit.Return()
+			}
+			resp, err := next.RoundTrip(r)
+			if err != nil {
+				release()
+				return nil, err
+			}
+
+			resp.Body = &closeHookBody{ReadCloser: resp.Body, onClose: release}
+			return resp, nil
+		})
+	}
+}
+
+func acceptableStatus(code int) bool {
+	return code < http.StatusInternalServerError && code != http.StatusTooManyRequests
+}
+
+// acceptableError 调用方取消的请求不计为失败
+func acceptableError(err error) bool {
+	return err == nil || errors.Is(err, context.Canceled)
+}
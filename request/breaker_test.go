@@ -0,0 +1,132 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tp-life/utils/breaker"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	cli := NewClient(WithMiddleware(CircuitBreaker()))
+	var rejected int
+	for i := 0; i < 200; i++ {
+		resp, err := cli.R().Get(srv.URL)
+		if err != nil {
+			var be *BreakerError
+			assert.True(t, errors.As(err, &be))
+			assert.Equal(t, strings.TrimPrefix(srv.URL, "http://"), be.Name)
+			assert.ErrorIs(t, err, breaker.ErrServiceUnavailable)
+			rejected++
+			continue
+		}
+		// unacceptable responses are still returned to the caller
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode())
+	}
+	assert.True(t, rejected > 0)
+}
+
+func TestCircuitBreakerAcceptable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	cli := NewClient(WithMiddleware(CircuitBreaker(WithAcceptableStatus(func(code int) bool {
+		return true
+	}))))
+	for i := 0; i < 200; i++ {
+		_, err := cli.R().Get(srv.URL)
+		assert.Nil(t, err)
+	}
+}
+
+func TestCircuitBreakerFallback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/bad") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	cli := NewClient(WithMiddleware(CircuitBreaker(
+		WithBreakerKey(RouteKey),
+		WithFallback(func(r *http.Request, err error) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{},
+				Body:       io.NopCloser(strings.NewReader("fallback")),
+				Request:    r,
+			}, nil
+		}),
+	)))
+
+	ctx := WithRoute(context.Background(), "/bad/{id}")
+	var fallbacks int
+	for i := 0; i < 200; i++ {
+		resp, err := cli.R().SetContext(ctx).Get(srv.URL + "/bad/1")
+		assert.Nil(t, err)
+		if resp.String() == "fallback" {
+			fallbacks++
+		}
+	}
+	assert.True(t, fallbacks > 0)
+
+	// other routes of the same host are not affected
+	for i := 0; i < 10; i++ {
+		resp, err := cli.R().SetContext(WithRoute(context.Background(), "/good")).Get(srv.URL + "/good")
+		assert.Nil(t, err)
+		assert.Equal(t, "ok", resp.String())
+	}
+}
+
+func TestRouteKey(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "http://example.com/users/1", nil)
+	assert.Equal(t, "example.com", RouteKey(r))
+	r = r.WithContext(WithRoute(r.Context(), "/users/{id}"))
+	assert.Equal(t, "GET example.com/users/{id}", RouteKey(r))
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	arrived := make(chan struct{})
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived <- struct{}{}
+		<-release
+	}))
+	defer srv.Close()
+
+	cli := NewClient(WithMiddleware(ConcurrencyLimit(1, nil)))
+	done := make(chan error)
+	go func() {
+		_, err := cli.R().Get(srv.URL)
+		done <- err
+	}()
+	<-arrived
+
+	_, err := cli.R().Get(srv.URL)
+	assert.ErrorIs(t, err, ErrConcurrencyLimit)
+
+	close(release)
+	assert.Nil(t, <-done)
+
+	// the slot is released after the body is read
+	go func() {
+		<-arrived
+	}()
+	_, err = cli.R().Get(srv.URL)
+	assert.Nil(t, err)
+}
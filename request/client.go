@@ -1,31 +1,80 @@
 package request
 
 import (
-	"bytes"
 	"context"
-	"log/slog"
 	"net/http"
 	"sync"
 	"time"
 
 	"github.com/go-resty/resty/v2"
-	jsoniter "github.com/json-iterator/go"
-	"github.com/opentracing/opentracing-go"
+	"github.com/tp-life/utils/jsonx"
+	"github.com/tp-life/utils/stat"
 )
 
 const (
 	HttpClientTimeout time.Duration = time.Second * 30
 )
 
-// Client 新建
+type (
+	// Option 自定义 client 的方法
+	Option func(opt *clientOptions)
+
+	clientOptions struct {
+		transport   http.RoundTripper
+		middlewares []Middleware
+	}
+)
+
+// Client 新建, 默认带有重试、超时、链路追踪、统计、按 host 熔断、日志和解压中间件
+// 默认不重试, 通过 WithRetry 为请求的 ctx 开启
 func Client() *resty.Client {
+	return NewClient(WithMiddleware(
+		Retry(WithMaxAttempts(1)),
+		Timeout(HttpClientTimeout),
+		Tracing(),
+		Metrics(clientMetrics),
+		CircuitBreaker(),
+		Logging(DefaultLogConfig()),
+		Decompress(),
+	))
+}
+
+// NewClient 使用中间件新建 client, 中间件按添加顺序执行
+func NewClient(opts ...Option) *resty.Client {
+	var o clientOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	transport := o.transport
+	if transport == nil {
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	}
+
+	// 请求和响应的 json 使用 jsonx 的引擎, 与其他包保持一致
 	return resty.New().
-		OnBeforeRequest(requestWithTimeout).
-		OnBeforeRequest(beforeRequestLog).
-		OnAfterResponse(afterResponseLog)
+		SetTransport(Chain(transport, o.middlewares...)).
+		SetJSONMarshaler(jsonx.Marshal).
+		SetJSONUnmarshaler(jsonx.Unmarshal)
+}
+
+// WithMiddleware 添加中间件
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(opt *clientOptions) {
+		opt.middlewares = append(opt.middlewares, middlewares...)
+	}
+}
+
+// WithTransport 自定义底层 http.RoundTripper
+func WithTransport(transport http.RoundTripper) Option {
+	return func(opt *clientOptions) {
+		opt.transport = transport
+	}
 }
 
 var (
+	clientMetrics = stat.NewMetrics("http.client")
+
 	_defaultClient *resty.Client
 	_once          sync.Once
 )
@@ -75,94 +124,5 @@ func doRequest(method string, url string, request *resty.Request) (resp *resty.R
 		resp, err = request.Options(url)
 	}
 
-	// without istio
-	span := opentracing.SpanFromContext(request.Context())
-	if span != nil {
-		if err != nil {
-			span.SetTag("error", true)
-			span.SetTag("errorMsg", "[http调用失败]")
-		} else {
-			span.SetTag("http.status_code", uint16(resp.StatusCode()))
-		}
-		span.Finish()
-	}
-
 	return resp, err
 }
-
-func requestWithTimeout(cli *resty.Client, r *resty.Request) error {
-	ctx := r.Context()
-	if ctx == nil {
-		ctx = context.TODO()
-	} else if _, ok := ctx.Deadline(); ok {
-		return nil
-	}
-	ctx, _ = context.WithTimeout(ctx, HttpClientTimeout)
-	r.SetContext(ctx)
-	return nil
-}
-
-// beforeRequestLog 调用开始记录日志
-func beforeRequestLog(client *resty.Client, r *resty.Request) error {
-
-	logRequest := map[string]any{
-		"URL":    r.URL,
-		"Method": r.Method,
-		"Header": r.Header,
-	}
-
-	if len(r.QueryParam) > 0 {
-		logRequest["Query"] = r.QueryParam
-	}
-	if len(r.FormData) > 0 {
-		logRequest["Form"] = r.FormData
-	}
-	if val := r.Error; val != nil {
-		logRequest["Error"] = val
-	}
-	if r.Body != nil {
-		logRequest["Body"] = r.Body
-	}
-	slog.InfoContext(r.Context(), "发送请求[http.client]", slog.Any("request", logRequest))
-	return nil
-}
-
-// afterResponseLog 完成调用，记录日志
-func afterResponseLog(client *resty.Client, response *resty.Response) error {
-
-	decoder := jsoniter.NewDecoder(bytes.NewBuffer(response.Body()))
-	decoder.UseNumber()
-	body := make(map[string]interface{})
-	err := decoder.Decode(&body)
-	if err != nil {
-		slog.InfoContext(response.Request.Context(), "Unmarshal-错误", slog.Any("error", err))
-		return nil
-	}
-
-	logResponse := map[string]any{
-		"URL":    response.Request.URL,
-		"Method": response.Request.Method,
-		"Header": response.Request.Header,
-	}
-
-	if body != nil {
-		logResponse["Body"] = body
-	}
-	if val := response.Result(); val != nil {
-		logResponse["Result"] = val
-	}
-	if val := response.Error(); val != nil {
-		logResponse["Error"] = val
-	}
-	if val := response.StatusCode(); val > 0 {
-		logResponse["StatusCode"] = val
-	}
-	if val := response.Status(); len(val) > 0 {
-		logResponse["Status"] = val
-	}
-	if val := response.Size(); val < 0 {
-		logResponse["Size"] = val
-	}
-	slog.InfoContext(response.Request.Context(), "接收响应[http.client]", slog.Any("response", logResponse))
-	return nil
-}
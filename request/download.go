@@ -0,0 +1,273 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const partSuffix = ".part"
+
+// ErrRangeIgnored 服务端没有按请求的范围返回内容
+var ErrRangeIgnored = errors.New("server ignored the range request")
+
+type (
+	downloadState struct {
+		URL       string  `json:"url"`
+		Size      int64   `json:"size"`
+		Validator string  `json:"validator"`
+		ChunkSize int64   `json:"chunk_size"`
+		Done      []int64 `json:"done"`
+	}
+
+	// remoteFile 探测到的远程文件信息
+	remoteFile struct {
+		size      int64
+		validator string
+		ranges    bool
+	}
+)
+
+// Download 下载 url 到 path, 服务端支持 Range 时分片并发下载
+// 下载过程写入 path.part, 中断后再次调用会从已完成的位置继续, 全部完成并校验通过后重命名为 path
+func Download(ctx context.Context, url, path string, opts ...TransferOption) error {
+	o := newTransferOptions(opts)
+	if len(o.stateFile) == 0 {
+		o.stateFile = path + partSuffix + ".json"
+	}
+
+	remote, err := probe(ctx, o, url)
+	if err != nil {
+		return err
+	}
+
+	part := path + partSuffix
+	if remote.ranges {
+		err = downloadChunks(ctx, o, url, part, remote)
+	} else {
+		err = downloadStream(ctx, o, url, part)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = verifyChecksum(o, part); err != nil {
+		if errors.Is(err, ErrChecksumMismatch) {
+			os.Remove(part)
+			os.Remove(o.stateFile)
+		}
+		return err
+	}
+
+	if err = os.Rename(part, path); err != nil {
+		return err
+	}
+	os.Remove(o.stateFile)
+
+	return nil
+}
+
+// probe 请求第一个字节, 探测文件大小和是否支持 Range
+func probe(ctx context.Context, o transferOptions, url string) (remoteFile, error) {
+	resp, err := o.client.R().SetContext(ctx).
+		SetHeaders(o.header).
+		SetHeader("Range", "bytes=0-0").
+		SetDoNotParseResponse(true).
+		Get(url)
+	if err != nil {
+		return remoteFile{}, err
+	}
+	drainBody(resp.RawBody())
+
+	switch resp.StatusCode() {
+	case http.StatusPartialContent:
+		size, ok := parseContentRangeSize(resp.Header().Get("Content-Range"))
+		if !ok {
+			return remoteFile{}, nil
+		}
+		return remoteFile{
+			size:      size,
+			validator: validator(resp.Header()),
+			ranges:    true,
+		}, nil
+	case http.StatusOK, http.StatusRequestedRangeNotSatisfiable:
+		// 不支持 Range, 或者是空文件
+		return remoteFile{}, nil
+	default:
+		return remoteFile{}, newAPIError(resp.StatusCode(), nil)
+	}
+}
+
+func downloadChunks(ctx context.Context, o transferOptions, url, part string, remote remoteFile) error {
+	chunks := planChunks(remote.size, o.chunkSize)
+	state := downloadState{
+		URL:       url,
+		Size:      remote.size,
+		Validator: remote.validator,
+		ChunkSize: o.chunkSize,
+	}
+
+	// 临时文件被删除或截断时, 已完成的记录不可信, 重新下载
+	flag := os.O_RDWR | os.O_CREATE
+	var saved downloadState
+	if loadState(o.stateFile, &saved) && saved.URL == url && saved.Size == remote.size &&
+		saved.Validator == remote.validator && saved.ChunkSize == o.chunkSize && len(saved.Done) == len(chunks) &&
+		fileSize(part) == remote.size {
+		for i, c := range chunks {
+			c.done = saved.Done[i]
+			o.progress.Add64(c.done)
+		}
+	} else {
+		flag |= os.O_TRUNC
+	}
+
+	file, err := os.OpenFile(part, flag, 0o644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err = file.Truncate(remote.size); err != nil {
+		return err
+	}
+
+	var lock sync.Mutex
+	save := func() error {
+		lock.Lock()
+		defer lock.Unlock()
+
+		state.Done = make([]int64, len(chunks))
+		for i, c := range chunks {
+			state.Done[i] = c.end - c.start - c.remaining()
+		}
+		return saveState(o.stateFile, state)
+	}
+
+	err = runChunks(ctx, o, chunks, func(ctx context.Context, c *chunk) error {
+		err := downloadChunk(ctx, o, url, remote.validator, file, c)
+		if err == nil {
+			err = save()
+		}
+		return err
+	})
+	if err != nil {
+		// 记录已完成的部分, 下次从中断的位置继续
+		save()
+		return err
+	}
+
+	return file.Sync()
+}
+
+func downloadChunk(ctx context.Context, o transferOptions, url, validator string, file *os.File, c *chunk) error {
+	start := c.end - c.remaining()
+	request := o.client.R().SetContext(ctx).
+		SetHeaders(o.header).
+		SetHeader("Range", fmt.Sprintf("bytes=%d-%d", start, c.end-1)).
+		SetDoNotParseResponse(true)
+	// 文件在下载过程中变化时, 服务端返回完整内容而不是部分内容
+	if len(validator) > 0 {
+		request.SetHeader("If-Range", validator)
+	}
+
+	resp, err := request.Get(url)
+	if err != nil {
+		return err
+	}
+	body := resp.RawBody()
+	defer body.Close()
+
+	if resp.StatusCode() != http.StatusPartialContent {
+		if resp.StatusCode() == http.StatusOK {
+			return ErrRangeIgnored
+		}
+		return newAPIError(resp.StatusCode(), nil)
+	}
+
+	w := &progressWriter{
+		w:        io.NewOffsetWriter(file, start),
+		chunk:    c,
+		progress: o.progress,
+	}
+	n, err := io.Copy(w, io.LimitReader(body, c.end-start))
+	if err != nil {
+		return err
+	}
+	if n < c.end-start {
+		return io.ErrUnexpectedEOF
+	}
+
+	return nil
+}
+
+// downloadStream 服务端不支持 Range 时整体下载
+func downloadStream(ctx context.Context, o transferOptions, url, part string) error {
+	return retryChunk(ctx, o, &chunk{}, func(ctx context.Context, _ *chunk) error {
+		resp, err := o.client.R().SetContext(ctx).
+			SetHeaders(o.header).
+			SetDoNotParseResponse(true).
+			Get(url)
+		if err != nil {
+			return err
+		}
+		body := resp.RawBody()
+		defer body.Close()
+
+		if resp.StatusCode() != http.StatusOK {
+			return newAPIError(resp.StatusCode(), nil)
+		}
+
+		file, err := os.Create(part)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		var written int64
+		if _, err = io.Copy(&progressWriter{w: file, chunk: &chunk{}, progress: ProgressFunc(func(n int64) {
+			written += n
+			o.progress.Add64(n)
+		})}, body); err != nil {
+			// 重新下载时进度从0开始
+			o.progress.Add64(-written)
+			return err
+		}
+
+		return file.Sync()
+	})
+}
+
+// parseContentRangeSize 解析 Content-Range: bytes 0-0/1234 中的总大小
+// fileSize 返回文件大小, 文件不存在时返回 -1
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return -1
+	}
+
+	return info.Size()
+}
+
+func parseContentRangeSize(val string) (int64, bool) {
+	_, size, ok := strings.Cut(val, "/")
+	if !ok || size == "*" {
+		return 0, false
+	}
+
+	n, err := strconv.ParseInt(strings.TrimSpace(size), 10, 64)
+	return n, err == nil && n >= 0
+}
+
+// validator 用于判断远程文件是否变化, 优先使用强 ETag
+func validator(header http.Header) string {
+	if etag := header.Get("ETag"); len(etag) > 0 && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+
+	return header.Get("Last-Modified")
+}
@@ -0,0 +1,230 @@
+package request
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/tp-life/utils/jsonx"
+)
+
+const (
+	// DefaultMaxBodyLog 默认记录的 body 最大字节数
+	DefaultMaxBodyLog = 4096
+
+	redacted  = "******"
+	truncated = "...(truncated)"
+)
+
+// DefaultRedactHeaders 默认脱敏的 header
+var DefaultRedactHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Api-Key",
+}
+
+type (
+	// LogConfig 请求日志配置
+	LogConfig struct {
+		// RedactHeaders 脱敏的 header, 不区分大小写
+		RedactHeaders []string
+		// RedactFields 脱敏的 query 参数、表单和 JSON body 字段, 不区分大小写
+		RedactFields []string
+		// MaxBodyLog 记录的 body 最大字节数, 超出部分截断, 小于0时不记录 body
+		MaxBodyLog int
+	}
+
+	logger struct {
+		headers    map[string]struct{}
+		fields     map[string]struct{}
+		maxBodyLog int
+	}
+
+	restoreBody struct {
+		io.Reader
+		io.Closer
+	}
+)
+
+// DefaultLogConfig 默认日志配置
+func DefaultLogConfig() LogConfig {
+	return LogConfig{
+		RedactHeaders: DefaultRedactHeaders,
+		MaxBodyLog:    DefaultMaxBodyLog,
+	}
+}
+
+// Logging 记录请求和响应日志, 按配置脱敏 header 和 body 字段
+func Logging(c LogConfig) Middleware {
+	l := &logger{
+		headers:    make(map[string]struct{}, len(c.RedactHeaders)),
+		fields:     make(map[string]struct{}, len(c.RedactFields)),
+		maxBodyLog: c.MaxBodyLog,
+	}
+	for _, h := range c.RedactHeaders {
+		l.headers[http.CanonicalHeaderKey(h)] = struct{}{}
+	}
+	for _, f := range c.RedactFields {
+		l.fields[strings.ToLower(f)] = struct{}{}
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			return l.roundTrip(next, r)
+		})
+	}
+}
+
+func (l *logger) roundTrip(next http.RoundTripper, r *http.Request) (*http.Response, error) {
+	logRequest := map[string]any{
+		"URL":    l.redactURL(r.URL),
+		"Method": r.Method,
+		"Header": l.redactHeader(r.Header),
+	}
+	if r.Body != nil && r.Body != http.NoBody {
+		r = cloneRequest(r)
+		var body any
+		body, r.Body = l.peekBody(r.Body, r.Header.Get("Content-Type"))
+		if body != nil {
+			logRequest["Body"] = body
+		}
+	}
+	slog.InfoContext(r.Context(), "发送请求[http.client]", slog.Any("request", logRequest))
+
+	start := time.Now()
+	resp, err := next.RoundTrip(r)
+	logResponse := map[string]any{
+		"URL":      logRequest["URL"],
+		"Method":   r.Method,
+		"Duration": time.Since(start).String(),
+	}
+	if err != nil {
+		logResponse["Error"] = err.Error()
+		slog.InfoContext(r.Context(), "接收响应[http.client]", slog.Any("response", logResponse))
+		return nil, err
+	}
+
+	logResponse["StatusCode"] = resp.StatusCode
+	logResponse["Status"] = resp.Status
+	logResponse["Header"] = l.redactHeader(resp.Header)
+	if resp.Body != nil && resp.Body != http.NoBody {
+		var body any
+		body, resp.Body = l.peekBody(resp.Body, resp.Header.Get("Content-Type"))
+		if body != nil {
+			logResponse["Body"] = body
+		}
+	}
+	slog.InfoContext(r.Context(), "接收响应[http.client]", slog.Any("response", logResponse))
+
+	return resp, nil
+}
+
+// peekBody 读取不超过 maxBodyLog 的 body 用于日志, 返回可以完整读取原 body 的 ReadCloser
+func (l *logger) peekBody(body io.ReadCloser, contentType string) (any, io.ReadCloser) {
+	if l.maxBodyLog < 0 {
+		return nil, body
+	}
+
+	head, err := io.ReadAll(io.LimitReader(body, int64(l.maxBodyLog)+1))
+	restored := &restoreBody{
+		Reader: io.MultiReader(bytes.NewReader(head), body),
+		Closer: body,
+	}
+	if err != nil {
+		return nil, restored
+	}
+	if len(head) > l.maxBodyLog {
+		// 截断的内容无法解析, 避免泄露敏感字段, 只在没有配置脱敏字段时记录
+		if len(l.fields) > 0 {
+			return truncated, restored
+		}
+		return string(head[:l.maxBodyLog]) + truncated, restored
+	}
+
+	return l.redactBody(head, contentType), restored
+}
+
+func (l *logger) redactBody(body []byte, contentType string) any {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(body))
+		if err == nil {
+			return l.redactValues(values).Encode()
+		}
+	case len(mediaType) == 0 || strings.Contains(mediaType, "json"):
+		decoder := jsonx.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		var val any
+		if err := decoder.Decode(&val); err == nil {
+			return l.redactJSON(val)
+		}
+	}
+
+	return string(body)
+}
+
+func (l *logger) redactJSON(val any) any {
+	switch v := val.(type) {
+	case map[string]any:
+		for key, item := range v {
+			if _, ok := l.fields[strings.ToLower(key)]; ok {
+				v[key] = redacted
+			} else {
+				v[key] = l.redactJSON(item)
+			}
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = l.redactJSON(item)
+		}
+	}
+
+	return val
+}
+
+func (l *logger) redactHeader(header http.Header) http.Header {
+	result := make(http.Header, len(header))
+	for key, values := range header {
+		if _, ok := l.headers[http.CanonicalHeaderKey(key)]; ok {
+			result[key] = []string{redacted}
+		} else {
+			result[key] = values
+		}
+	}
+
+	return result
+}
+
+// redactURL 隐藏 url 中的密码和敏感参数
+func (l *logger) redactURL(u *url.URL) string {
+	if len(l.fields) == 0 || len(u.RawQuery) == 0 {
+		return u.Redacted()
+	}
+
+	values, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return u.Redacted()
+	}
+
+	cu := *u
+	cu.RawQuery = l.redactValues(values).Encode()
+	return cu.Redacted()
+}
+
+func (l *logger) redactValues(values url.Values) url.Values {
+	for key := range values {
+		if _, ok := l.fields[strings.ToLower(key)]; ok {
+			values[key] = []string{redacted}
+		}
+	}
+
+	return values
+}
@@ -0,0 +1,104 @@
+package request
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	old := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() {
+		slog.SetDefault(old)
+	})
+	return &buf
+}
+
+func TestLoggingRedact(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=secret-cookie")
+		w.Write([]byte(`{"token":"secret-resp","data":{"id":1}}`))
+	}))
+	defer srv.Close()
+
+	buf := captureLog(t)
+	config := DefaultLogConfig()
+	config.RedactFields = []string{"password", "token", "access_token"}
+	cli := NewClient(WithMiddleware(Logging(config)))
+
+	resp, err := cli.R().
+		SetHeader("Authorization", "Bearer secret-auth").
+		SetHeader("X-Custom", "visible").
+		SetQueryParam("access_token", "secret-query").
+		SetBody(map[string]any{"user": "bob", "Password": "secret-pwd", "items": []any{map[string]any{"token": "secret-item"}}}).
+		Post(srv.URL)
+	assert.Nil(t, err)
+	// the body is still fully readable by the caller
+	assert.Equal(t, `{"token":"secret-resp","data":{"id":1}}`, resp.String())
+
+	logs := buf.String()
+	for _, secret := range []string{"secret-auth", "secret-query", "secret-pwd", "secret-item", "secret-resp", "secret-cookie"} {
+		assert.NotContains(t, logs, secret)
+	}
+	for _, visible := range []string{"visible", "bob", "发送请求[http.client]", "接收响应[http.client]", `"StatusCode":200`} {
+		assert.Contains(t, logs, visible)
+	}
+}
+
+func TestLoggingUserinfo(t *testing.T) {
+	srv := echoHeaders(t)
+	target := strings.Replace(srv.URL, "://", "://user:secret-pass@", 1)
+
+	for _, fields := range [][]string{nil, {"token"}} {
+		buf := captureLog(t)
+		cli := NewClient(WithMiddleware(Logging(LogConfig{RedactFields: fields})))
+		_, err := cli.R().SetQueryParam("token", "secret-query").Get(target)
+		assert.Nil(t, err)
+		_, err = cli.R().Get(target)
+		assert.Nil(t, err)
+
+		logs := buf.String()
+		assert.NotContains(t, logs, "secret-pass")
+		assert.Contains(t, logs, "user:xxxxx@")
+	}
+}
+
+func TestLoggingForm(t *testing.T) {
+	srv := echoHeaders(t)
+	buf := captureLog(t)
+	cli := NewClient(WithMiddleware(Logging(LogConfig{RedactFields: []string{"password"}, MaxBodyLog: 1024})))
+
+	resp, err := cli.R().SetFormData(map[string]string{"user": "bob", "password": "secret-pwd"}).Post(srv.URL)
+	assert.Nil(t, err)
+	assert.Contains(t, resp.String(), "secret-pwd")
+	requestLog, _, _ := strings.Cut(buf.String(), "\n")
+	assert.NotContains(t, requestLog, "secret-pwd")
+	assert.Contains(t, requestLog, "user=bob")
+}
+
+func TestLoggingMaxBody(t *testing.T) {
+	srv := echoHeaders(t)
+	body := strings.Repeat("x", 100)
+
+	buf := captureLog(t)
+	resp, err := NewClient(WithMiddleware(Logging(LogConfig{MaxBodyLog: 10}))).R().SetBody(body).Post(srv.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, body, resp.String())
+	assert.Contains(t, buf.String(), strings.Repeat("x", 10)+truncated)
+	assert.NotContains(t, buf.String(), strings.Repeat("x", 11))
+
+	buf.Reset()
+	resp, err = NewClient(WithMiddleware(Logging(LogConfig{MaxBodyLog: -1}))).R().SetBody(body).Post(srv.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, body, resp.String())
+	assert.NotContains(t, buf.String(), "xxx")
+}
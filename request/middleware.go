@@ -0,0 +1,210 @@
+package request
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tp-life/utils/codec"
+	"github.com/tp-life/utils/utils"
+)
+
+// RequestIDHeader 默认的请求ID header
+const RequestIDHeader = "X-Request-Id"
+
+const (
+	acceptEncodingHeader  = "Accept-Encoding"
+	contentEncodingHeader = "Content-Encoding"
+	contentLengthHeader   = "Content-Length"
+)
+
+type (
+	// Middleware 包装 http.RoundTripper, 在发送请求前后执行自定义逻辑
+	Middleware func(next http.RoundTripper) http.RoundTripper
+
+	// RoundTripperFunc 函数形式的 http.RoundTripper
+	RoundTripperFunc func(r *http.Request) (*http.Response, error)
+
+	// SignFunc 对即将发送的请求签名, 如设置鉴权 header
+	SignFunc func(r *http.Request) error
+
+	// closeHookBody body 关闭时执行 onClose, 用于在响应读取完毕后释放资源
+	closeHookBody struct {
+		io.ReadCloser
+		once    sync.Once
+		onClose func()
+	}
+
+	// decompressBody 首次读取时才创建解压 reader, 空 body 如 HEAD 响应不会报错
+	decompressBody struct {
+		body       io.ReadCloser
+		compressor codec.Compressor
+		reader     io.ReadCloser
+		err        error
+	}
+)
+
+// RoundTrip 实现 http.RoundTripper
+func (f RoundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// Chain 将中间件依次包装在 next 外层, 第一个中间件最先执行
+func Chain(next http.RoundTripper, middlewares ...Middleware) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		next = middlewares[i](next)
+	}
+
+	return next
+}
+
+// Timeout 请求的 context 没有设置 deadline 时, 使用 timeout 作为超时时间
+// 超时覆盖到响应 body 读取完毕, body 关闭时释放 context
+func Timeout(timeout time.Duration) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			if _, ok := r.Context().Deadline(); ok || timeout <= 0 {
+				return next.RoundTrip(r)
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			resp, err := next.RoundTrip(r.WithContext(ctx))
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+
+			resp.Body = &closeHookBody{ReadCloser: resp.Body, onClose: cancel}
+			return resp, nil
+		})
+	}
+}
+
+// Decompress 请求没有 Accept-Encoding header 时, 声明 codec 中注册的压缩格式, 并按 Content-Encoding 解压响应 body
+// 与 http.Transport 一致, 请求自带 Accept-Encoding 时由调用方自行解压
+func Decompress() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			if len(r.Header.Get(acceptEncodingHeader)) > 0 {
+				return next.RoundTrip(r)
+			}
+
+			r = cloneRequest(r)
+			r.Header.Set(acceptEncodingHeader, codec.AcceptEncoding())
+			resp, err := next.RoundTrip(r)
+			if err != nil {
+				return nil, err
+			}
+
+			compressor, ok := codec.CompressorForEncoding(resp.Header.Get(contentEncodingHeader))
+			if !ok {
+				return resp, nil
+			}
+
+			resp.Body = &decompressBody{body: resp.Body, compressor: compressor}
+			resp.Header.Del(contentEncodingHeader)
+			resp.Header.Del(contentLengthHeader)
+			resp.ContentLength = -1
+			resp.Uncompressed = true
+			return resp, nil
+		})
+	}
+}
+
+// RequestID 请求没有 header 时设置请求ID, gen 为空时使用 uuid
+func RequestID(header string, gen func(r *http.Request) string) Middleware {
+	if len(header) == 0 {
+		header = RequestIDHeader
+	}
+	if gen == nil {
+		gen = func(*http.Request) string {
+			return utils.NewUuid()
+		}
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			if len(r.Header.Get(header)) > 0 {
+				return next.RoundTrip(r)
+			}
+
+			r = cloneRequest(r)
+			r.Header.Set(header, gen(r))
+			return next.RoundTrip(r)
+		})
+	}
+}
+
+// HostHeaders 按 host 设置默认 header, 请求已有的 header 不会被覆盖
+// key 为 host 或 host:port, "*" 对所有 host 生效
+func HostHeaders(headers map[string]map[string]string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			var cloned bool
+			for _, key := range []string{r.URL.Host, r.URL.Hostname(), "*"} {
+				for k, v := range headers[strings.ToLower(key)] {
+					if len(r.Header.Get(k)) > 0 {
+						continue
+					}
+					if !cloned {
+						r = cloneRequest(r)
+						cloned = true
+					}
+					r.Header.Set(k, v)
+				}
+			}
+
+			return next.RoundTrip(r)
+		})
+	}
+}
+
+// Sign 使用自定义签名方法对请求签名, 签名失败时不发送请求
+func Sign(sign SignFunc) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			r = cloneRequest(r)
+			if err := sign(r); err != nil {
+				return nil, err
+			}
+
+			return next.RoundTrip(r)
+		})
+	}
+}
+
+func (b *closeHookBody) Close() error {
+	defer b.once.Do(b.onClose)
+	return b.ReadCloser.Close()
+}
+
+func (b *decompressBody) Read(p []byte) (int, error) {
+	if b.reader == nil && b.err == nil {
+		b.reader, b.err = b.compressor.NewReader(b.body)
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+
+	return b.reader.Read(p)
+}
+
+func (b *decompressBody) Close() error {
+	if b.reader != nil {
+		b.reader.Close()
+	}
+
+	return b.body.Close()
+}
+
+// cloneRequest RoundTripper 不能修改原请求, 修改前需要复制
+func cloneRequest(r *http.Request) *http.Request {
+	return r.Clone(r.Context())
+}
@@ -0,0 +1,172 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tp-life/utils/codec"
+)
+
+func echoHeaders(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for k, v := range r.Header {
+			w.Header()["Echo-"+k] = v
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestChainOrder(t *testing.T) {
+	var order []string
+	mw := func(name string) Middleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				order = append(order, name)
+				return next.RoundTrip(r)
+			})
+		}
+	}
+
+	srv := echoHeaders(t)
+	resp, err := NewClient(WithMiddleware(mw("a"), mw("b")), WithMiddleware(mw("c"))).R().Get(srv.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.Equal(t, []string{"a", "b", "c"}, order)
+}
+
+func TestRequestID(t *testing.T) {
+	srv := echoHeaders(t)
+	cli := NewClient(WithMiddleware(RequestID("", nil)))
+
+	resp, err := cli.R().Get(srv.URL)
+	assert.Nil(t, err)
+	assert.Len(t, resp.Header().Get("Echo-X-Request-Id"), 36)
+
+	resp, err = cli.R().SetHeader(RequestIDHeader, "fixed").Get(srv.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, "fixed", resp.Header().Get("Echo-X-Request-Id"))
+
+	resp, err = NewClient(WithMiddleware(RequestID("X-Trace", func(*http.Request) string {
+		return "generated"
+	}))).R().Get(srv.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, "generated", resp.Header().Get("Echo-X-Trace"))
+}
+
+func TestHostHeaders(t *testing.T) {
+	srv := echoHeaders(t)
+	cli := NewClient(WithMiddleware(HostHeaders(map[string]map[string]string{
+		"127.0.0.1":  {"X-Tenant": "t1", "X-App": "app"},
+		"other.host": {"X-Other": "other"},
+		"*":          {"X-All": "all", "X-Tenant": "t2"},
+	})))
+
+	resp, err := cli.R().SetHeader("X-App", "custom").Get(srv.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, "t1", resp.Header().Get("Echo-X-Tenant"))
+	assert.Equal(t, "custom", resp.Header().Get("Echo-X-App"))
+	assert.Equal(t, "all", resp.Header().Get("Echo-X-All"))
+	assert.Empty(t, resp.Header().Get("Echo-X-Other"))
+}
+
+func TestSign(t *testing.T) {
+	srv := echoHeaders(t)
+	cli := NewClient(WithMiddleware(Sign(func(r *http.Request) error {
+		r.Header.Set("Authorization", "Bearer "+r.Method)
+		return nil
+	})))
+
+	resp, err := cli.R().Post(srv.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, "Bearer POST", resp.Header().Get("Echo-Authorization"))
+
+	errSign := errors.New("sign failed")
+	_, err = NewClient(WithMiddleware(Sign(func(r *http.Request) error {
+		return errSign
+	}))).R().Get(srv.URL)
+	assert.ErrorIs(t, err, errSign)
+}
+
+func TestTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+
+	cli := NewClient(WithMiddleware(Timeout(50 * time.Millisecond)))
+	_, err := cli.R().Get(srv.URL)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// the deadline of the request takes precedence
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = NewClient(WithMiddleware(Timeout(time.Minute))).R().SetContext(ctx).Get(srv.URL)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.True(t, time.Since(start) < time.Second)
+}
+
+func TestTimeoutCoversBody(t *testing.T) {
+	srv := echoHeaders(t)
+	resp, err := NewClient(WithMiddleware(Timeout(time.Second))).R().
+		SetBody(strings.Repeat("a", 1024)).Post(srv.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, 1024, len(resp.Body()))
+}
+
+func TestDecompress(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Echo-Accept-Encoding", r.Header.Get("Accept-Encoding"))
+		encoding := r.URL.Query().Get("encoding")
+		c, ok := codec.CompressorForEncoding(encoding)
+		if !ok {
+			w.Write([]byte("hello"))
+			return
+		}
+
+		w.Header().Set("Content-Encoding", encoding)
+		if r.Method == http.MethodHead {
+			return
+		}
+
+		body, err := codec.Compress(c, []byte("hello"))
+		assert.Nil(t, err)
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	cli := NewClient(WithMiddleware(Decompress()))
+	for _, encoding := range []string{"", "gzip", "deflate", "x-snappy-framed"} {
+		t.Run(encoding, func(t *testing.T) {
+			resp, err := cli.R().SetQueryParam("encoding", encoding).Get(srv.URL)
+			assert.Nil(t, err)
+			assert.Equal(t, "hello", resp.String())
+			assert.Equal(t, "gzip, deflate", resp.Header().Get("Echo-Accept-Encoding"))
+			assert.Empty(t, resp.Header().Get("Content-Encoding"))
+		})
+	}
+
+	// the caller decodes the response if Accept-Encoding is set
+	resp, err := cli.R().SetHeader("Accept-Encoding", "deflate").
+		SetQueryParam("encoding", "deflate").Get(srv.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, "deflate", resp.Header().Get("Content-Encoding"))
+
+	resp, err = cli.R().SetQueryParam("encoding", "gzip").Head(srv.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+}
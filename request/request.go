@@ -1,18 +1,17 @@
 package request
 
 import (
+	"bytes"
 	"context"
 
 	"errors"
-	"fmt"
 	"io"
 
-	"github.com/bytedance/sonic"
-
 	"net/http"
 	"time"
 
 	"github.com/go-resty/resty/v2"
+	"github.com/tp-life/utils/jsonx"
 )
 
 // Get GET
@@ -35,16 +34,16 @@ func Delete(ctx context.Context, url string, body interface{}) (*resty.Response,
 	return doRequest(http.MethodDelete, url, NewRequest(ctx).SetBody(body))
 }
 
-// ReadJSON 解析
+// ReadJSON 解析, 状态码 >= 400 时返回 *APIError
 func ReadJSON(resp *resty.Response, out interface{}) error {
-	if code := resp.StatusCode(); code >= 400 {
-		return fmt.Errorf("StatusCode: %d, Body: %s", code, resp.Body())
+	if code := resp.StatusCode(); code >= http.StatusBadRequest {
+		return newAPIError(code, resp.Body())
 	}
 	if out == nil {
 		return nil
 	}
 
-	return sonic.Unmarshal(resp.Body(), out)
+	return jsonx.Unmarshal(resp.Body(), out)
 
 }
 
@@ -141,206 +140,93 @@ func DownAndUploadFile(ctx context.Context, srvURL, targetURL string, fn func(ct
 }
 
 // GetWithRetry GetWithRetry
+// Deprecated: 使用 WithRetry 为请求的 ctx 设置重试策略
 func GetWithRetry(url string, pathParams, urlParams map[string]string, count int, waitTime, maxWaitTime time.Duration, f func(*resty.Response, error) bool) (*resty.Response, error) {
-	client := DefaultClient()
-	// if count > 0 {
-	//	client.SetRetryCount(count)
-	//	if waitTime > 0 {
-	//		client.SetRetryWaitTime(waitTime)
-	//	}
-	//	if maxWaitTime > 0 {
-	//		client.SetRetryMaxWaitTime(maxWaitTime)
-	//	}
-	//	if f != nil {
-	//		client.AddRetryCondition(f)
-	//	}
-	// }
-
-	client.AddRetryCondition(func(res *resty.Response, err error) bool {
-		// 如果请求成功（code >= 200 and <= 299）并且 err 为nil，则不进行retry
-		return !(res.IsSuccess() && err == nil)
-	})
-
-	// 获取请求
-	request := client.R()
-
-	// 设置路径参数
-	if pathParams != nil {
-		request.SetPathParams(pathParams)
-	}
-	// 设置路由参数
-	if urlParams != nil {
-		request.SetQueryParams(urlParams)
-	}
-
-	response, err := request.Get(url)
-	if err != nil {
-		return nil, err
-	}
-	return response, nil
+	return GetWithRetryV2(url, nil, pathParams, urlParams, count, waitTime, maxWaitTime, f)
 }
 
 // PostWithRetry PostWithRetry
+// Deprecated: 使用 WithRetry 为请求的 ctx 设置重试策略, post 请求也按 count 重试, 需确认请求可以重复发送
 func PostWithRetry(url string, pathParams, urlParams map[string]string, body interface{}, count int, waitTime, maxWaitTime time.Duration, f func(*resty.Response, error) bool) (*resty.Response, error) {
-	client := DefaultClient()
-	// 设置重试参数
-	// if count > 0 {
-	//	client.SetRetryCount(count)
-	//	if waitTime > 0 {
-	//		client.SetRetryWaitTime(waitTime)
-	//	}
-	//	if maxWaitTime > 0 {
-	//		client.SetRetryMaxWaitTime(maxWaitTime)
-	//	}
-	//	if f != nil {
-	//		client.AddRetryCondition(f)
-	//	}
-	// }
-	// 获取请求
-	request := client.R()
-
-	// 设置路径参数
-	if pathParams != nil {
-		request.SetPathParams(pathParams)
-	}
-
-	// 设置路由参数
-	if urlParams != nil {
-		request.SetQueryParams(urlParams)
-	}
-
-	if body != nil {
-		request.SetBody(body)
-	}
-	// 响应结果
-	response, err := request.Post(url)
-	if err != nil {
-		return nil, err
-	}
-	return response, nil
+	return PostWithRetryV2(url, nil, pathParams, urlParams, body, count, waitTime, maxWaitTime, f)
 }
 
 // GetWithRetryV2 get 请求 带重试机制 有header
+// Deprecated: 使用 WithRetry 为请求的 ctx 设置重试策略
 func GetWithRetryV2(url string, head, pathParams, urlParams map[string]string, count int, waitTime, maxWaitTime time.Duration, f func(*resty.Response, error) bool) (*resty.Response, error) {
-	client := DefaultClient()
-	// if count > 0 {
-	//	client.SetRetryCount(count)
-	//	if waitTime > 0 {
-	//		client.SetRetryWaitTime(waitTime)
-	//	}
-	//	if maxWaitTime > 0 {
-	//		client.SetRetryMaxWaitTime(maxWaitTime)
-	//	}
-	//	if f != nil {
-	//		client.AddRetryCondition(f)
-	//	}
-	// }
-	// 获取请求
-	request := client.R()
-	for k, v := range head {
-		request.SetHeader(k, v)
-	}
-	// 设置路径参数
-	if pathParams != nil {
-		request.SetPathParams(pathParams)
-	}
-	// 设置路由参数
-	if urlParams != nil {
-		request.SetQueryParams(urlParams)
-	}
-	response, err := request.Get(url)
-	if err != nil {
-		return nil, err
-	}
-	return response, nil
+	request := retryRequest(count, waitTime, maxWaitTime, f).
+		SetHeaders(head).
+		SetPathParams(pathParams).
+		SetQueryParams(urlParams)
+
+	return request.Get(url)
 }
 
 // PostWithRetryV2 post 请求 带重试机制 有header
+// Deprecated: 使用 WithRetry 为请求的 ctx 设置重试策略, post 请求也按 count 重试, 需确认请求可以重复发送
 func PostWithRetryV2(url string, head, pathParams, urlParams map[string]string, body interface{}, count int, waitTime, maxWaitTime time.Duration, f func(*resty.Response, error) bool) (*resty.Response, error) {
-	client := DefaultClient()
-	// 设置重试参数
-	// if count > 0 {
-	//	client.SetRetryCount(count)
-	//	if waitTime > 0 {
-	//		client.SetRetryWaitTime(waitTime)
-	//	}
-	//	if maxWaitTime > 0 {
-	//		client.SetRetryMaxWaitTime(maxWaitTime)
-	//	}
-	//	if f != nil {
-	//		client.AddRetryCondition(f)
-	//	}
-	// }
-	// 获取请求
-	request := client.R()
-	for k, v := range head {
-		request.SetHeader(k, v)
-	}
-	// 设置路径参数
-	if pathParams != nil {
-		request.SetPathParams(pathParams)
-	}
-
-	// 设置路由参数
-	if urlParams != nil {
-		request.SetQueryParams(urlParams)
-	}
-
+	request := retryRequest(count, waitTime, maxWaitTime, f).
+		SetHeaders(head).
+		SetPathParams(pathParams).
+		SetQueryParams(urlParams)
 	if body != nil {
 		request.SetBody(body)
 	}
-	// 响应结果
-	response, err := request.Post(url)
-	if err != nil {
-		return nil, err
-	}
-	return response, nil
+
+	return request.Post(url)
 }
 
 // PostFileWithRetry PostFileWithRetry
+// Deprecated: 使用 WithRetry 为请求的 ctx 设置重试策略, post 请求也按 count 重试, 需确认请求可以重复发送
 func PostFileWithRetry(url string, pathParams, urlParams map[string]string, param, fileName, contentType string, reader io.Reader, count int, waitTime, maxWaitTime time.Duration, f func(*resty.Response, error) bool) (*resty.Response, error) {
-	client := DefaultClient()
-	// 设置重试参数
-	if count > 0 {
-		client.SetRetryCount(count)
-		if waitTime > 0 {
-			client.SetRetryWaitTime(waitTime)
-		}
-		if maxWaitTime > 0 {
-			client.SetRetryMaxWaitTime(maxWaitTime)
-		}
-		if f != nil {
-			client.AddRetryCondition(f)
+	request := retryRequest(count, waitTime, maxWaitTime, f).
+		SetPathParams(pathParams).
+		SetQueryParams(urlParams)
+
+	content := make([]byte, 2)
+	n, err := io.ReadFull(reader, content)
+	if n == 0 {
+		if err == nil || err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, errors.New("there is nothing read")
 		}
+		return nil, err
 	}
-	// 获取请求
-	request := client.R()
+	// 设置文件传参数, 补回已读取的内容
+	request.SetMultipartField(param, fileName, contentType, io.MultiReader(bytes.NewReader(content[:n]), reader))
 
-	// 设置路径参数
-	if pathParams != nil {
-		request.SetPathParams(pathParams)
-	}
+	return request.Post(url)
+}
 
-	// 设置路由参数
-	if urlParams != nil {
-		request.SetQueryParams(urlParams)
+// retryRequest 将旧的重试参数转换为 RetryPolicy, count 为重试次数
+// 调用方指定了重试次数, 非幂等方法也重试
+func retryRequest(count int, waitTime, maxWaitTime time.Duration, f func(*resty.Response, error) bool) *resty.Request {
+	request := DefaultRequest(context.Background())
+	opts := []RetryOption{WithMaxAttempts(count + 1), WithNonIdempotentRetry()}
+	if waitTime > 0 {
+		opts = append(opts, WithBackoff(waitTime, maxWaitTime))
 	}
-	content := make([]byte, 2)
-	n, err := reader.Read(content)
-	if err != nil {
-		return nil, err
+	if f != nil {
+		opts = append(opts, WithRetryIf(func(resp *http.Response, err error) bool {
+			res, readErr := bufferResponse(request, resp)
+			if err == nil {
+				err = readErr
+			}
+			return f(res, err)
+		}))
 	}
 
-	if n != 2 {
-		return nil, errors.New("there is nothing read")
-	}
-	// 设置文件传参数
-	request.SetMultipartField(param, fileName, contentType, reader)
+	return request.SetContext(WithRetry(context.Background(), opts...))
+}
 
-	// 响应结果
-	response, err := request.Post(url)
-	if err != nil {
-		return nil, err
+// bufferResponse 读取响应 body 供回调使用, 并替换为可以再次读取的 body
+func bufferResponse(request *resty.Request, resp *http.Response) (*resty.Response, error) {
+	res := &resty.Response{Request: request, RawResponse: resp}
+	if resp == nil {
+		return res, nil
 	}
-	return response, nil
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return res.SetBody(body), err
 }
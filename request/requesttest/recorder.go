@@ -0,0 +1,320 @@
+package requesttest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/tp-life/utils/request"
+)
+
+const (
+	// ModeReplay serves the interactions saved in the golden file, without network.
+	ModeReplay Mode = iota
+	// ModeRecord sends requests to the real servers and saves the interactions.
+	ModeRecord
+
+	// ModeEnv is the environment variable to switch Recorders to ModeRecord,
+	// e.g. REQUESTTEST_MODE=record go test ./...
+	ModeEnv = "REQUESTTEST_MODE"
+
+	redacted      = "******"
+	base64Encoded = "base64"
+)
+
+type (
+	// Mode is the mode of a Recorder.
+	Mode int
+
+	// RecorderOption customizes a Recorder.
+	RecorderOption func(r *Recorder)
+
+	// Recorder is an http.RoundTripper that records real interactions to a
+	// golden file, or replays them from it.
+	Recorder struct {
+		history
+		t             testing.TB
+		golden        string
+		mode          Mode
+		next          http.RoundTripper
+		redactHeaders map[string]struct{}
+
+		lock         sync.Mutex
+		interactions []*interaction
+	}
+
+	interaction struct {
+		Request  recordedRequest  `json:"request"`
+		Response recordedResponse `json:"response"`
+		used     bool
+	}
+
+	recordedRequest struct {
+		Method       string      `json:"method"`
+		URL          string      `json:"url"`
+		Header       http.Header `json:"header,omitempty"`
+		Body         string      `json:"body,omitempty"`
+		BodyEncoding string      `json:"body_encoding,omitempty"`
+	}
+
+	recordedResponse struct {
+		StatusCode   int         `json:"status_code"`
+		Header       http.Header `json:"header,omitempty"`
+		Body         string      `json:"body,omitempty"`
+		BodyEncoding string      `json:"body_encoding,omitempty"`
+	}
+
+	goldenFile struct {
+		Interactions []*interaction `json:"interactions"`
+	}
+)
+
+// ModeFromEnv returns ModeRecord if ModeEnv is set to record, otherwise ModeReplay.
+func ModeFromEnv() Mode {
+	if strings.EqualFold(os.Getenv(ModeEnv), "record") {
+		return ModeRecord
+	}
+
+	return ModeReplay
+}
+
+// WithMode sets the mode of the Recorder, defaults to ModeFromEnv.
+func WithMode(mode Mode) RecorderOption {
+	return func(r *Recorder) {
+		r.mode = mode
+	}
+}
+
+// WithRedactHeaders adds headers to redact in the golden file,
+// request.DefaultRedactHeaders are always redacted.
+func WithRedactHeaders(headers ...string) RecorderOption {
+	return func(r *Recorder) {
+		for _, h := range headers {
+			r.redactHeaders[http.CanonicalHeaderKey(h)] = struct{}{}
+		}
+	}
+}
+
+// WithRecordTransport sets the transport to send requests in ModeRecord,
+// defaults to http.DefaultTransport.
+func WithRecordTransport(next http.RoundTripper) RecorderOption {
+	return func(r *Recorder) {
+		r.next = next
+	}
+}
+
+// NewRecorder returns a Recorder with the golden file, usually in testdata.
+// In ModeRecord the golden file is written when the test finishes.
+func NewRecorder(t testing.TB, golden string, opts ...RecorderOption) *Recorder {
+	r := &Recorder{
+		t:             t,
+		golden:        golden,
+		mode:          ModeFromEnv(),
+		next:          http.DefaultTransport,
+		redactHeaders: make(map[string]struct{}),
+	}
+	for _, h := range request.DefaultRedactHeaders {
+		r.redactHeaders[http.CanonicalHeaderKey(h)] = struct{}{}
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if r.mode == ModeRecord {
+		t.Cleanup(func() {
+			if err := r.save(); err != nil {
+				t.Errorf("requesttest: save golden file %s: %v", r.golden, err)
+			}
+		})
+		return r
+	}
+
+	if err := r.load(); err != nil {
+		t.Fatalf("requesttest: load golden file %s: %v", r.golden, err)
+	}
+
+	return r
+}
+
+// Client returns a request client that sends requests through r.
+func (r *Recorder) Client(opts ...request.Option) *resty.Client {
+	return request.NewClient(append(opts, request.WithTransport(r))...)
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	sent, err := r.record(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.mode == ModeRecord {
+		return r.forward(req, sent)
+	}
+
+	return r.replay(req, sent)
+}
+
+func (r *Recorder) forward(req *http.Request, sent Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(sent.Body))
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	in := &interaction{
+		Request: recordedRequest{
+			Method: sent.Method,
+			URL:    sent.URL,
+			Header: r.redact(sent.Header),
+		},
+		Response: recordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     r.redact(resp.Header),
+		},
+	}
+	in.Request.Body, in.Request.BodyEncoding = encodeRecorded(sent.Body)
+	in.Response.Body, in.Response.BodyEncoding = encodeRecorded(body)
+
+	r.lock.Lock()
+	r.interactions = append(r.interactions, in)
+	r.lock.Unlock()
+
+	return resp, nil
+}
+
+func (r *Recorder) replay(req *http.Request, sent Request) (*http.Response, error) {
+	r.lock.Lock()
+	in := r.match(sent)
+	r.lock.Unlock()
+	if in == nil {
+		r.t.Errorf("requesttest: no recorded interaction for %s %s", sent.Method, sent.URL)
+		return nil, fmt.Errorf("requesttest: no recorded interaction for %s %s", sent.Method, sent.URL)
+	}
+
+	body, err := decodeRecorded(in.Response.Body, in.Response.BodyEncoding)
+	if err != nil {
+		return nil, err
+	}
+
+	header := in.Response.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", in.Response.StatusCode, http.StatusText(in.Response.StatusCode)),
+		StatusCode:    in.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// match returns the first unused interaction with the same method, url and body,
+// or with the same method and url if the body differs, e.g. with generated ids.
+func (r *Recorder) match(sent Request) *interaction {
+	var candidate *interaction
+	for _, in := range r.interactions {
+		if in.used || in.Request.Method != sent.Method || in.Request.URL != sent.URL {
+			continue
+		}
+
+		body, err := decodeRecorded(in.Request.Body, in.Request.BodyEncoding)
+		if err == nil && bytes.Equal(body, sent.Body) {
+			candidate = in
+			break
+		}
+		if candidate == nil {
+			candidate = in
+		}
+	}
+
+	if candidate != nil {
+		candidate.used = true
+	}
+
+	return candidate
+}
+
+func (r *Recorder) redact(header http.Header) http.Header {
+	if len(header) == 0 {
+		return nil
+	}
+
+	result := header.Clone()
+	for key := range result {
+		if _, ok := r.redactHeaders[http.CanonicalHeaderKey(key)]; ok {
+			result[key] = []string{redacted}
+		}
+	}
+
+	return result
+}
+
+func (r *Recorder) load() error {
+	content, err := os.ReadFile(r.golden)
+	if err != nil {
+		return err
+	}
+
+	var file goldenFile
+	if err = json.Unmarshal(content, &file); err != nil {
+		return err
+	}
+
+	r.interactions = file.Interactions
+	return nil
+}
+
+func (r *Recorder) save() error {
+	r.lock.Lock()
+	content, err := json.MarshalIndent(goldenFile{Interactions: r.interactions}, "", "  ")
+	r.lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(r.golden), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(r.golden, append(content, '\n'), 0o644)
+}
+
+func encodeRecorded(body []byte) (string, string) {
+	if utf8.Valid(body) {
+		return string(body), ""
+	}
+
+	return base64.StdEncoding.EncodeToString(body), base64Encoded
+}
+
+func decodeRecorded(body, encoding string) ([]byte, error) {
+	if encoding == base64Encoded {
+		return base64.StdEncoding.DecodeString(body)
+	}
+
+	return []byte(body), nil
+}
@@ -0,0 +1,83 @@
+package requesttest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorder(t *testing.T) {
+	golden := filepath.Join(t.TempDir(), "testdata", "users.json")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Header().Set("X-Path", r.URL.Path)
+		switch r.URL.Path {
+		case "/binary":
+			w.Write([]byte{0xff, 0xfe, 0x00})
+		default:
+			w.Write(append([]byte("echo:"), body...))
+		}
+	}))
+
+	t.Run("record", func(t *testing.T) {
+		rec := NewRecorder(t, golden, WithMode(ModeRecord), WithRedactHeaders("X-Secret"))
+		cli := rec.Client()
+
+		resp, err := cli.R().SetHeader("Authorization", "Bearer token").SetHeader("X-Secret", "s").
+			SetBody("first").Post(srv.URL + "/users")
+		assert.Nil(t, err)
+		assert.Equal(t, "echo:first", resp.String())
+		resp, err = cli.R().SetBody("second").Post(srv.URL + "/users")
+		assert.Nil(t, err)
+		assert.Equal(t, "echo:second", resp.String())
+		resp, err = cli.R().Get(srv.URL + "/binary")
+		assert.Nil(t, err)
+		assert.Equal(t, []byte{0xff, 0xfe, 0x00}, resp.Body())
+	})
+	srv.Close()
+
+	content, err := os.ReadFile(golden)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(content), "Bearer token")
+	assert.NotContains(t, string(content), "session=secret")
+	assert.NotContains(t, string(content), `"s"`)
+	assert.Contains(t, string(content), "******")
+
+	t.Run("replay", func(t *testing.T) {
+		rec := NewRecorder(t, golden, WithMode(ModeReplay))
+		cli := rec.Client()
+
+		// matched by body first, regardless of order
+		resp, err := cli.R().SetBody("second").Post(srv.URL + "/users")
+		assert.Nil(t, err)
+		assert.Equal(t, "echo:second", resp.String())
+		assert.Equal(t, "/users", resp.Header().Get("X-Path"))
+		resp, err = cli.R().SetBody("changed").Post(srv.URL + "/users")
+		assert.Nil(t, err)
+		assert.Equal(t, "echo:first", resp.String())
+		resp, err = cli.R().Get(srv.URL + "/binary")
+		assert.Nil(t, err)
+		assert.Equal(t, []byte{0xff, 0xfe, 0x00}, resp.Body())
+
+		rec.AssertCount(t, http.MethodPost, "/users", 2)
+
+		mock := new(testing.T)
+		rec.t = mock
+		_, err = cli.R().Get(srv.URL + "/binary")
+		assert.NotNil(t, err)
+		assert.True(t, mock.Failed())
+	})
+}
+
+func TestModeFromEnv(t *testing.T) {
+	t.Setenv(ModeEnv, "")
+	assert.Equal(t, ModeReplay, ModeFromEnv())
+	t.Setenv(ModeEnv, "RECORD")
+	assert.Equal(t, ModeRecord, ModeFromEnv())
+}
@@ -0,0 +1,215 @@
+package requesttest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/tp-life/utils/jsonx"
+	"github.com/tp-life/utils/request"
+)
+
+type (
+	// Request is a request sent through a Transport or Recorder.
+	Request struct {
+		Method string
+		URL    string
+		Header http.Header
+		Body   []byte
+	}
+
+	// Transport is an in-process fake http.RoundTripper, requests are routed
+	// to handlers registered with http.ServeMux patterns, like "GET /users/{id}"
+	// or "POST api.example.com/orders".
+	Transport struct {
+		history
+		t   testing.TB
+		mux *http.ServeMux
+	}
+
+	history struct {
+		lock     sync.Mutex
+		requests []Request
+	}
+)
+
+// NewTransport returns a Transport, requests without a matching route fail the test.
+func NewTransport(t testing.TB) *Transport {
+	return &Transport{
+		t:   t,
+		mux: http.NewServeMux(),
+	}
+}
+
+// Handle registers handler for the given http.ServeMux pattern.
+func (tr *Transport) Handle(pattern string, handler http.HandlerFunc) {
+	tr.mux.HandleFunc(pattern, handler)
+}
+
+// Reply registers a handler that replies with status and body for the given pattern.
+// body can be a string, []byte or any value marshaled to json.
+func (tr *Transport) Reply(pattern string, status int, body any) {
+	content, contentType, err := encodeBody(body)
+	if err != nil {
+		tr.t.Fatalf("requesttest: encode reply of %q: %v", pattern, err)
+	}
+
+	tr.Handle(pattern, func(w http.ResponseWriter, r *http.Request) {
+		if len(contentType) > 0 {
+			w.Header().Set("Content-Type", contentType)
+		}
+		w.WriteHeader(status)
+		w.Write(content)
+	})
+}
+
+// Client returns a request client that sends requests through tr.
+func (tr *Transport) Client(opts ...request.Option) *resty.Client {
+	return request.NewClient(append(opts, request.WithTransport(tr))...)
+}
+
+// RoundTrip implements http.RoundTripper.
+func (tr *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	sent, err := tr.record(r)
+	if err != nil {
+		return nil, err
+	}
+
+	req := r.Clone(r.Context())
+	req.Host = r.URL.Host
+	req.RequestURI = r.URL.RequestURI()
+	req.Body = io.NopCloser(bytes.NewReader(sent.Body))
+	if _, pattern := tr.mux.Handler(req); len(pattern) == 0 {
+		tr.t.Errorf("requesttest: no route for %s %s", r.Method, r.URL)
+		return nil, fmt.Errorf("requesttest: no route for %s %s", r.Method, r.URL)
+	}
+
+	rec := httptest.NewRecorder()
+	tr.mux.ServeHTTP(rec, req)
+	resp := rec.Result()
+	resp.Request = r
+	return resp, nil
+}
+
+// Requests returns the sent requests in order.
+func (h *history) Requests() []Request {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	return append([]Request(nil), h.requests...)
+}
+
+// Count returns the number of sent requests with the given method and URL path,
+// an empty method matches all methods.
+func (h *history) Count(method, path string) int {
+	var count int
+	for _, r := range h.Requests() {
+		if r.matches(method, path) {
+			count++
+		}
+	}
+
+	return count
+}
+
+// AssertCalled asserts that a request with the given method and URL path was sent,
+// and returns the last one.
+func (h *history) AssertCalled(t testing.TB, method, path string) Request {
+	t.Helper()
+
+	requests := h.Requests()
+	for i := len(requests) - 1; i >= 0; i-- {
+		if requests[i].matches(method, path) {
+			return requests[i]
+		}
+	}
+
+	t.Errorf("requesttest: expected %s %s to be called, sent requests:\n%s", method, path, formatRequests(requests))
+	return Request{}
+}
+
+// AssertNotCalled asserts that no request with the given method and URL path was sent.
+func (h *history) AssertNotCalled(t testing.TB, method, path string) {
+	t.Helper()
+
+	if count := h.Count(method, path); count > 0 {
+		t.Errorf("requesttest: expected %s %s not to be called, but called %d times", method, path, count)
+	}
+}
+
+// AssertCount asserts that the requests with the given method and URL path were sent n times.
+func (h *history) AssertCount(t testing.TB, method, path string, n int) {
+	t.Helper()
+
+	if count := h.Count(method, path); count != n {
+		t.Errorf("requesttest: expected %s %s to be called %d times, but called %d times", method, path, n, count)
+	}
+}
+
+func (h *history) record(r *http.Request) (Request, error) {
+	var body []byte
+	if r.Body != nil {
+		var err error
+		if body, err = io.ReadAll(r.Body); err != nil {
+			return Request{}, err
+		}
+		r.Body.Close()
+	}
+
+	sent := Request{
+		Method: r.Method,
+		URL:    r.URL.String(),
+		Header: r.Header.Clone(),
+		Body:   body,
+	}
+
+	h.lock.Lock()
+	h.requests = append(h.requests, sent)
+	h.lock.Unlock()
+
+	return sent, nil
+}
+
+// JSON unmarshals the request body into v.
+func (r Request) JSON(v any) error {
+	return jsonx.Unmarshal(r.Body, v)
+}
+
+func (r Request) matches(method, path string) bool {
+	if len(method) > 0 && method != r.Method {
+		return false
+	}
+
+	u, err := url.Parse(r.URL)
+	return err == nil && u.Path == path
+}
+
+func encodeBody(body any) ([]byte, string, error) {
+	switch v := body.(type) {
+	case nil:
+		return nil, "", nil
+	case string:
+		return []byte(v), "text/plain; charset=utf-8", nil
+	case []byte:
+		return v, "", nil
+	default:
+		content, err := jsonx.Marshal(v)
+		return content, "application/json", err
+	}
+}
+
+func formatRequests(requests []Request) string {
+	var buf strings.Builder
+	for _, r := range requests {
+		fmt.Fprintf(&buf, "\t%s %s\n", r.Method, r.URL)
+	}
+
+	return buf.String()
+}
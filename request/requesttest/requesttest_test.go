@@ -0,0 +1,60 @@
+package requesttest
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tp-life/utils/request"
+)
+
+type user struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestTransport(t *testing.T) {
+	tr := NewTransport(t)
+	tr.Reply("GET api.example.com/users/{id}", http.StatusOK, user{Id: 1, Name: "bob"})
+	tr.Handle("POST /users", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(r.Header.Get("X-Tenant")))
+	})
+	cli := tr.Client()
+
+	u, err := request.Do[user](context.Background(), request.Req{
+		URL:    "http://api.example.com/users/1",
+		Client: cli,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, user{Id: 1, Name: "bob"}, u)
+
+	resp, err := cli.R().SetHeader("X-Tenant", "t1").SetBody(user{Name: "alice"}).Post("http://other.example.com/users")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode())
+	assert.Equal(t, "t1", resp.String())
+
+	sent := tr.AssertCalled(t, http.MethodPost, "/users")
+	var body user
+	assert.Nil(t, sent.JSON(&body))
+	assert.Equal(t, "alice", body.Name)
+	assert.Equal(t, "t1", sent.Header.Get("X-Tenant"))
+	tr.AssertCount(t, "", "/users/1", 1)
+	tr.AssertNotCalled(t, http.MethodDelete, "/users/1")
+	assert.Len(t, tr.Requests(), 2)
+}
+
+func TestTransportNoRoute(t *testing.T) {
+	mock := new(testing.T)
+	tr := NewTransport(mock)
+	tr.Reply("GET /users", http.StatusOK, "[]")
+
+	_, err := tr.Client().R().Post("http://api.example.com/users")
+	assert.NotNil(t, err)
+	assert.True(t, mock.Failed())
+
+	var failed testing.T
+	tr.AssertCalled(&failed, http.MethodGet, "/users")
+	assert.True(t, failed.Failed())
+}
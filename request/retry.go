@@ -0,0 +1,311 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// IdempotencyKeyHeader 幂等键 header, 设置后非幂等方法也可以重试
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	defaultRetryAttempts  = 3
+	defaultRetryBaseDelay = 100 * time.Millisecond
+	defaultRetryMaxDelay  = 5 * time.Second
+	defaultMaxRetryAfter  = time.Minute
+	drainBodyLimit        = 4096
+)
+
+type (
+	// RetryPolicy 重试策略
+	RetryPolicy struct {
+		// MaxAttempts 最大请求次数, 包含第一次请求, 小于等于1时不重试
+		MaxAttempts int
+		// BaseDelay 和 MaxDelay 指数退避的初始间隔和最大间隔, 实际间隔为 [0, 退避间隔) 的随机值
+		BaseDelay time.Duration
+		MaxDelay  time.Duration
+		// Deadline 包含所有重试在内的总超时时间, 为0时不限制
+		Deadline time.Duration
+		// RetryStatus 需要重试的状态码
+		RetryStatus []int
+		// RetryIf 自定义是否重试, 优先于 RetryStatus 和网络错误判断
+		RetryIf func(resp *http.Response, err error) bool
+		// IdempotencyHeader 非幂等方法只有设置了该 header 才重试
+		IdempotencyHeader string
+		// NonIdempotent 非幂等方法也重试, 调用方需确认请求可以重复发送
+		NonIdempotent bool
+		// MaxRetryAfter 响应的 Retry-After 超过该值时不再重试, 直接返回本次结果, 为0时使用默认的1分钟
+		MaxRetryAfter time.Duration
+	}
+
+	// RetryOption 自定义重试策略的方法
+	RetryOption func(p *RetryPolicy)
+
+	retryKey struct{}
+)
+
+// DefaultRetryPolicy 默认重试策略, 最多请求3次, 重试 429、502、503、504 和网络错误
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: defaultRetryAttempts,
+		BaseDelay:   defaultRetryBaseDelay,
+		MaxDelay:    defaultRetryMaxDelay,
+		RetryStatus: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+		IdempotencyHeader: IdempotencyKeyHeader,
+	}
+}
+
+// WithMaxAttempts 设置最大请求次数
+func WithMaxAttempts(attempts int) RetryOption {
+	return func(p *RetryPolicy) {
+		p.MaxAttempts = attempts
+	}
+}
+
+// WithBackoff 设置退避的初始间隔和最大间隔
+func WithBackoff(base, max time.Duration) RetryOption {
+	return func(p *RetryPolicy) {
+		p.BaseDelay = base
+		p.MaxDelay = max
+	}
+}
+
+// WithRetryDeadline 设置包含所有重试在内的总超时时间
+func WithRetryDeadline(deadline time.Duration) RetryOption {
+	return func(p *RetryPolicy) {
+		p.Deadline = deadline
+	}
+}
+
+// WithRetryStatus 设置需要重试的状态码
+func WithRetryStatus(codes ...int) RetryOption {
+	return func(p *RetryPolicy) {
+		p.RetryStatus = codes
+	}
+}
+
+// WithNonIdempotentRetry 非幂等方法也重试, 调用方需确认请求可以重复发送
+func WithNonIdempotentRetry() RetryOption {
+	return func(p *RetryPolicy) {
+		p.NonIdempotent = true
+	}
+}
+
+// WithMaxRetryAfter 设置可以等待的最长 Retry-After
+func WithMaxRetryAfter(max time.Duration) RetryOption {
+	return func(p *RetryPolicy) {
+		p.MaxRetryAfter = max
+	}
+}
+
+// WithRetryIf 自定义是否重试
+func WithRetryIf(retryIf func(resp *http.Response, err error) bool) RetryOption {
+	return func(p *RetryPolicy) {
+		p.RetryIf = retryIf
+	}
+}
+
+// WithRetry 为 ctx 上的请求设置重试策略, opts 在默认策略基础上修改
+func WithRetry(ctx context.Context, opts ...RetryOption) context.Context {
+	p := DefaultRetryPolicy()
+	for _, opt := range opts {
+		opt(&p)
+	}
+
+	return context.WithValue(ctx, retryKey{}, p)
+}
+
+// Retry 按重试策略重试请求, 请求 ctx 上通过 WithRetry 设置的策略优先
+// 请求 body 无法重放时不重试
+func Retry(opts ...RetryOption) Middleware {
+	p := DefaultRetryPolicy()
+	for _, opt := range opts {
+		opt(&p)
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			policy, ok := r.Context().Value(retryKey{}).(RetryPolicy)
+			if !ok {
+				policy = p
+			}
+			if !policy.retryable(r) {
+				return next.RoundTrip(r)
+			}
+
+			return policy.do(next, r)
+		})
+	}
+}
+
+func (p RetryPolicy) do(next http.RoundTripper, r *http.Request) (*http.Response, error) {
+	ctx := r.Context()
+	cancel := context.CancelFunc(func() {})
+	if p.Deadline > 0 {
+		ctx, cancel = context.WithTimeout(ctx, p.Deadline)
+		r = r.WithContext(ctx)
+	}
+
+	for attempt := 1; ; attempt++ {
+		req := r
+		if attempt > 1 {
+			req = r.Clone(ctx)
+			if r.GetBody != nil {
+				body, err := r.GetBody()
+				if err != nil {
+					cancel()
+					return nil, err
+				}
+				req.Body = body
+			}
+		}
+
+		resp, err := next.RoundTrip(req)
+		if attempt >= p.MaxAttempts || !p.shouldRetry(ctx, resp, err) {
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+			resp.Body = &closeHookBody{ReadCloser: resp.Body, onClose: cancel}
+			return resp, nil
+		}
+
+		delay := p.backoff(attempt)
+		var tooLong bool
+		if resp != nil {
+			if after, ok := retryAfter(resp.Header.Get("Retry-After")); ok && after > delay {
+				delay = after
+				tooLong = after > p.maxRetryAfter()
+			}
+		}
+		// Retry-After 过长或剩余时间不足以等待下一次重试, 返回本次结果
+		if deadline, ok := ctx.Deadline(); tooLong || ok && time.Until(deadline) <= delay {
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+			resp.Body = &closeHookBody{ReadCloser: resp.Body, onClose: cancel}
+			return resp, nil
+		}
+		if resp != nil {
+			drainBody(resp.Body)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			cancel()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryable 只重试 body 可以重放的幂等请求, 或设置了幂等键的请求
+func (p RetryPolicy) retryable(r *http.Request) bool {
+	if p.MaxAttempts <= 1 {
+		return false
+	}
+	if r.Body != nil && r.Body != http.NoBody && r.GetBody == nil {
+		return false
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace,
+		http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return p.NonIdempotent || len(p.IdempotencyHeader) > 0 && len(r.Header.Get(p.IdempotencyHeader)) > 0
+	}
+}
+
+func (p RetryPolicy) maxRetryAfter() time.Duration {
+	if p.MaxRetryAfter > 0 {
+		return p.MaxRetryAfter
+	}
+
+	return defaultMaxRetryAfter
+}
+
+func (p RetryPolicy) shouldRetry(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if p.RetryIf != nil {
+		return p.RetryIf(resp, err)
+	}
+	if err != nil {
+		// 熔断和限流时重试只会加重下游负担
+		var be *BreakerError
+		return !errors.As(err, &be) && !errors.Is(err, ErrConcurrencyLimit)
+	}
+
+	for _, code := range p.RetryStatus {
+		if resp.StatusCode == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// backoff 指数退避加全抖动, 见 https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	if p.BaseDelay <= 0 {
+		return 0
+	}
+
+	limit := p.MaxDelay
+	if limit <= 0 {
+		limit = math.MaxInt64
+	}
+
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < limit; i++ {
+		// 翻倍前判断, 避免溢出
+		if delay > limit/2 {
+			delay = limit
+			break
+		}
+		delay *= 2
+	}
+	delay = min(delay, limit)
+
+	return time.Duration(rand.Int63n(int64(delay)))
+}
+
+// retryAfter 解析 Retry-After, 支持秒数和 HTTP 日期
+func retryAfter(val string) (time.Duration, bool) {
+	if len(val) == 0 {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(val); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(val); err == nil {
+		return time.Until(t), true
+	}
+
+	return 0, false
+}
+
+// drainBody 读取少量剩余内容后关闭, 使连接可以复用
+func drainBody(body io.ReadCloser) {
+	io.Copy(io.Discard, io.LimitReader(body, drainBodyLimit))
+	body.Close()
+}
@@ -0,0 +1,209 @@
+package request
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func flakyServer(t *testing.T, failures int32, status int) (*httptest.Server, *int32) {
+	t.Helper()
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if atomic.AddInt32(&calls, 1) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestRetryStatus(t *testing.T) {
+	srv, calls := flakyServer(t, 2, http.StatusServiceUnavailable)
+	cli := NewClient(WithMiddleware(Retry(WithBackoff(time.Millisecond, 10*time.Millisecond))))
+
+	resp, err := cli.R().SetBody("payload").Put(srv.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.Equal(t, "payload", resp.String())
+	assert.Equal(t, int32(3), atomic.LoadInt32(calls))
+}
+
+func TestRetryExhausted(t *testing.T) {
+	srv, calls := flakyServer(t, 10, http.StatusBadGateway)
+	cli := NewClient(WithMiddleware(Retry(WithMaxAttempts(2), WithBackoff(time.Millisecond, time.Millisecond))))
+
+	resp, err := cli.R().Get(srv.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode())
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+}
+
+func TestRetryNotRetryableStatus(t *testing.T) {
+	srv, calls := flakyServer(t, 10, http.StatusInternalServerError)
+	cli := NewClient(WithMiddleware(Retry(WithBackoff(time.Millisecond, time.Millisecond))))
+
+	resp, err := cli.R().Get(srv.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode())
+	assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+}
+
+func TestRetryIdempotency(t *testing.T) {
+	srv, calls := flakyServer(t, 2, http.StatusServiceUnavailable)
+	cli := NewClient(WithMiddleware(Retry(WithBackoff(time.Millisecond, time.Millisecond))))
+
+	resp, err := cli.R().SetBody("a").Post(srv.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode())
+	assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+
+	resp, err = cli.R().SetBody("a").SetHeader(IdempotencyKeyHeader, "key").Post(srv.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.Equal(t, "a", resp.String())
+	assert.Equal(t, int32(3), atomic.LoadInt32(calls))
+}
+
+func TestRetryContextPolicy(t *testing.T) {
+	srv, calls := flakyServer(t, 1, http.StatusTooManyRequests)
+	cli := NewClient(WithMiddleware(Retry(WithMaxAttempts(1))))
+
+	resp, err := cli.R().Get(srv.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode())
+
+	ctx := WithRetry(context.Background(), WithBackoff(time.Millisecond, time.Millisecond))
+	resp, err = cli.R().SetContext(ctx).Get(srv.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+}
+
+func TestRetryNetworkError(t *testing.T) {
+	var calls int32
+	cli := NewClient(
+		WithTransport(RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("ok")), Request: r}, nil
+		})),
+		WithMiddleware(Retry(WithBackoff(time.Millisecond, time.Millisecond))),
+	)
+
+	resp, err := cli.R().Get("http://example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, "ok", resp.String())
+	assert.Equal(t, int32(2), calls)
+}
+
+func TestRetryAfterAndDeadline(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Retry-After", "10")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	cli := NewClient(WithMiddleware(Retry(WithBackoff(time.Millisecond, time.Millisecond), WithRetryDeadline(time.Second))))
+	start := time.Now()
+	resp, err := cli.R().Get(srv.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	assert.True(t, time.Since(start) < time.Second)
+}
+
+func TestRetryAfter(t *testing.T) {
+	d, ok := retryAfter("2")
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Second, d)
+	d, ok = retryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	assert.True(t, ok)
+	assert.True(t, d > 58*time.Second)
+	_, ok = retryAfter("")
+	assert.False(t, ok)
+	_, ok = retryAfter("-1")
+	assert.False(t, ok)
+	_, ok = retryAfter("soon")
+	assert.False(t, ok)
+}
+
+func TestBackoff(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+	for attempt := 1; attempt < 100; attempt++ {
+		d := p.backoff(attempt)
+		assert.True(t, d >= 0 && d < 50*time.Millisecond)
+	}
+	assert.Equal(t, time.Duration(0), RetryPolicy{}.backoff(3))
+
+	// no overflow without max delay
+	p = RetryPolicy{BaseDelay: time.Second}
+	for _, attempt := range []int{1, 40, 64, 1000} {
+		assert.True(t, p.backoff(attempt) >= 0)
+	}
+}
+
+func TestGetWithRetry(t *testing.T) {
+	srv, calls := flakyServer(t, 1, http.StatusInternalServerError)
+	resp, err := GetWithRetry(srv.URL, nil, nil, 2, time.Millisecond, time.Millisecond, func(r *resty.Response, err error) bool {
+		return err != nil || r.StatusCode() >= 500
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+}
+
+func TestRetryCallbackResponse(t *testing.T) {
+	srv, calls := flakyServer(t, 1, http.StatusInternalServerError)
+	var bodies []string
+	resp, err := PostWithRetry(srv.URL, nil, nil, "hello", 2, time.Millisecond, 0, func(r *resty.Response, err error) bool {
+		assert.NotNil(t, r.Request)
+		bodies = append(bodies, r.String())
+		return r.StatusCode() >= 500
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", resp.String())
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+	// the body is still readable after the callback
+	assert.Equal(t, []string{"", "hello"}, bodies)
+}
+
+func TestPostFileWithRetry(t *testing.T) {
+	srv, calls := flakyServer(t, 1, http.StatusServiceUnavailable)
+	resp, err := PostFileWithRetry(srv.URL, nil, nil, "file", "a.txt", "text/plain",
+		strings.NewReader("content"), 2, time.Millisecond, time.Millisecond, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.Contains(t, resp.String(), "content")
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+}
+
+func TestRetryAfterTooLong(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	cli := NewClient(WithMiddleware(Retry(WithBackoff(time.Millisecond, time.Millisecond))))
+	resp, err := cli.R().Get(srv.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
@@ -0,0 +1,84 @@
+package request
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/tp-life/utils/stat"
+	"github.com/tp-life/utils/trace"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// Tracing 为每次请求创建 OpenTelemetry client span, 并通过全局 propagator 注入 traceparent 和 baggage
+// span 在响应 body 关闭时结束
+func Tracing() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			ctx, span := trace.TracerFromContext(r.Context()).Start(r.Context(), spanName(r),
+				oteltrace.WithSpanKind(oteltrace.SpanKindClient),
+				oteltrace.WithAttributes(semconv.HTTPClientAttributesFromHTTPRequest(r)...),
+			)
+
+			r = r.Clone(ctx)
+			otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(r.Header))
+
+			resp, err := next.RoundTrip(r)
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+				span.End()
+				return nil, err
+			}
+
+			span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(resp.StatusCode)...)
+			span.SetStatus(semconv.SpanStatusFromHTTPStatusCodeAndSpanKind(resp.StatusCode, oteltrace.SpanKindClient))
+			resp.Body = &closeHookBody{ReadCloser: resp.Body, onClose: func() {
+				span.End()
+			}}
+			return resp, nil
+		})
+	}
+}
+
+// Metrics 将请求耗时和状态上报到 m, 熔断和限流丢弃的请求计为 drop
+func Metrics(m *stat.Metrics) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.RoundTrip(r)
+			if err != nil {
+				var be *BreakerError
+				if errors.As(err, &be) || errors.Is(err, ErrConcurrencyLimit) {
+					m.AddDrop()
+					return nil, err
+				}
+			}
+
+			status := "error"
+			if resp != nil {
+				status = strconv.Itoa(resp.StatusCode)
+			}
+			m.Add(stat.Task{
+				Duration:    time.Since(start),
+				Description: r.Method + " " + r.URL.Host + " " + status,
+			})
+
+			return resp, err
+		})
+	}
+}
+
+// spanName 设置了路由模板时使用路由模板, 避免 span 名称基数过高
+func spanName(r *http.Request) string {
+	if route, ok := r.Context().Value(routeKey{}).(string); ok && len(route) > 0 {
+		return r.Method + " " + route
+	}
+
+	return "HTTP " + r.Method
+}
@@ -0,0 +1,85 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tp-life/utils/stat"
+	"github.com/tp-life/utils/trace/tracetest"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestTracing(t *testing.T) {
+	me := tracetest.NewInMemoryExporter(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Echo-Traceparent", r.Header.Get("Traceparent"))
+		w.Header().Set("Echo-Baggage", r.Header.Get("Baggage"))
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	member, err := baggage.NewMember("tenant", "t1")
+	assert.Nil(t, err)
+	bag, err := baggage.New(member)
+	assert.Nil(t, err)
+	ctx := baggage.ContextWithBaggage(WithRoute(context.Background(), "/users/{id}"), bag)
+
+	resp, err := NewClient(WithMiddleware(Tracing())).R().SetContext(ctx).Get(srv.URL + "/users/1")
+	assert.Nil(t, err)
+	assert.Equal(t, "tenant=t1", resp.Header().Get("Echo-Baggage"))
+
+	spans := me.GetSpans()
+	if assert.Len(t, spans, 1) {
+		span := spans[0]
+		assert.Equal(t, "GET /users/{id}", span.Name)
+		assert.Equal(t, oteltrace.SpanKindClient, span.SpanKind)
+		assert.Equal(t, codes.Error, span.Status.Code)
+		assert.Contains(t, resp.Header().Get("Echo-Traceparent"), span.SpanContext.TraceID().String())
+		assert.Contains(t, resp.Header().Get("Echo-Traceparent"), span.SpanContext.SpanID().String())
+
+		attrs := make(map[string]any)
+		for _, attr := range span.Attributes {
+			attrs[string(attr.Key)] = attr.Value.AsInterface()
+		}
+		assert.Equal(t, "GET", attrs["http.method"])
+		assert.Equal(t, int64(http.StatusInternalServerError), attrs["http.status_code"])
+	}
+}
+
+func TestTracingError(t *testing.T) {
+	me := tracetest.NewInMemoryExporter(t)
+	errDial := errors.New("dial failed")
+	cli := NewClient(
+		WithTransport(RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			return nil, errDial
+		})),
+		WithMiddleware(Tracing()),
+	)
+
+	_, err := cli.R().Get("http://example.com")
+	assert.ErrorIs(t, err, errDial)
+	spans := me.GetSpans()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, "HTTP GET", spans[0].Name)
+		assert.Equal(t, codes.Error, spans[0].Status.Code)
+		assert.Equal(t, "dial failed", spans[0].Status.Description)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	srv := echoHeaders(t)
+	m := stat.NewMetrics("test")
+	cli := NewClient(WithMiddleware(Metrics(m), ConcurrencyLimit(0, nil)))
+
+	_, err := cli.R().Get(srv.URL)
+	assert.ErrorIs(t, err, ErrConcurrencyLimit)
+
+	_, err = NewClient(WithMiddleware(Metrics(m))).R().Get(srv.URL)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,320 @@
+package request
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/tp-life/utils/jsonx"
+)
+
+const (
+	defaultChunkSize    = 4 << 20
+	defaultConcurrency  = 4
+	defaultChunkRetries = 3
+)
+
+var (
+	// ErrChecksumMismatch 文件校验和不一致
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+
+	transferClientOnce sync.Once
+	transferClient     *resty.Client
+)
+
+type (
+	// Progress 传输进度, *pb.ProgressBar 实现了该接口, 可以与 filex.NewProgressScanner 共用进度条
+	Progress interface {
+		Add64(add int64) int64
+	}
+
+	// ProgressFunc 函数形式的 Progress
+	ProgressFunc func(add int64)
+
+	// TransferOption 自定义下载和上传的方法
+	TransferOption func(opt *transferOptions)
+
+	transferOptions struct {
+		client       *resty.Client
+		header       map[string]string
+		chunkSize    int64
+		concurrency  int
+		chunkRetries int
+		progress     Progress
+		newHash      func() hash.Hash
+		checksum     string
+		stateFile    string
+		method       string
+	}
+
+	// chunk 文件中 [start, end) 的分片, done 为已完成的字节数
+	chunk struct {
+		start int64
+		end   int64
+		done  int64
+	}
+
+	// progressWriter 写入时更新分片和整体进度
+	progressWriter struct {
+		w        io.Writer
+		chunk    *chunk
+		progress Progress
+	}
+)
+
+// Add64 实现 Progress
+func (f ProgressFunc) Add64(add int64) int64 {
+	f(add)
+	return add
+}
+
+// WithTransferClient 自定义传输使用的 client, 默认 client 不限制超时, 不记录 body
+func WithTransferClient(cli *resty.Client) TransferOption {
+	return func(opt *transferOptions) {
+		opt.client = cli
+	}
+}
+
+// WithTransferHeaders 设置每个分片请求的 header
+func WithTransferHeaders(header map[string]string) TransferOption {
+	return func(opt *transferOptions) {
+		opt.header = header
+	}
+}
+
+// WithChunkSize 设置分片大小, 默认4MB
+func WithChunkSize(size int64) TransferOption {
+	return func(opt *transferOptions) {
+		opt.chunkSize = size
+	}
+}
+
+// WithConcurrency 设置并发传输的分片数, 默认4
+func WithConcurrency(n int) TransferOption {
+	return func(opt *transferOptions) {
+		opt.concurrency = n
+	}
+}
+
+// WithChunkRetries 设置每个分片的重试次数, 默认3, 重试从分片中断的位置继续
+func WithChunkRetries(retries int) TransferOption {
+	return func(opt *transferOptions) {
+		opt.chunkRetries = retries
+	}
+}
+
+// WithProgress 设置进度回调, 恢复传输时已完成的部分也会计入
+func WithProgress(progress Progress) TransferOption {
+	return func(opt *transferOptions) {
+		opt.progress = progress
+	}
+}
+
+// WithChecksum 校验文件, expected 为十六进制的摘要, 如 WithChecksum(sha256.New, "e3b0...")
+func WithChecksum(newHash func() hash.Hash, expected string) TransferOption {
+	return func(opt *transferOptions) {
+		opt.newHash = newHash
+		opt.checksum = strings.ToLower(expected)
+	}
+}
+
+// WithStateFile 设置记录传输进度的文件, 用于中断后恢复
+// 下载默认为目标文件加 .part.json 后缀, 上传默认不记录
+func WithStateFile(path string) TransferOption {
+	return func(opt *transferOptions) {
+		opt.stateFile = path
+	}
+}
+
+// WithUploadMethod 设置上传分片的请求方法, 默认 PUT
+func WithUploadMethod(method string) TransferOption {
+	return func(opt *transferOptions) {
+		opt.method = method
+	}
+}
+
+func newTransferOptions(opts []TransferOption) transferOptions {
+	o := transferOptions{
+		chunkSize:    defaultChunkSize,
+		concurrency:  defaultConcurrency,
+		chunkRetries: defaultChunkRetries,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.client == nil {
+		o.client = defaultTransferClient()
+	}
+	if o.chunkSize <= 0 {
+		o.chunkSize = defaultChunkSize
+	}
+	if o.concurrency <= 0 {
+		o.concurrency = 1
+	}
+	if o.progress == nil {
+		o.progress = ProgressFunc(func(int64) {})
+	}
+
+	return o
+}
+
+// defaultTransferClient 大文件传输耗时长且 body 为二进制, 不使用默认的超时和日志中间件
+func defaultTransferClient() *resty.Client {
+	transferClientOnce.Do(func() {
+		transferClient = NewClient(WithMiddleware(
+			Tracing(),
+			Metrics(clientMetrics),
+			CircuitBreaker(),
+		))
+	})
+
+	return transferClient
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	atomic.AddInt64(&w.chunk.done, int64(n))
+	w.progress.Add64(int64(n))
+	return n, err
+}
+
+func (c *chunk) remaining() int64 {
+	return c.end - c.start - atomic.LoadInt64(&c.done)
+}
+
+// planChunks 将 size 字节按 chunkSize 切分
+func planChunks(size, chunkSize int64) []*chunk {
+	var chunks []*chunk
+	for start := int64(0); start < size; start += chunkSize {
+		end := start + chunkSize
+		if end > size {
+			end = size
+		}
+		chunks = append(chunks, &chunk{start: start, end: end})
+	}
+
+	return chunks
+}
+
+// runChunks 并发处理未完成的分片, 每个分片失败后退避重试
+func runChunks(ctx context.Context, o transferOptions, chunks []*chunk, fn func(ctx context.Context, c *chunk) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	tasks := make(chan *chunk)
+	var once sync.Once
+	var firstErr error
+	var wg sync.WaitGroup
+	for i := 0; i < o.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range tasks {
+				if err := retryChunk(ctx, o, c, fn); err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+
+	for _, c := range chunks {
+		if c.remaining() <= 0 {
+			continue
+		}
+		select {
+		case tasks <- c:
+		case <-ctx.Done():
+		}
+	}
+	close(tasks)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+
+	return ctx.Err()
+}
+
+func retryChunk(ctx context.Context, o transferOptions, c *chunk, fn func(ctx context.Context, c *chunk) error) error {
+	policy := RetryPolicy{BaseDelay: defaultRetryBaseDelay, MaxDelay: defaultRetryMaxDelay}
+	for attempt := 1; ; attempt++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		err := fn(ctx, c)
+		if err == nil || attempt > o.chunkRetries || ctx.Err() != nil {
+			return err
+		}
+
+		timer := time.NewTimer(policy.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// verifyChecksum 计算文件摘要并与期望值比较
+func verifyChecksum(o transferOptions, path string) error {
+	if o.newHash == nil {
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	h := o.newHash()
+	if _, err = io.Copy(h, file); err != nil {
+		return err
+	}
+	if hex.EncodeToString(h.Sum(nil)) != o.checksum {
+		return ErrChecksumMismatch
+	}
+
+	return nil
+}
+
+func loadState(path string, state any) bool {
+	if len(path) == 0 {
+		return false
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+
+	return jsonx.Unmarshal(content, state) == nil
+}
+
+func saveState(path string, state any) error {
+	if len(path) == 0 {
+		return nil
+	}
+
+	content, err := jsonx.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, content, 0o644)
+}
@@ -0,0 +1,294 @@
+package request
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type cutWriter struct {
+	http.ResponseWriter
+	limit int
+}
+
+func (w *cutWriter) Write(p []byte) (int, error) {
+	if len(p) > w.limit {
+		w.ResponseWriter.Write(p[:w.limit])
+		panic(http.ErrAbortHandler)
+	}
+	w.limit -= len(p)
+	return w.ResponseWriter.Write(p)
+}
+
+type rangeServer struct {
+	content  []byte
+	ranges   bool
+	failures int32
+	fail     func(r *http.Request) bool
+	lock     sync.Mutex
+	requests []string
+}
+
+func (s *rangeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	s.requests = append(s.requests, r.Header.Get("Range"))
+	s.lock.Unlock()
+
+	if s.fail != nil && s.fail(r) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	if !s.ranges {
+		w.Write(s.content)
+		return
+	}
+	if r.Header.Get("Range") != "bytes=0-0" && atomic.AddInt32(&s.failures, -1) >= 0 {
+		w = &cutWriter{ResponseWriter: w, limit: 100}
+	}
+
+	w.Header().Set("ETag", `"v1"`)
+	http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(s.content))
+}
+
+func randomContent(size int) []byte {
+	content := make([]byte, size)
+	rand.New(rand.NewSource(1)).Read(content)
+	return content
+}
+
+func sha256Hex(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestDownload(t *testing.T) {
+	content := randomContent(10000)
+	srv := httptest.NewServer(&rangeServer{content: content, ranges: true, failures: 3})
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "file.bin")
+	var progress int64
+	err := Download(context.Background(), srv.URL, path,
+		WithChunkSize(1000),
+		WithConcurrency(3),
+		WithChecksum(sha256.New, strings.ToUpper(sha256Hex(content))),
+		WithProgress(ProgressFunc(func(n int64) {
+			atomic.AddInt64(&progress, n)
+		})),
+	)
+	assert.Nil(t, err)
+
+	actual, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, content, actual)
+	assert.Equal(t, int64(len(content)), progress)
+	assert.NoFileExists(t, path+partSuffix)
+	assert.NoFileExists(t, path+partSuffix+".json")
+}
+
+func TestDownloadResume(t *testing.T) {
+	content := randomContent(10000)
+	server := &rangeServer{content: content, ranges: true, fail: func(r *http.Request) bool {
+		return strings.HasPrefix(r.Header.Get("Range"), "bytes=5000-")
+	}}
+	srv := httptest.NewServer(server)
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "file.bin")
+	err := Download(context.Background(), srv.URL, path, WithChunkSize(1000), WithConcurrency(1), WithChunkRetries(0))
+	var apiErr *APIError
+	assert.True(t, errors.As(err, &apiErr))
+	assert.FileExists(t, path+partSuffix)
+	assert.FileExists(t, path+partSuffix+".json")
+
+	server.fail = nil
+	server.requests = nil
+	var progress int64
+	err = Download(context.Background(), srv.URL, path, WithChunkSize(1000), WithConcurrency(2),
+		WithProgress(ProgressFunc(func(n int64) {
+			atomic.AddInt64(&progress, n)
+		})))
+	assert.Nil(t, err)
+	actual, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, content, actual)
+	assert.Equal(t, int64(len(content)), progress)
+	for _, r := range server.requests {
+		assert.False(t, strings.HasPrefix(r, "bytes=1000-"), r)
+	}
+	assert.Len(t, server.requests, 6)
+}
+
+func TestDownloadResumeWithBrokenPart(t *testing.T) {
+	content := randomContent(10000)
+	breakers := map[string]func(part string) error{
+		"removed": os.Remove,
+		"truncated": func(part string) error {
+			return os.Truncate(part, 1000)
+		},
+	}
+
+	for name, breakPart := range breakers {
+		t.Run(name, func(t *testing.T) {
+			server := &rangeServer{content: content, ranges: true, fail: func(r *http.Request) bool {
+				return strings.HasPrefix(r.Header.Get("Range"), "bytes=5000-")
+			}}
+			srv := httptest.NewServer(server)
+			defer srv.Close()
+
+			path := filepath.Join(t.TempDir(), "file.bin")
+			err := Download(context.Background(), srv.URL, path,
+				WithChunkSize(1000), WithConcurrency(1), WithChunkRetries(0))
+			assert.NotNil(t, err)
+			assert.FileExists(t, path+partSuffix+".json")
+			assert.Nil(t, breakPart(path+partSuffix))
+
+			server.fail = nil
+			assert.Nil(t, Download(context.Background(), srv.URL, path, WithChunkSize(1000), WithConcurrency(2)))
+			actual, err := os.ReadFile(path)
+			assert.Nil(t, err)
+			assert.Equal(t, content, actual)
+		})
+	}
+}
+
+func TestDownloadChecksumMismatch(t *testing.T) {
+	srv := httptest.NewServer(&rangeServer{content: randomContent(3000), ranges: true})
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "file.bin")
+	err := Download(context.Background(), srv.URL, path, WithChunkSize(1000), WithChecksum(sha256.New, "00"))
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	assert.NoFileExists(t, path)
+	assert.NoFileExists(t, path+partSuffix)
+}
+
+func TestDownloadWithoutRange(t *testing.T) {
+	content := randomContent(5000)
+	srv := httptest.NewServer(&rangeServer{content: content})
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "file.bin")
+	var progress int64
+	err := Download(context.Background(), srv.URL, path, WithChecksum(sha256.New, sha256Hex(content)),
+		WithProgress(ProgressFunc(func(n int64) {
+			progress += n
+		})))
+	assert.Nil(t, err)
+	actual, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, content, actual)
+	assert.Equal(t, int64(len(content)), progress)
+}
+
+func TestParseContentRangeSize(t *testing.T) {
+	size, ok := parseContentRangeSize("bytes 0-0/1234")
+	assert.True(t, ok)
+	assert.Equal(t, int64(1234), size)
+	_, ok = parseContentRangeSize("bytes 0-0/*")
+	assert.False(t, ok)
+	_, ok = parseContentRangeSize("")
+	assert.False(t, ok)
+}
+
+type uploadServer struct {
+	lock    sync.Mutex
+	content []byte
+	ranges  []string
+	fail    func(r *http.Request) bool
+}
+
+func (s *uploadServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	contentRange := r.Header.Get("Content-Range")
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.ranges = append(s.ranges, contentRange)
+	if s.fail != nil && s.fail(r) {
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+
+	sum := md5.Sum(body)
+	if r.Header.Get("Content-MD5") != base64.StdEncoding.EncodeToString(sum[:]) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var start, end, total int64
+	if _, err := fmt.Sscanf(contentRange, "bytes %d-%d/%d", &start, &end, &total); err != nil {
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+	if s.content == nil {
+		s.content = make([]byte, total)
+	}
+	copy(s.content[start:end+1], body)
+	w.WriteHeader(http.StatusPermanentRedirect)
+}
+
+func TestUpload(t *testing.T) {
+	content := randomContent(10000)
+	path := filepath.Join(t.TempDir(), "file.bin")
+	assert.Nil(t, os.WriteFile(path, content, 0o644))
+	state := path + ".upload.json"
+
+	server := &uploadServer{fail: func(r *http.Request) bool {
+		return strings.HasPrefix(r.Header.Get("Content-Range"), "bytes 7000-")
+	}}
+	srv := httptest.NewServer(server)
+	defer srv.Close()
+
+	err := Upload(context.Background(), srv.URL, path, WithChunkSize(1000), WithConcurrency(1),
+		WithChunkRetries(0), WithStateFile(state))
+	assert.NotNil(t, err)
+	assert.FileExists(t, state)
+
+	server.fail = nil
+	server.ranges = nil
+	var progress int64
+	err = Upload(context.Background(), srv.URL, path, WithChunkSize(1000), WithConcurrency(3),
+		WithStateFile(state), WithChecksum(sha256.New, sha256Hex(content)),
+		WithProgress(ProgressFunc(func(n int64) {
+			atomic.AddInt64(&progress, n)
+		})))
+	assert.Nil(t, err)
+	assert.Equal(t, content, server.content)
+	assert.Len(t, server.ranges, 3)
+	assert.Equal(t, int64(len(content)), progress)
+	assert.NoFileExists(t, state)
+
+	err = Upload(context.Background(), srv.URL, path, WithChecksum(sha256.New, "00"))
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+}
+
+func TestUploadEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty")
+	assert.Nil(t, os.WriteFile(path, nil, 0o644))
+
+	server := &uploadServer{}
+	srv := httptest.NewServer(server)
+	defer srv.Close()
+
+	assert.Nil(t, Upload(context.Background(), srv.URL, path))
+	assert.Equal(t, []string{"bytes */0"}, server.ranges)
+}
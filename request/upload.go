@@ -0,0 +1,135 @@
+package request
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/tp-life/utils/filex"
+)
+
+// uploadState 上传进度, 本地文件变化后不再恢复
+type uploadState struct {
+	URL       string `json:"url"`
+	Size      int64  `json:"size"`
+	ModTime   int64  `json:"mod_time"`
+	ChunkSize int64  `json:"chunk_size"`
+	Done      []bool `json:"done"`
+}
+
+// Upload 将 path 分片并发上传到 url, 每个分片是一个独立的请求
+// 分片请求带有 Content-Range: bytes start-end/total 和 Content-MD5, 服务端按范围写入即可, 重复上传同一分片是幂等的
+// 设置了 WithStateFile 时, 中断后再次调用只上传未完成的分片
+func Upload(ctx context.Context, url, path string, opts ...TransferOption) error {
+	o := newTransferOptions(opts)
+	if len(o.method) == 0 {
+		o.method = http.MethodPut
+	}
+
+	// 上传前校验本地文件
+	if err := verifyChecksum(o, path); err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	size := info.Size()
+	if size == 0 {
+		return uploadChunk(ctx, o, url, file, &chunk{}, 0)
+	}
+
+	chunks := planChunks(size, o.chunkSize)
+	state := uploadState{
+		URL:       url,
+		Size:      size,
+		ModTime:   info.ModTime().UnixNano(),
+		ChunkSize: o.chunkSize,
+		Done:      make([]bool, len(chunks)),
+	}
+	var saved uploadState
+	if loadState(o.stateFile, &saved) && saved.URL == state.URL && saved.Size == state.Size &&
+		saved.ModTime == state.ModTime && saved.ChunkSize == state.ChunkSize && len(saved.Done) == len(chunks) {
+		for i, c := range chunks {
+			if saved.Done[i] {
+				state.Done[i] = true
+				c.done = c.end - c.start
+				o.progress.Add64(c.done)
+			}
+		}
+	}
+
+	index := make(map[*chunk]int, len(chunks))
+	for i, c := range chunks {
+		index[c] = i
+	}
+
+	var lock sync.Mutex
+	err = runChunks(ctx, o, chunks, func(ctx context.Context, c *chunk) error {
+		if err := uploadChunk(ctx, o, url, file, c, size); err != nil {
+			return err
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+		state.Done[index[c]] = true
+		return saveState(o.stateFile, state)
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(o.stateFile) > 0 {
+		os.Remove(o.stateFile)
+	}
+
+	return nil
+}
+
+// uploadChunk 读取分片内容后上传, 分片内容在内存中, 请求可以安全重试
+func uploadChunk(ctx context.Context, o transferOptions, url string, file *os.File, c *chunk, size int64) error {
+	content := make([]byte, c.end-c.start)
+	if _, err := io.ReadFull(io.LimitReader(filex.NewRangeReader(file, c.start, c.end), c.end-c.start), content); err != nil {
+		return err
+	}
+
+	contentRange := fmt.Sprintf("bytes %d-%d/%d", c.start, c.end-1, size)
+	if size == 0 {
+		contentRange = "bytes */0"
+	}
+	sum := md5.Sum(content)
+	resp, err := o.client.R().SetContext(ctx).
+		SetHeaders(o.header).
+		SetHeader("Content-Type", "application/octet-stream").
+		SetHeader("Content-Range", contentRange).
+		SetHeader("Content-MD5", base64.StdEncoding.EncodeToString(sum[:])).
+		SetBody(content).
+		Execute(o.method, url)
+	if err != nil {
+		return err
+	}
+
+	// 308 为 Resume Incomplete, 表示分片已接收
+	if code := resp.StatusCode(); code >= http.StatusBadRequest ||
+		code < http.StatusOK || (code >= http.StatusMultipleChoices && code != http.StatusPermanentRedirect) {
+		return newAPIError(code, resp.Body())
+	}
+
+	atomic.StoreInt64(&c.done, c.end-c.start)
+	o.progress.Add64(c.end - c.start)
+	return nil
+}
@@ -0,0 +1,609 @@
+package validation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultTagKey     = "validate"
+	defaultNameTagKey = "json"
+	skipTag           = "-"
+	ruleSeparator     = ','
+	paramSeparator    = "="
+	escapeChar        = '\\'
+	localeSeparators  = "-_"
+)
+
+var (
+	errValueNotStruct = errors.New("validation: value type is not struct")
+	defaultEngine     = NewEngine()
+
+	validatorType        = reflect.TypeOf((*Validator)(nil)).Elem()
+	contextValidatorType = reflect.TypeOf((*ContextValidator)(nil)).Elem()
+)
+
+type (
+	// An Engine validates the structs with the rules in the struct tags, like:
+	//
+	//	type Request struct {
+	//		Name     string            `json:"name" validate:"required,max=32"`
+	//		Email    string            `json:"email" validate:"omitempty,email"`
+	//		Age      int               `json:"age" validate:"gte=0,lte=150"`
+	//		Role     string            `json:"role" validate:"oneof=admin|user"`
+	//		Tags     []string          `json:"tags" validate:"max=10,dive,required,max=32"`
+	//		Start    time.Time         `json:"start"`
+	//		End      time.Time         `json:"end" validate:"gtfield=Start"`
+	//		Address  *Address          `json:"address" validate:"required"`
+	//		Contacts map[string]Person `json:"contacts" validate:"dive"`
+	//	}
+	//
+	// The rules are separated by commas, commas in the params need to be escaped by backslashes.
+	// The rules after dive are applied to the elements of slices, arrays and maps.
+	// Nested structs are always validated, the elements of slices, arrays and maps are validated with dive.
+	// Nested values of any kind that implement Validator or ContextValidator are validated by their methods
+	// as well, but the method of the top level value is not called, so that it can call Struct on itself.
+	// The errors of the nested methods that duplicate the errors of the tag rules are dropped,
+	// so that the nested values can validate themselves with Struct as well:
+	//
+	//	func (a Address) Validate() error {
+	//		return validation.Struct(a)
+	//	}
+	// The parsed rules are cached by types, unknown rules are reported on the first validation of the type.
+	Engine struct {
+		tagKey     string
+		nameTagKey string
+		locale     string
+		lock       sync.RWMutex
+		rules      map[string]RuleFunc
+		messages   map[string]map[string]string
+		cache      sync.Map
+	}
+
+	// EngineOption customizes an Engine.
+	EngineOption func(e *Engine)
+
+	structInfo struct {
+		fields []*fieldInfo
+		err    error
+	}
+
+	fieldInfo struct {
+		index    int
+		name     string
+		embedded bool
+		rules    *ruleSet
+	}
+
+	ruleSet struct {
+		required  bool
+		omitEmpty bool
+		rules     []rule
+		dive      *ruleSet
+	}
+
+	rule struct {
+		name  string
+		param string
+		fn    RuleFunc
+	}
+
+	structValidator struct {
+		engine *Engine
+		value  any
+	}
+
+	walker struct {
+		engine *Engine
+		ctx    context.Context
+		locale string
+		errs   FieldErrors
+	}
+)
+
+// NewEngine returns an Engine with the builtin rules and messages.
+func NewEngine(opts ...EngineOption) *Engine {
+	e := &Engine{
+		tagKey:     defaultTagKey,
+		nameTagKey: defaultNameTagKey,
+		locale:     LocaleEn,
+		rules:      builtinRules(),
+		messages:   make(map[string]map[string]string, len(defaultMessages)),
+	}
+	for locale, messages := range defaultMessages {
+		e.RegisterMessages(locale, messages)
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e
+}
+
+// WithLocale customizes the Engine to use locale for the messages, if not given in the context.
+func WithLocale(locale string) EngineOption {
+	return func(e *Engine) {
+		e.locale = locale
+	}
+}
+
+// WithNameTagKey customizes the Engine to name the fields with the tag key, json by default,
+// the Go field names are used if the tag keys are not given.
+func WithNameTagKey(key string) EngineOption {
+	return func(e *Engine) {
+		e.nameTagKey = key
+	}
+}
+
+// WithTagKey customizes the Engine to read the rules from the tag key, validate by default.
+func WithTagKey(key string) EngineOption {
+	return func(e *Engine) {
+		e.tagKey = key
+	}
+}
+
+// Of returns a Validator of v with the default engine.
+func Of(v any) Validator {
+	return defaultEngine.Of(v)
+}
+
+// RegisterMessages registers the message templates of the rules for locale to the default engine.
+func RegisterMessages(locale string, messages map[string]string) {
+	defaultEngine.RegisterMessages(locale, messages)
+}
+
+// RegisterRule registers a rule to the default engine.
+func RegisterRule(name string, fn RuleFunc) {
+	defaultEngine.RegisterRule(name, fn)
+}
+
+// Struct validates v with the default engine.
+func Struct(v any) error {
+	return defaultEngine.Struct(v)
+}
+
+// StructCtx validates v with ctx with the default engine.
+func StructCtx(ctx context.Context, v any) error {
+	return defaultEngine.StructCtx(ctx, v)
+}
+
+// Of returns a Validator of v, which implements ContextValidator as well.
+func (e *Engine) Of(v any) Validator {
+	return structValidator{
+		engine: e,
+		value:  v,
+	}
+}
+
+// RegisterMessages registers the message templates of the rules for locale,
+// {field}, {rule}, {param} and {value} in the templates are replaced on rendering.
+// The template keyed by empty string is used for the rules without templates.
+func (e *Engine) RegisterMessages(locale string, messages map[string]string) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	m, ok := e.messages[locale]
+	if !ok {
+		m = make(map[string]string, len(messages))
+		e.messages[locale] = m
+	}
+	for name, message := range messages {
+		m[name] = message
+	}
+}
+
+// RegisterRule registers a rule, the builtin rule with the same name is replaced.
+func (e *Engine) RegisterRule(name string, fn RuleFunc) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.rules[name] = fn
+	// the rules are resolved on parsing the structs
+	e.cache.Clear()
+}
+
+// Struct validates v, which is a struct or a pointer to struct.
+// FieldErrors is returned if any field is invalid.
+func (e *Engine) Struct(v any) error {
+	return e.StructCtx(context.Background(), v)
+}
+
+// StructCtx validates v with ctx, ctx is passed to the rules and ContextValidator values,
+// and the locale in ctx is used for the messages.
+func (e *Engine) StructCtx(ctx context.Context, v any) error {
+	rv := indirect(reflect.ValueOf(v))
+	if !rv.IsValid() || rv.Kind() != reflect.Struct {
+		return errValueNotStruct
+	}
+
+	locale := LocaleFromContext(ctx)
+	if len(locale) == 0 {
+		locale = e.locale
+	}
+
+	w := &walker{
+		engine: e,
+		ctx:    ctx,
+		locale: locale,
+	}
+	if err := w.validateStruct(rv, ""); err != nil {
+		return err
+	}
+	if len(w.errs) > 0 {
+		return w.errs
+	}
+
+	return nil
+}
+
+func (e *Engine) getStructInfo(tp reflect.Type) (*structInfo, error) {
+	if val, ok := e.cache.Load(tp); ok {
+		info := val.(*structInfo)
+		return info, info.err
+	}
+
+	info := e.parseStruct(tp)
+	val, _ := e.cache.LoadOrStore(tp, info)
+	info = val.(*structInfo)
+
+	return info, info.err
+}
+
+func (e *Engine) message(locale, name string) string {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	locales := []string{locale}
+	if pos := strings.IndexAny(locale, localeSeparators); pos > 0 {
+		locales = append(locales, locale[:pos])
+	}
+	locales = append(locales, LocaleEn)
+
+	for _, key := range []string{name, defaultMessageKey} {
+		for _, l := range locales {
+			if message, ok := e.messages[l][key]; ok {
+				return message
+			}
+		}
+	}
+
+	return defaultMessages[LocaleEn][defaultMessageKey]
+}
+
+func (e *Engine) parseStruct(tp reflect.Type) *structInfo {
+	info := new(structInfo)
+	for i := 0; i < tp.NumField(); i++ {
+		field := tp.Field(i)
+		tag := field.Tag.Get(e.tagKey)
+		if tag == skipTag {
+			continue
+		}
+
+		// the exported fields of unexported embedded structs are promoted as well
+		ft := deref(field.Type)
+		if field.Anonymous && ft.Kind() == reflect.Struct && len(tag) == 0 {
+			info.fields = append(info.fields, &fieldInfo{
+				index:    i,
+				embedded: true,
+			})
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		rules, err := parseRules(tag)
+		if err == nil {
+			err = e.resolveRules(rules)
+		}
+		if err != nil {
+			info.err = fmt.Errorf("validation: field %s.%s, %w", tp.Name(), field.Name, err)
+			return info
+		}
+		// only the nested structs and validators need to be walked without rules
+		if rules == nil && ft.Kind() != reflect.Struct && !implementsValidator(field.Type) {
+			continue
+		}
+
+		info.fields = append(info.fields, &fieldInfo{
+			index: i,
+			name:  e.fieldName(field),
+			rules: rules,
+		})
+	}
+
+	return info
+}
+
+func (e *Engine) fieldName(field reflect.StructField) string {
+	if len(e.nameTagKey) == 0 {
+		return field.Name
+	}
+
+	name, _, _ := strings.Cut(field.Tag.Get(e.nameTagKey), ",")
+	if len(name) == 0 || name == skipTag {
+		return field.Name
+	}
+
+	return name
+}
+
+// resolveRules looks up the functions of the rules in rs, unknown rules are reported.
+func (e *Engine) resolveRules(rs *ruleSet) error {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	for ; rs != nil; rs = rs.dive {
+		for i := range rs.rules {
+			fn, ok := e.rules[rs.rules[i].name]
+			if !ok {
+				return fmt.Errorf("unknown rule %q", rs.rules[i].name)
+			}
+			rs.rules[i].fn = fn
+		}
+	}
+
+	return nil
+}
+
+func (v structValidator) Validate() error {
+	return v.engine.Struct(v.value)
+}
+
+func (v structValidator) ValidateCtx(ctx context.Context) error {
+	return v.engine.StructCtx(ctx, v.value)
+}
+
+func (w *walker) addError(path string, r rule, value reflect.Value) {
+	fe := &FieldError{
+		Field:    path,
+		Rule:     r.name,
+		Param:    r.param,
+		template: w.engine.message(w.locale, r.name),
+	}
+	if value.IsValid() && value.CanInterface() {
+		fe.Value = value.Interface()
+	}
+	fe.Message = renderMessage(fe.template, fe)
+	w.errs = append(w.errs, fe)
+}
+
+// compose calls the Validate or ValidateCtx method of v, if implemented,
+// the errors that duplicate the walked ones since start are dropped.
+func (w *walker) compose(v reflect.Value, path string, start int) {
+	if !v.CanInterface() {
+		return
+	}
+
+	var target any
+	if v.CanAddr() {
+		target = v.Addr().Interface()
+	} else {
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+		target = ptr.Interface()
+	}
+
+	var err error
+	switch val := target.(type) {
+	case ContextValidator:
+		err = val.ValidateCtx(w.ctx)
+	case Validator:
+		err = val.Validate()
+	}
+	if err == nil {
+		return
+	}
+
+	walked := w.errs[start:]
+	for _, fe := range newValidatorErrors(path, err) {
+		if !slices.ContainsFunc(walked, fe.duplicates) {
+			w.errs = append(w.errs, fe)
+		}
+	}
+}
+
+func (w *walker) validateElements(parent, v reflect.Value, path string, rs *ruleSet) error {
+	switch v.Kind() {
+	case reflect.Array, reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if err := w.validateField(parent, v.Index(i), fmt.Sprintf("%s[%d]", path, i), rs); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		// sort the keys to make the errors stable
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, key := range keys {
+			elemPath := fmt.Sprintf("%s[%v]", path, key.Interface())
+			if err := w.validateField(parent, v.MapIndex(key), elemPath, rs); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (w *walker) validateField(parent, v reflect.Value, path string, rs *ruleSet) error {
+	value := indirect(v)
+	if rs != nil {
+		if isEmpty(v) {
+			if rs.required {
+				w.addError(path, rule{name: requiredRule}, value)
+				return nil
+			}
+			if rs.omitEmpty {
+				return nil
+			}
+		}
+
+		// nil values are only checked by required
+		if value.IsValid() {
+			for _, r := range rs.rules {
+				if !r.fn(w.ctx, Field{
+					Name:   path,
+					Value:  value,
+					Param:  r.param,
+					Parent: parent,
+				}) {
+					w.addError(path, r, value)
+					return nil
+				}
+			}
+		}
+	}
+
+	if !value.IsValid() {
+		return nil
+	}
+
+	start := len(w.errs)
+	switch value.Kind() {
+	case reflect.Struct:
+		if err := w.validateStruct(value, path); err != nil {
+			return err
+		}
+	case reflect.Array, reflect.Slice, reflect.Map:
+		if rs != nil && rs.dive != nil {
+			if err := w.validateElements(parent, value, path, rs.dive); err != nil {
+				return err
+			}
+		}
+	}
+
+	w.compose(value, path, start)
+	return nil
+}
+
+func (w *walker) validateStruct(v reflect.Value, path string) error {
+	info, err := w.engine.getStructInfo(v.Type())
+	if err != nil {
+		return err
+	}
+
+	for _, field := range info.fields {
+		fv := v.Field(field.index)
+		if field.embedded {
+			// embedded structs are flattened
+			if fv = indirect(fv); fv.IsValid() {
+				if err := w.validateStruct(fv, path); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if err := w.validateField(v, fv, joinPath(path, field.name), field.rules); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// implementsValidator reports whether tp or the pointer to tp implements Validator or ContextValidator.
+func implementsValidator(tp reflect.Type) bool {
+	tp = deref(tp)
+	for _, t := range []reflect.Type{tp, reflect.PointerTo(tp)} {
+		if t.Implements(validatorType) || t.Implements(contextValidatorType) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func deref(tp reflect.Type) reflect.Type {
+	for tp.Kind() == reflect.Ptr {
+		tp = tp.Elem()
+	}
+
+	return tp
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+
+	return v
+}
+
+// isEmpty reports whether v is nil, zero or has no elements.
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	case reflect.Slice, reflect.Map, reflect.String:
+		return v.Len() == 0
+	default:
+		return v.IsZero()
+	}
+}
+
+func parseRules(tag string) (*ruleSet, error) {
+	if len(tag) == 0 {
+		return nil, nil
+	}
+
+	root := new(ruleSet)
+	current := root
+	for _, segment := range splitRules(tag) {
+		name, param, _ := strings.Cut(segment, paramSeparator)
+		name = strings.TrimSpace(name)
+		switch name {
+		case "":
+			return nil, fmt.Errorf("empty rule in %q", tag)
+		case requiredRule:
+			current.required = true
+		case omitEmptyRule:
+			current.omitEmpty = true
+		case diveRule:
+			current.dive = new(ruleSet)
+			current = current.dive
+		default:
+			current.rules = append(current.rules, rule{
+				name:  name,
+				param: param,
+			})
+		}
+	}
+
+	return root, nil
+}
+
+// splitRules splits tag by the commas that are not escaped by backslashes.
+func splitRules(tag string) []string {
+	var segments []string
+	var buf strings.Builder
+	for i := 0; i < len(tag); i++ {
+		switch c := tag[i]; {
+		case c == escapeChar && i+1 < len(tag) && tag[i+1] == ruleSeparator:
+			buf.WriteByte(ruleSeparator)
+			i++
+		case c == ruleSeparator:
+			segments = append(segments, buf.String())
+			buf.Reset()
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	segments = append(segments, buf.String())
+
+	return segments
+}
@@ -0,0 +1,337 @@
+package validation
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	testAddress struct {
+		City string `json:"city" validate:"required"`
+		Zip  string `json:"zip" validate:"omitempty,numeric,len=6"`
+	}
+
+	testSelfAddress struct {
+		City string `json:"city" validate:"required"`
+		Zip  string `json:"zip" validate:"omitempty,len=6"`
+	}
+
+	testSelfRequest struct {
+		Addr testSelfAddress `json:"addr"`
+	}
+
+	testContact struct {
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	}
+
+	testBase struct {
+		ID string `json:"id" validate:"required,uuid"`
+	}
+
+	testUser struct {
+		testBase
+		Name     string                 `json:"name" validate:"required,min=2,max=8"`
+		Email    string                 `json:"email" validate:"omitempty,email"`
+		Age      int                    `json:"age" validate:"gte=0,lte=150"`
+		Role     string                 `json:"role" validate:"oneof=admin|user"`
+		Tags     []string               `json:"tags" validate:"max=3,unique,dive,required,alphanum"`
+		Timeout  time.Duration          `json:"timeout" validate:"gte=1s"`
+		Start    time.Time              `json:"start"`
+		End      time.Time              `json:"end" validate:"gtfield=Start"`
+		Address  *testAddress           `json:"address" validate:"required"`
+		Contacts map[string]testContact `json:"contacts" validate:"dive"`
+		Matrix   [][]int                `json:"matrix" validate:"dive,min=1,dive,gt=0"`
+		Ignored  string                 `json:"ignored" validate:"-"`
+	}
+
+	testPassword struct {
+		Password string `validate:"min=6"`
+		Confirm  string `validate:"eqfield=Password"`
+	}
+
+	testTeam struct {
+		Name    string        `json:"name" validate:"required"`
+		Members []testMember  `json:"members" validate:"dive"`
+		Owner   *testMember   `json:"owner"`
+		Checked testCtxMember `json:"checked"`
+	}
+
+	testMember struct {
+		Name string `json:"name" validate:"required"`
+		Age  int    `json:"age"`
+	}
+
+	testCtxMember struct {
+		Name string `json:"name"`
+	}
+
+	testStatus string
+
+	testOrder struct {
+		Status   testStatus   `json:"status"`
+		Statuses []testStatus `json:"statuses" validate:"dive"`
+		Previous *testStatus  `json:"previous"`
+	}
+
+	ctxKey struct{}
+)
+
+func (c testContact) Validate() error {
+	if len(c.Name) == 0 && len(c.Email) == 0 {
+		return errors.New("name or email is required")
+	}
+
+	return nil
+}
+
+func (m *testMember) Validate() error {
+	if m.Age < 0 {
+		return Struct(struct {
+			Age int `json:"age" validate:"gte=0"`
+		}{Age: m.Age})
+	}
+
+	return nil
+}
+
+func (m testCtxMember) ValidateCtx(ctx context.Context) error {
+	if m.Name == ctx.Value(ctxKey{}) {
+		return errors.New("name is taken")
+	}
+
+	return nil
+}
+
+func (s testStatus) Validate() error {
+	if s != "open" && s != "closed" {
+		return errors.New("unknown status " + string(s))
+	}
+
+	return nil
+}
+
+func newTestUser() testUser {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return testUser{
+		testBase: testBase{ID: "0b7a3c2e-1d4f-4a5b-9c6d-7e8f9a0b1c2d"},
+		Name:     "kevin",
+		Email:    "kevin@example.com",
+		Age:      18,
+		Role:     "admin",
+		Tags:     []string{"a", "b1"},
+		Timeout:  time.Second,
+		Start:    start,
+		End:      start.Add(time.Hour),
+		Address:  &testAddress{City: "sz"},
+		Contacts: map[string]testContact{"a": {Name: "a"}},
+		Matrix:   [][]int{{1, 2}, {3}},
+	}
+}
+
+func TestStruct(t *testing.T) {
+	user := newTestUser()
+	assert.NoError(t, Struct(user))
+	assert.NoError(t, Struct(&user))
+	assert.NoError(t, Of(&user).Validate())
+
+	user = testUser{
+		testBase: testBase{ID: "1"},
+		Name:     "k",
+		Email:    "kevin",
+		Age:      200,
+		Role:     "guest",
+		Tags:     []string{"a", "", "b-1"},
+		Timeout:  time.Millisecond,
+		Start:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Address:  &testAddress{Zip: "12a"},
+		Contacts: map[string]testContact{"b": {}, "a": {Name: "a"}},
+		Matrix:   [][]int{{1}, {}, {0}},
+		Ignored:  "ignored",
+	}
+	err := Struct(&user)
+	var fes FieldErrors
+	assert.True(t, errors.As(err, &fes))
+
+	rules := make(map[string]string)
+	for _, fe := range fes {
+		rules[fe.Field] = fe.Rule
+	}
+	assert.Equal(t, map[string]string{
+		"id":           uuidRule,
+		"name":         minRule,
+		"email":        emailRule,
+		"age":          lteRule,
+		"role":         oneofRule,
+		"tags[1]":      requiredRule,
+		"tags[2]":      alphaNumRule,
+		"timeout":      gteRule,
+		"end":          gtFieldRule,
+		"address.city": requiredRule,
+		"address.zip":  numericRule,
+		"contacts[b]":  validatorRule,
+		"matrix[1]":    minRule,
+		"matrix[2][0]": gtRule,
+	}, rules)
+	assert.Equal(t, "name must be at least 2", fes[1].Error())
+
+	user = newTestUser()
+	user.Address = nil
+	user.Tags = []string{"a", "b", "a", "c"}
+	assert.EqualError(t, Struct(user), "tags must be at most 3\naddress is required")
+}
+
+func TestStructCompose(t *testing.T) {
+	ctx := context.WithValue(context.Background(), ctxKey{}, "taken")
+	team := testTeam{
+		Name: "team",
+		Members: []testMember{
+			{Name: "a"},
+			{Age: -1},
+		},
+		Owner:   &testMember{Name: "b", Age: -2},
+		Checked: testCtxMember{Name: "taken"},
+	}
+
+	err := StructCtx(ctx, team)
+	var fes FieldErrors
+	assert.True(t, errors.As(err, &fes))
+	assert.Equal(t, `members[1].name is required
+members[1].age must be greater than or equal to 0
+owner.age must be greater than or equal to 0
+checked: name is taken`, fes.Error())
+	assert.Equal(t, validatorRule, fes[3].Rule)
+
+	// the errors of the nested Validate methods are wrapped
+	team.Members = nil
+	team.Owner = nil
+	err = Of(team).(ContextValidator).ValidateCtx(ctx)
+	assert.EqualError(t, errors.Unwrap(err.(FieldErrors)[0]), "name is taken")
+}
+
+func (a testSelfAddress) Validate() error {
+	return Struct(a)
+}
+
+func TestStructComposeSelfValidation(t *testing.T) {
+	assert.EqualError(t, Struct(testSelfRequest{}), "addr.city is required")
+	assert.EqualError(t, Struct(testSelfRequest{Addr: testSelfAddress{Zip: "1"}}),
+		"addr.city is required\naddr.zip must be 6 in length")
+	assert.NoError(t, Struct(testSelfRequest{Addr: testSelfAddress{City: "sz"}}))
+}
+
+func TestStructComposeNonStruct(t *testing.T) {
+	closed := testStatus("closed")
+	assert.NoError(t, Struct(testOrder{Status: "open", Previous: &closed}))
+
+	invalid := testStatus("bad")
+	assert.EqualError(t, Struct(testOrder{
+		Status:   "draft",
+		Statuses: []testStatus{"open", "paid"},
+		Previous: &invalid,
+	}), `status: unknown status draft
+statuses[1]: unknown status paid
+previous: unknown status bad`)
+}
+
+func TestStructCustomRule(t *testing.T) {
+	type inner struct {
+		Name string `validate:"reserved"`
+		Code string `validate:"prefix=ab\\,c"`
+	}
+
+	e := NewEngine()
+	assert.EqualError(t, e.Struct(inner{}), `validation: field inner.Name, unknown rule "reserved"`)
+	// unknown rules are reported even if they are not reached
+	type typo struct {
+		Email string `validate:"omitempty,emial"`
+	}
+	assert.EqualError(t, e.Struct(typo{}), `validation: field typo.Email, unknown rule "emial"`)
+
+	e.RegisterRule("reserved", func(ctx context.Context, field Field) bool {
+		return field.Value.String() != ctx.Value(ctxKey{})
+	})
+	e.RegisterRule("prefix", func(_ context.Context, field Field) bool {
+		return len(field.Value.String()) >= len(field.Param) &&
+			field.Value.String()[:len(field.Param)] == field.Param
+	})
+	e.RegisterMessages(LocaleEn, map[string]string{
+		"reserved": "{field} {value} is reserved",
+	})
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "admin")
+	assert.NoError(t, e.StructCtx(ctx, inner{Name: "kevin", Code: "ab,c1"}))
+	assert.EqualError(t, e.StructCtx(ctx, inner{Name: "admin", Code: "ab"}),
+		"Name admin is reserved\nCode failed on the prefix rule")
+}
+
+func TestStructLocale(t *testing.T) {
+	type inner struct {
+		Name string `json:"name" validate:"required"`
+		Age  int    `json:"age" validate:"gte=18"`
+	}
+
+	assert.EqualError(t, StructCtx(ContextWithLocale(context.Background(), "zh-CN"), inner{Age: 1}),
+		"name为必填字段\nage必须大于或等于18")
+
+	e := NewEngine(WithLocale(LocaleZh), WithNameTagKey(""))
+	assert.EqualError(t, e.Struct(inner{Name: "a", Age: 1}), "Age必须大于或等于18")
+	// falls back to English for unknown locales
+	assert.EqualError(t, e.StructCtx(ContextWithLocale(context.Background(), "fr"), inner{Name: "a", Age: 1}),
+		"Age must be greater than or equal to 18")
+}
+
+func TestStructErrors(t *testing.T) {
+	assert.Equal(t, errValueNotStruct, Struct(1))
+	assert.Equal(t, errValueNotStruct, Struct((*testUser)(nil)))
+
+	type wrong struct {
+		Name string `validate:"required,,min=1"`
+	}
+	assert.Error(t, Struct(wrong{}))
+
+	assert.NoError(t, Struct(testPassword{Password: "123456", Confirm: "123456"}))
+	assert.Error(t, Struct(testPassword{Password: "123456", Confirm: "12345"}))
+}
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		rule    string
+		param   string
+		valid   []any
+		invalid []any
+	}{
+		{rule: minRule, param: "2", valid: []any{"中文", []int{1, 2}, 2, 2.5}, invalid: []any{"a", []int{1}, 1, true}},
+		{rule: lenRule, param: "2", valid: []any{"ab", map[string]int{"a": 1, "b": 2}}, invalid: []any{"abc"}},
+		{rule: eqRule, param: "true", valid: []any{true, "true"}, invalid: []any{false, "false"}},
+		{rule: neRule, param: "3", valid: []any{2, "a"}, invalid: []any{3, uint(3), []int{1, 2, 3}}},
+		{rule: ltRule, param: "1m", valid: []any{time.Second}, invalid: []any{time.Minute}},
+		{rule: oneofRule, param: "1|2", valid: []any{1, "2"}, invalid: []any{3, []int{1}}},
+		{rule: ipv4Rule, valid: []any{"10.0.0.1"}, invalid: []any{"::1", 1}},
+		{rule: ipv6Rule, valid: []any{"::1"}, invalid: []any{"10.0.0.1"}},
+		{rule: cidrRule, valid: []any{"10.0.0.0/8"}, invalid: []any{"10.0.0.1"}},
+		{rule: hostPortRule, valid: []any{"localhost:80"}, invalid: []any{"localhost", "localhost:70000"}},
+		{rule: urlRule, valid: []any{"https://example.com"}, invalid: []any{"example.com"}},
+		{rule: alphaRule, valid: []any{"abc"}, invalid: []any{"a1", ""}},
+		{rule: regexpRule, param: "^a+$", valid: []any{"aa"}, invalid: []any{"ab"}},
+		{rule: uniqueRule, valid: []any{[]string{"a", "b"}, [][]int{{1}, {2}}, []any{[]int{1}, []int{2}, 1}}, invalid: []any{[]int{1, 1}, map[string][]int{"a": {1}, "b": {1}}, []any{[]int{1}, []int{1}}}},
+	}
+
+	rules := builtinRules()
+	for _, test := range tests {
+		t.Run(test.rule, func(t *testing.T) {
+			fn := rules[test.rule]
+			for _, v := range test.valid {
+				assert.True(t, fn(context.Background(), Field{Value: reflect.ValueOf(v), Param: test.param}), v)
+			}
+			for _, v := range test.invalid {
+				assert.False(t, fn(context.Background(), Field{Value: reflect.ValueOf(v), Param: test.param}), v)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package validation
+
+import (
+	"errors"
+	"strings"
+)
+
+type (
+	// A FieldError describes a field that failed the validation.
+	FieldError struct {
+		// Field is the full path of the field, like Services[2].Name.
+		Field string
+		// Rule is the failed rule, like required or min,
+		// it's validate if the error is returned by the Validate method of the field.
+		Rule string
+		// Param is the parameter of the rule, like 3 in min=3.
+		Param string
+		// Value is the value of the field.
+		Value any
+		// Message is the localized message.
+		Message string
+		// Err is the error returned by the Validate method of the field, nil for the tag rules.
+		Err error
+		// template is the message template, used to render the message again on prefixing the field.
+		template string
+	}
+
+	// FieldErrors is the list of all the field errors found on validating.
+	FieldErrors []*FieldError
+)
+
+func (e *FieldError) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the underlying error.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Error returns the errors in lines.
+func (e FieldErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, fe := range e {
+		msgs = append(msgs, fe.Error())
+	}
+
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns the underlying errors, so that errors.Is and errors.As work on them.
+func (e FieldErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, fe := range e {
+		errs = append(errs, fe)
+	}
+
+	return errs
+}
+
+// duplicates reports whether e and other are the same failure, the messages of the tag rules
+// are not compared, because they might be rendered in different locales.
+func (e *FieldError) duplicates(other *FieldError) bool {
+	if e.Field != other.Field || e.Rule != other.Rule || e.Param != other.Param {
+		return false
+	}
+
+	return e.Rule != validatorRule || e.Message == other.Message
+}
+
+// newValidatorErrors converts err returned by the Validate method of the field at path into field errors,
+// the field errors returned by the nested validations are prefixed with path.
+func newValidatorErrors(path string, err error) FieldErrors {
+	var fes FieldErrors
+	if !errors.As(err, &fes) {
+		return FieldErrors{{
+			Field:   path,
+			Rule:    validatorRule,
+			Message: path + ": " + err.Error(),
+			Err:     err,
+		}}
+	}
+
+	prefixed := make(FieldErrors, 0, len(fes))
+	for _, fe := range fes {
+		clone := *fe
+		clone.Field = joinPath(path, fe.Field)
+		if len(clone.template) > 0 {
+			clone.Message = renderMessage(clone.template, &clone)
+		}
+		prefixed = append(prefixed, &clone)
+	}
+
+	return prefixed
+}
+
+func joinPath(parent, name string) string {
+	if len(parent) == 0 {
+		return name
+	}
+	if len(name) == 0 || name[0] == '[' {
+		return parent + name
+	}
+
+	return parent + "." + name
+}
@@ -0,0 +1,108 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const (
+	// LocaleEn is the English locale, which is the default.
+	LocaleEn = "en"
+	// LocaleZh is the Chinese locale.
+	LocaleZh = "zh"
+
+	defaultMessageKey = ""
+)
+
+type localeKey struct{}
+
+var defaultMessages = map[string]map[string]string{
+	LocaleEn: {
+		defaultMessageKey: "{field} failed on the {rule} rule",
+		requiredRule:      "{field} is required",
+		minRule:           "{field} must be at least {param}",
+		maxRule:           "{field} must be at most {param}",
+		lenRule:           "{field} must be {param} in length",
+		eqRule:            "{field} must be equal to {param}",
+		neRule:            "{field} must not be equal to {param}",
+		gtRule:            "{field} must be greater than {param}",
+		gteRule:           "{field} must be greater than or equal to {param}",
+		ltRule:            "{field} must be less than {param}",
+		lteRule:           "{field} must be less than or equal to {param}",
+		oneofRule:         "{field} must be one of [{param}]",
+		emailRule:         "{field} must be a valid email",
+		urlRule:           "{field} must be a valid url",
+		ipRule:            "{field} must be a valid ip",
+		ipv4Rule:          "{field} must be a valid ipv4",
+		ipv6Rule:          "{field} must be a valid ipv6",
+		cidrRule:          "{field} must be a valid cidr",
+		hostPortRule:      "{field} must be a valid host:port",
+		uuidRule:          "{field} must be a valid uuid",
+		alphaRule:         "{field} must contain letters only",
+		alphaNumRule:      "{field} must contain letters and digits only",
+		numericRule:       "{field} must be numeric",
+		regexpRule:        "{field} must match {param}",
+		uniqueRule:        "{field} must contain unique values",
+		eqFieldRule:       "{field} must be equal to {param}",
+		neFieldRule:       "{field} must not be equal to {param}",
+		gtFieldRule:       "{field} must be greater than {param}",
+		gteFieldRule:      "{field} must be greater than or equal to {param}",
+		ltFieldRule:       "{field} must be less than {param}",
+		lteFieldRule:      "{field} must be less than or equal to {param}",
+	},
+	LocaleZh: {
+		defaultMessageKey: "{field}未通过{rule}校验",
+		requiredRule:      "{field}为必填字段",
+		minRule:           "{field}不能小于{param}",
+		maxRule:           "{field}不能大于{param}",
+		lenRule:           "{field}长度必须为{param}",
+		eqRule:            "{field}必须等于{param}",
+		neRule:            "{field}不能等于{param}",
+		gtRule:            "{field}必须大于{param}",
+		gteRule:           "{field}必须大于或等于{param}",
+		ltRule:            "{field}必须小于{param}",
+		lteRule:           "{field}必须小于或等于{param}",
+		oneofRule:         "{field}必须是[{param}]中的一个",
+		emailRule:         "{field}必须是有效的邮箱",
+		urlRule:           "{field}必须是有效的url",
+		ipRule:            "{field}必须是有效的ip",
+		ipv4Rule:          "{field}必须是有效的ipv4",
+		ipv6Rule:          "{field}必须是有效的ipv6",
+		cidrRule:          "{field}必须是有效的cidr",
+		hostPortRule:      "{field}必须是有效的host:port",
+		uuidRule:          "{field}必须是有效的uuid",
+		alphaRule:         "{field}只能包含字母",
+		alphaNumRule:      "{field}只能包含字母和数字",
+		numericRule:       "{field}必须是数字",
+		regexpRule:        "{field}必须匹配{param}",
+		uniqueRule:        "{field}不能包含重复的值",
+		eqFieldRule:       "{field}必须等于{param}",
+		neFieldRule:       "{field}不能等于{param}",
+		gtFieldRule:       "{field}必须大于{param}",
+		gteFieldRule:      "{field}必须大于或等于{param}",
+		ltFieldRule:       "{field}必须小于{param}",
+		lteFieldRule:      "{field}必须小于或等于{param}",
+	},
+}
+
+// ContextWithLocale returns a context with locale, which is used to localize the messages
+// on validating with ctx, like the locale from the Accept-Language header.
+func ContextWithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// LocaleFromContext returns the locale in ctx, empty if not set.
+func LocaleFromContext(ctx context.Context) string {
+	locale, _ := ctx.Value(localeKey{}).(string)
+	return locale
+}
+
+func renderMessage(template string, fe *FieldError) string {
+	return strings.NewReplacer(
+		"{field}", fe.Field,
+		"{rule}", fe.Rule,
+		"{param}", fe.Param,
+		"{value}", fmt.Sprint(fe.Value),
+	).Replace(template)
+}
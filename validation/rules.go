@@ -0,0 +1,302 @@
+package validation
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/tp-life/utils/internal/rulex"
+)
+
+const (
+	requiredRule  = "required"
+	omitEmptyRule = "omitempty"
+	diveRule      = "dive"
+	validatorRule = "validate"
+	minRule       = "min"
+	maxRule       = "max"
+	lenRule       = "len"
+	eqRule        = "eq"
+	neRule        = "ne"
+	gtRule        = "gt"
+	gteRule       = "gte"
+	ltRule        = "lt"
+	lteRule       = "lte"
+	oneofRule     = "oneof"
+	emailRule     = "email"
+	urlRule       = "url"
+	ipRule        = "ip"
+	ipv4Rule      = "ipv4"
+	ipv6Rule      = "ipv6"
+	cidrRule      = "cidr"
+	hostPortRule  = "hostport"
+	uuidRule      = "uuid"
+	alphaRule     = "alpha"
+	alphaNumRule  = "alphanum"
+	numericRule   = "numeric"
+	regexpRule    = "regexp"
+	uniqueRule    = "unique"
+	eqFieldRule   = "eqfield"
+	neFieldRule   = "nefield"
+	gtFieldRule   = "gtfield"
+	gteFieldRule  = "gtefield"
+	ltFieldRule   = "ltfield"
+	lteFieldRule  = "ltefield"
+
+	oneofSeparator = "|"
+)
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	uuidRegex    = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	regexCache   sync.Map
+)
+
+type (
+	// A Field is the field being validated by a rule.
+	Field struct {
+		// Name is the full path of the field, like Services[2].Name.
+		Name string
+		// Value is the value of the field, pointers are dereferenced.
+		Value reflect.Value
+		// Param is the parameter of the rule, like 3 in min=3.
+		Param string
+		// Parent is the struct that the field belongs to, used to compare with the sibling fields.
+		Parent reflect.Value
+	}
+
+	// RuleFunc validates the field, returns false if the field is invalid.
+	RuleFunc func(ctx context.Context, field Field) bool
+)
+
+func builtinRules() map[string]RuleFunc {
+	return map[string]RuleFunc{
+		minRule:      compareParamRule(func(c int) bool { return c >= 0 }),
+		maxRule:      compareParamRule(func(c int) bool { return c <= 0 }),
+		gtRule:       compareParamRule(func(c int) bool { return c > 0 }),
+		gteRule:      compareParamRule(func(c int) bool { return c >= 0 }),
+		ltRule:       compareParamRule(func(c int) bool { return c < 0 }),
+		lteRule:      compareParamRule(func(c int) bool { return c <= 0 }),
+		lenRule:      validateLen,
+		eqRule:       equalParamRule(true),
+		neRule:       equalParamRule(false),
+		oneofRule:    validateOneof,
+		emailRule:    stringRule(rulex.IsEmail),
+		urlRule:      stringRule(rulex.IsURL),
+		ipRule:       stringRule(rulex.IsIP),
+		ipv4Rule:     stringRule(rulex.IsIPv4),
+		ipv6Rule:     stringRule(rulex.IsIPv6),
+		cidrRule:     stringRule(rulex.IsCIDR),
+		hostPortRule: stringRule(rulex.IsHostPort),
+		uuidRule:     stringRule(uuidRegex.MatchString),
+		alphaRule:    stringRule(allRunes(unicode.IsLetter)),
+		alphaNumRule: stringRule(allRunes(func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) })),
+		numericRule:  stringRule(isNumeric),
+		regexpRule:   validateRegexp,
+		uniqueRule:   validateUnique,
+		eqFieldRule:  equalFieldRule(true),
+		neFieldRule:  equalFieldRule(false),
+		gtFieldRule:  compareFieldRule(func(c int) bool { return c > 0 }),
+		gteFieldRule: compareFieldRule(func(c int) bool { return c >= 0 }),
+		ltFieldRule:  compareFieldRule(func(c int) bool { return c < 0 }),
+		lteFieldRule: compareFieldRule(func(c int) bool { return c <= 0 }),
+	}
+}
+
+// compareParamRule compares the lengths of strings, slices and maps, or the numbers with the param.
+func compareParamRule(ok func(int) bool) RuleFunc {
+	return func(_ context.Context, field Field) bool {
+		c, valid := compareWithParam(field.Value, field.Param)
+		return valid && ok(c)
+	}
+}
+
+func compareFieldRule(ok func(int) bool) RuleFunc {
+	return func(_ context.Context, field Field) bool {
+		sibling, found := siblingValue(field)
+		if !found {
+			return false
+		}
+
+		c, valid := rulex.Compare(field.Value, sibling)
+		return valid && ok(c)
+	}
+}
+
+// equalParamRule compares the strings, numbers and bools with the param,
+// the lengths are compared for slices and maps.
+func equalParamRule(equal bool) RuleFunc {
+	return func(_ context.Context, field Field) bool {
+		v := field.Value
+		switch v.Kind() {
+		case reflect.String:
+			return (v.String() == field.Param) == equal
+		case reflect.Bool:
+			b, err := strconv.ParseBool(field.Param)
+			return err == nil && (v.Bool() == b) == equal
+		default:
+			c, valid := compareWithParam(v, field.Param)
+			return valid && (c == 0) == equal
+		}
+	}
+}
+
+func equalFieldRule(equal bool) RuleFunc {
+	return func(_ context.Context, field Field) bool {
+		sibling, found := siblingValue(field)
+		if !found {
+			return false
+		}
+
+		if c, valid := rulex.Compare(field.Value, sibling); valid {
+			return (c == 0) == equal
+		}
+
+		return reflect.DeepEqual(field.Value.Interface(), sibling.Interface()) == equal
+	}
+}
+
+func stringRule(fn func(string) bool) RuleFunc {
+	return func(_ context.Context, field Field) bool {
+		return field.Value.Kind() == reflect.String && fn(field.Value.String())
+	}
+}
+
+func validateLen(_ context.Context, field Field) bool {
+	c, valid := compareWithParam(field.Value, field.Param)
+	return valid && c == 0
+}
+
+func validateOneof(_ context.Context, field Field) bool {
+	v := field.Value
+	switch v.Kind() {
+	case reflect.Array, reflect.Slice, reflect.Map, reflect.Struct, reflect.Func, reflect.Chan:
+		return false
+	}
+
+	val := fmt.Sprint(v.Interface())
+	for _, option := range strings.Split(field.Param, oneofSeparator) {
+		if option == val {
+			return true
+		}
+	}
+
+	return false
+}
+
+func validateRegexp(_ context.Context, field Field) bool {
+	if field.Value.Kind() != reflect.String {
+		return false
+	}
+
+	var re *regexp.Regexp
+	if val, ok := regexCache.Load(field.Param); ok {
+		re = val.(*regexp.Regexp)
+	} else {
+		var err error
+		if re, err = regexp.Compile(field.Param); err != nil {
+			return false
+		}
+		regexCache.Store(field.Param, re)
+	}
+
+	return re.MatchString(field.Value.String())
+}
+
+func validateUnique(_ context.Context, field Field) bool {
+	v := field.Value
+	var values []reflect.Value
+	switch v.Kind() {
+	case reflect.Array, reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			values = append(values, v.Index(i))
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			values = append(values, iter.Value())
+		}
+	default:
+		return false
+	}
+
+	return rulex.FirstDuplicate(values) < 0
+}
+
+// compareWithParam compares v with param, the lengths are compared for strings, slices and maps.
+func compareWithParam(v reflect.Value, param string) (int, bool) {
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(param)
+		if err != nil {
+			return 0, false
+		}
+
+		return cmp.Compare(v.Int(), int64(d)), true
+	case v.Kind() == reflect.String:
+		n, err := strconv.Atoi(param)
+		if err != nil {
+			return 0, false
+		}
+
+		return cmp.Compare(utf8.RuneCountInString(v.String()), n), true
+	case v.Kind() == reflect.Array || v.Kind() == reflect.Slice || v.Kind() == reflect.Map:
+		n, err := strconv.Atoi(param)
+		if err != nil {
+			return 0, false
+		}
+
+		return cmp.Compare(v.Len(), n), true
+	}
+
+	f, ok := rulex.Number(v)
+	if !ok {
+		return 0, false
+	}
+
+	p, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return cmp.Compare(f, p), true
+}
+
+// siblingValue returns the field named by the param in the parent struct.
+func siblingValue(field Field) (reflect.Value, bool) {
+	if !field.Parent.IsValid() || field.Parent.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+
+	sibling := indirect(field.Parent.FieldByName(field.Param))
+	return sibling, sibling.IsValid()
+}
+
+func allRunes(fn func(rune) bool) func(string) bool {
+	return func(s string) bool {
+		if len(s) == 0 {
+			return false
+		}
+
+		for _, r := range s {
+			if !fn(r) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+func isNumeric(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
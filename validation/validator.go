@@ -1,7 +1,18 @@
 package validation
 
-// Validator represents a validator.
-type Validator interface {
-	// Validate validates the value.
-	Validate() error
-}
+import "context"
+
+type (
+	// Validator represents a validator.
+	Validator interface {
+		// Validate validates the value.
+		Validate() error
+	}
+
+	// ContextValidator represents a validator that validates with a context,
+	// like checking the values against the database.
+	ContextValidator interface {
+		// ValidateCtx validates the value with ctx.
+		ValidateCtx(ctx context.Context) error
+	}
+)